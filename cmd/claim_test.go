@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lbryio/lbry.go/v2/extras/jsonrpc"
+	"github.com/lbryio/lbry.go/v2/lbrycrd"
+	"github.com/lbryio/lbry.go/v2/schema/stake"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	pb "github.com/lbryio/types/v2/go"
+)
+
+const testAddress = "bMUxfQVUeDi7ActVeZJZHzHKBceai7kHha"
+
+// newTestDaemon returns the url of a fake daemon that resolves lbry://test to output 0 of tx and shows tx
+func newTestDaemon(t *testing.T, tx *wire.MsgTx) string {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	txid := tx.TxHash().String()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string                 `json:"method"`
+			Params map[string]interface{} `json:"params"`
+			ID     int                    `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var result interface{}
+		var rpcErr map[string]interface{}
+		switch {
+		case req.Method == "resolve" && req.Params["urls"] == "lbry://test":
+			result = map[string]interface{}{"lbry://test": map[string]interface{}{"name": "test", "txid": txid, "nout": 0}}
+		case req.Method == "resolve":
+			result = map[string]interface{}{}
+		case req.Method == "transaction_show" && req.Params["txid"] == txid:
+			result = map[string]interface{}{"txid": txid, "hex": hex.EncodeToString(buf.Bytes())}
+		default:
+			rpcErr = map[string]interface{}{"code": jsonrpc.ErrorCodeApplication, "message": "unexpected call to " + req.Method}
+		}
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result}
+		if rpcErr != nil {
+			resp = map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "error": rpcErr}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// testClaimTx builds an unsigned transaction with a claim for "test" in output 0
+func testClaimTx(t *testing.T, claim *stake.StakeHelper) *wire.MsgTx {
+	address, err := lbrycrd.DecodeAddress(testAddress, &lbrycrd.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &lbrycrd.TxOptions{
+		Fee:           0.001,
+		PayoutAddress: address,
+		ChangeAddress: address,
+		Unspent:       []btcjson.ListUnspentResult{{TxID: chainhash.Hash{1}.String(), Amount: 2, Spendable: true}},
+	}
+	u, err := (&lbrycrd.Client{}).BuildClaimName("test", claim, 1, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Tx
+}
+
+func TestClaimLoader_Load(t *testing.T) {
+	claim, err := lbrycrd.NewStreamClaim("title", "description")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := claim.CompileValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := testClaimTx(t, claim)
+	txid := tx.TxHash().String()
+	claimID, err := lbrycrd.ClaimIDFromOutpoint(txid, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	support := &stake.StakeHelper{Support: &pb.Support{Emoji: "👍"}, Version: stake.NoSig}
+	supportValue, err := support.CompileValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	daemonURL := newTestDaemon(t, tx)
+
+	fromTx := &claimInfo{Name: "test", ClaimID: claimID, Outpoint: txid + ":0", Op: "create", Address: testAddress, Format: "protobuf"}
+	tests := []struct {
+		name    string
+		arg     string
+		support bool
+		info    *claimInfo
+		hasTx   bool
+		err     string
+	}{
+		{name: "hex", arg: hex.EncodeToString(value), info: &claimInfo{Format: "protobuf"}},
+		{name: "outpoint", arg: txid + ":0", info: fromTx, hasTx: true},
+		{name: "url", arg: "lbry://test", info: fromTx, hasTx: true},
+		{name: "bad hex", arg: "xyz", err: "hex"},
+		{name: "support", arg: hex.EncodeToString(supportValue), support: true, info: &claimInfo{Format: "protobuf"}},
+		{name: "bad nout", arg: txid + ":x", err: "invalid outpoint"},
+		{name: "missing output", arg: txid + ":5", err: "no output 5"},
+		{name: "change output", arg: txid + ":1", err: "not a claim"},
+		{name: "unknown transaction", arg: chainhash.Hash{2}.String() + ":0", err: "unexpected call"},
+		{name: "unresolved url", arg: "lbry://missing", err: "did not resolve"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := &claimLoader{flags: &claimFlags{daemonURL: daemonURL, support: test.support}}
+			defer l.close()
+			info, loaded, nout, err := l.load(test.arg)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected an error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (loaded != nil) != test.hasTx || nout != 0 {
+				t.Errorf("expected a transaction: %t, got %v with nout %d", test.hasTx, loaded != nil, nout)
+			}
+			if info.Value == nil {
+				t.Fatal("expected a decoded value")
+			}
+			if test.support && info.Value.Support.GetEmoji() != "👍" || !test.support && info.Value.Claim.GetTitle() != "title" {
+				t.Errorf("expected the decoded value, got %+v", info.Value)
+			}
+			info.Value = nil
+			if *info != *test.info {
+				t.Errorf("expected %+v, got %+v", test.info, info)
+			}
+		})
+	}
+}
+
+func TestNewClaimInfo(t *testing.T) {
+	channelID := "589bc4845caca70977332025990b2a1807732b44"
+	channelBytes, err := lbrycrd.ClaimIDToBytes(channelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := lbrycrd.NewStreamClaim("title", "description")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := stream.CompileValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	claim, err := stake.DecodeClaimBytes(value, lbrycrd.LbrycrdMain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := *claim
+	signed.ClaimID = channelBytes
+	signed.Signature = []byte{1, 2, 3}
+
+	tests := []struct {
+		name   string
+		helper *stake.StakeHelper
+		json   map[string]interface{}
+	}{
+		{name: "nothing decoded", json: map[string]interface{}{"format": "unknown"}},
+		{name: "json", helper: stream, json: map[string]interface{}{"format": "json"}},
+		{name: "unsigned", helper: claim, json: map[string]interface{}{"format": "protobuf"}},
+		{name: "signed", helper: &signed, json: map[string]interface{}{
+			"format":             "protobuf",
+			"signing_channel_id": channelID,
+			"signature":          "010203",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			info := newClaimInfo(test.helper)
+			if info.Value != test.helper {
+				t.Error("expected the value to be the decoded claim")
+			}
+			raw, err := json.Marshal(info)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out map[string]interface{}
+			if err := json.Unmarshal(raw, &out); err != nil {
+				t.Fatal(err)
+			}
+			if test.helper != nil {
+				if _, ok := out["value"]; !ok {
+					t.Errorf("expected a value in %s", raw)
+				}
+				delete(out, "value")
+			}
+			if len(out) != len(test.json) {
+				t.Errorf("expected %d fields, got %s", len(test.json), raw)
+			}
+			for k, v := range test.json {
+				if out[k] != v {
+					t.Errorf("expected %s to be %v, got %v", k, v, out[k])
+				}
+			}
+		})
+	}
+}
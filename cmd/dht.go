@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/lbryio/lbry.go/v2/dht"
+	"github.com/lbryio/lbry.go/v2/dht/bits"
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+type dhtFlags struct {
+	address   string
+	seeds     string
+	nodeID    string
+	stateFile string
+	logLevel  string
+	rpcPort   int
+	nat       bool
+}
+
+func (f *dhtFlags) register(fs *pflag.FlagSet) {
+	fs.StringVar(&f.address, "address", "0.0.0.0:"+strconv.Itoa(dht.DefaultPort), "address to listen on, as ip:port")
+	fs.StringVar(&f.seeds, "seeds", "", "comma-separated seed nodes (default: the lbry seed nodes)")
+	fs.StringVar(&f.nodeID, "node-id", "", "hex-encoded node id (default: random)")
+	fs.StringVar(&f.stateFile, "state-file", "", "file to save the routing table to, so the node can rejoin without the seeds")
+	fs.StringVar(&f.logLevel, "log-level", "info", "log level (debug, info, warn, error)")
+	fs.IntVar(&f.rpcPort, "rpc-port", 0, "if nonzero, serve the json-rpc api on this port")
+	fs.BoolVar(&f.nat, "nat", false, "forward the dht port on the router using UPnP or NAT-PMP")
+}
+
+func (f *dhtFlags) config() (*dht.Config, error) {
+	err := setLogLevel(f.logLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	c := dht.NewStandardConfig()
+
+	host, port, err := net.SplitHostPort(f.address)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	if host == "" {
+		host = "0.0.0.0" // allow ':4444'
+	}
+	c.Address = net.JoinHostPort(host, port)
+
+	if f.seeds != "" {
+		c.SeedNodes = strings.Split(f.seeds, ",")
+	}
+	c.NodeID = f.nodeID
+	c.RoutingTableFile = f.stateFile
+	c.RPCPort = f.rpcPort
+	c.NATTraversal = f.nat
+
+	return c, nil
+}
+
+func init() {
+	flags := &dhtFlags{}
+
+	dhtCmd := &cobra.Command{
+		Use:   "dht",
+		Short: "run and query a DHT node",
+	}
+	flags.register(dhtCmd.PersistentFlags())
+
+	startCmd := &cobra.Command{
+		Use:   "start",
+		Short: "run a DHT node until interrupted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return dhtStart(flags)
+		},
+	}
+	findCmd := &cobra.Command{
+		Use:   "find <hash>",
+		Short: "find the peers that have a blob",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return dhtFind(flags, args[0])
+		},
+	}
+	announceCmd := &cobra.Command{
+		Use:   "announce <hash>",
+		Short: "announce that a peer has a blob",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return dhtAnnounce(flags, args[0])
+		},
+	}
+
+	dhtCmd.AddCommand(startCmd, findCmd, announceCmd)
+	rootCmd.AddCommand(dhtCmd)
+}
+
+func dhtStart(flags *dhtFlags) error {
+	d, err := startDHT(flags)
+	if err != nil {
+		return err
+	}
+
+	interruptChan := make(chan os.Signal, 1)
+	signal.Notify(interruptChan, os.Interrupt, syscall.SIGTERM)
+	<-interruptChan
+
+	d.Shutdown()
+	return nil
+}
+
+func dhtFind(flags *dhtFlags, hex string) error {
+	hash, err := bits.FromHex(hex)
+	if err != nil {
+		return err
+	}
+
+	d, err := startDHT(flags)
+	if err != nil {
+		return err
+	}
+	defer d.Shutdown()
+
+	contacts, err := d.Get(hash)
+	if err != nil {
+		return err
+	}
+
+	if len(contacts) == 0 {
+		fmt.Println("no peers found")
+		return nil
+	}
+	for _, c := range contacts {
+		fmt.Println(c.String())
+	}
+	return nil
+}
+
+func dhtAnnounce(flags *dhtFlags, hex string) error {
+	hash, err := bits.FromHex(hex)
+	if err != nil {
+		return err
+	}
+
+	d, err := startDHT(flags)
+	if err != nil {
+		return err
+	}
+	defer d.Shutdown()
+
+	// wait for the stores to be acknowledged, or time out, before shutting down closes the connection
+	stored, err := d.AnnounceAndWait(hash)
+	if err != nil {
+		return err
+	}
+	if stored == 0 {
+		return errors.Err("no peers stored %s", hash.Hex())
+	}
+
+	fmt.Printf("announced %s, stored by %d peers\n", hash.Hex(), stored)
+	return nil
+}
+
+func startDHT(flags *dhtFlags) (*dht.DHT, error) {
+	c, err := flags.config()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := dht.New(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	d.WaitUntilJoined()
+	return d, nil
+}
@@ -0,0 +1,413 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/dht"
+	"github.com/lbryio/lbry.go/v2/dht/bits"
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"go.uber.org/atomic"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultSeedShutdownTimeout = 30 * time.Second
+
+// seedConfig is the config file for dht-seed. For example:
+//
+//	listen: 0.0.0.0:4444
+//	node_id_file: /var/lib/lbry-dht/node_id
+//	routing_table_file: /var/lib/lbry-dht/contacts.json
+//	ban_list: [203.0.113.7, 198.51.100.0/24]
+//	rate_limit: {packets_per_second: 50, burst: 200}
+//	http: 127.0.0.1:8080
+//	log_file: /var/log/lbry-dht.log
+type seedConfig struct {
+	// the udp address to listen on
+	Listen string `yaml:"listen"`
+	// nodes to join through. the lbry seed nodes if empty
+	Seeds []string `yaml:"seeds"`
+	// where the node id is kept, so it stays the same across restarts. a random id is written there if it's missing
+	NodeIDFile       string `yaml:"node_id_file"`
+	RoutingTableFile string `yaml:"routing_table_file"`
+	// ips and cidr ranges whose packets are dropped
+	BanList   []string `yaml:"ban_list"`
+	RateLimit struct {
+		// per ip. 0 means no limit
+		PacketsPerSecond float64 `yaml:"packets_per_second"`
+		Burst            int     `yaml:"burst"`
+	} `yaml:"rate_limit"`
+	// the address to serve /metrics and /status on. nothing is served if empty
+	HTTP     string `yaml:"http"`
+	LogLevel string `yaml:"log_level"`
+	// logs go to stderr if empty. the file is reopened on SIGHUP, so it works with logrotate
+	LogFile string `yaml:"log_file"`
+	NAT     bool   `yaml:"nat"`
+	// how long to wait for the http server to finish its requests when shutting down
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+func loadSeedConfig(path string) (*seedConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+
+	c := &seedConfig{
+		Listen:          net.JoinHostPort("0.0.0.0", fmt.Sprint(dht.DefaultPort)),
+		LogLevel:        "info",
+		ShutdownTimeout: defaultSeedShutdownTimeout,
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err = dec.Decode(c)
+	if err != nil && err != io.EOF {
+		return nil, errors.Prefix("parsing "+path, err)
+	}
+
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		return nil, errors.Prefix("listen", err)
+	}
+	if _, err := log.ParseLevel(c.LogLevel); err != nil {
+		return nil, errors.Prefix("log_level", err)
+	}
+	return c, nil
+}
+
+// loadNodeID reads the node id from path, or makes a new one and saves it there
+func loadNodeID(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		id := strings.TrimSpace(string(data))
+		if _, err := bits.FromHex(id); err != nil {
+			return "", errors.Prefix("node id in "+path, err)
+		}
+		return id, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", errors.Err(err)
+	}
+
+	id := bits.Rand().Hex()
+	err = os.WriteFile(path, []byte(id+"\n"), 0600)
+	if err != nil {
+		return "", errors.Err(err)
+	}
+	log.Infof("saved new node id to %s", path)
+	return id, nil
+}
+
+func init() {
+	var configPath string
+
+	seedCmd := &cobra.Command{
+		Use:   "dht-seed",
+		Short: "run a DHT seed node from a config file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSeed(configPath)
+		},
+	}
+	seedCmd.Flags().StringVar(&configPath, "config", "dht-seed.yml", "yaml config file")
+	rootCmd.AddCommand(seedCmd)
+}
+
+// seedNode is a dht node run as a seed. SIGHUP reloads the ban list, rate limit, and log level from the config file
+// and reopens the log file. SIGTERM or SIGINT shut it down cleanly, saving the routing table.
+//
+// The udp port is opened with SO_REUSEPORT where that's supported, so a restart can have no downtime: start the new
+// process with the same config, which picks up the same node id and routing table, and then send SIGTERM to the old
+// one.
+type seedNode struct {
+	configPath string
+	started    time.Time
+
+	lock    sync.Mutex
+	conf    *seedConfig
+	logFile *os.File
+
+	d      *dht.DHT
+	filter *dht.FilterConn
+	// set once the node has joined, and unset when it starts shutting down
+	ready *atomic.Bool
+}
+
+func runSeed(configPath string) error {
+	conf, err := loadSeedConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	s := &seedNode{configPath: configPath, conf: conf, started: time.Now(), ready: atomic.NewBool(false)}
+	err = s.setUpLogging()
+	if err != nil {
+		return err
+	}
+	defer s.closeLog()
+
+	nodeID, err := loadNodeID(conf.NodeIDFile)
+	if err != nil {
+		return err
+	}
+
+	dc := dht.NewStandardConfig()
+	dc.Address = conf.Listen
+	if len(conf.Seeds) > 0 {
+		dc.SeedNodes = conf.Seeds
+	}
+	dc.NodeID = nodeID
+	dc.RoutingTableFile = conf.RoutingTableFile
+	dc.NATTraversal = conf.NAT
+
+	s.d, err = dht.New(dc)
+	if err != nil {
+		return err
+	}
+
+	lc := net.ListenConfig{Control: reusePortControl}
+	pc, err := lc.ListenPacket(context.Background(), dht.Network, conf.Listen)
+	if err != nil {
+		return errors.Err(err)
+	}
+	s.filter = dht.NewFilterConn(pc.(*net.UDPConn))
+	err = s.applyFilters(conf)
+	if err != nil {
+		pc.Close()
+		return err
+	}
+
+	var srv *http.Server
+	if conf.HTTP != "" {
+		srv = &http.Server{Addr: conf.HTTP, Handler: s.handler()}
+		go func() {
+			err := srv.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				log.Error(errors.Prefix("http server", err))
+			}
+		}()
+	}
+	stopHTTP := func() {
+		if srv == nil {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), s.config().ShutdownTimeout)
+		defer cancel()
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			log.Warn(errors.Prefix("http server shutdown", err))
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(signals)
+
+	// the first join attempt happens while the dht starts, which can take a while, so signals are handled in the
+	// meantime. the dht can only be shut down once it has started, so a stop signal waits for that, and a second one
+	// quits right away
+	started := make(chan error, 1)
+	go func() { started <- s.d.StartWithConn(s.filter) }()
+	stopping := false
+	for started != nil {
+		select {
+		case err = <-started:
+			started = nil
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				s.reload()
+			} else if stopping {
+				return errors.Err("interrupted while joining the network")
+			} else {
+				log.Info("stopping once the dht has started, signal again to quit now")
+				stopping = true
+			}
+		}
+	}
+	if err != nil {
+		pc.Close()
+		stopHTTP()
+		return err
+	}
+
+	if !stopping {
+		s.ready.Store(true)
+		log.Infof("dht seed %s running on %s", s.d.ID().HexShort(), conf.Listen)
+
+		for sig := range signals {
+			if sig == syscall.SIGHUP {
+				s.reload()
+				continue
+			}
+			break
+		}
+	}
+
+	log.Info("shutting down")
+	s.ready.Store(false)
+	stopHTTP()
+	s.d.Shutdown()
+	log.Info("stopped")
+	return nil
+}
+
+func (s *seedNode) config() *seedConfig {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.conf
+}
+
+func (s *seedNode) applyFilters(conf *seedConfig) error {
+	err := s.filter.SetBanList(conf.BanList)
+	if err != nil {
+		return err
+	}
+	s.filter.SetRateLimit(conf.RateLimit.PacketsPerSecond, conf.RateLimit.Burst)
+	return nil
+}
+
+// reload applies the settings that can change without a restart, and reopens the log file
+func (s *seedNode) reload() {
+	if err := s.reopenLog(); err != nil {
+		log.Error(errors.Prefix("reopening log", err))
+	}
+
+	conf, err := loadSeedConfig(s.configPath)
+	if err != nil {
+		log.Error(errors.Prefix("reloading config", err))
+		return
+	}
+	if err := s.applyFilters(conf); err != nil {
+		log.Error(errors.Prefix("reloading config", err))
+		return
+	}
+	_ = setLogLevel(conf.LogLevel)
+
+	s.lock.Lock()
+	old := s.conf
+	// the rest need a restart, so keep what's actually running
+	conf.Listen, conf.Seeds, conf.NodeIDFile, conf.RoutingTableFile, conf.HTTP, conf.LogFile, conf.NAT =
+		old.Listen, old.Seeds, old.NodeIDFile, old.RoutingTableFile, old.HTTP, old.LogFile, old.NAT
+	s.conf = conf
+	s.lock.Unlock()
+
+	log.Infof("reloaded config: %d bans, rate limit %v/s", len(conf.BanList), conf.RateLimit.PacketsPerSecond)
+}
+
+func (s *seedNode) setUpLogging() error {
+	err := setLogLevel(s.conf.LogLevel)
+	if err != nil {
+		return err
+	}
+	return s.reopenLog()
+}
+
+func (s *seedNode) reopenLog() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conf.LogFile == "" {
+		return nil
+	}
+	f, err := os.OpenFile(s.conf.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Err(err)
+	}
+	log.SetOutput(f)
+	if s.logFile != nil {
+		s.logFile.Close()
+	}
+	s.logFile = f
+	return nil
+}
+
+func (s *seedNode) closeLog() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.logFile != nil {
+		log.SetOutput(os.Stderr)
+		s.logFile.Close()
+		s.logFile = nil
+	}
+}
+
+type seedStatus struct {
+	Ready         bool
+	UptimeSeconds int64
+	dht.Stats
+	Dropped dht.FilterStats
+}
+
+func (s *seedNode) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		status := seedStatus{Ready: s.ready.Load(), UptimeSeconds: int64(time.Since(s.started).Seconds())}
+		if status.Ready {
+			status.Stats = s.d.Stats()
+			status.Dropped = s.filter.Stats()
+		} else {
+			// starting up or shutting down, so load balancers should go elsewhere
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(status)
+	})
+
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeSeedMetrics(w, s.ready.Load(), time.Since(s.started), s.d, s.filter)
+	})
+
+	return mux
+}
+
+// writeSeedMetrics writes the node's stats in the prometheus text format
+func writeSeedMetrics(w io.Writer, ready bool, uptime time.Duration, d *dht.DHT, filter *dht.FilterConn) {
+	metric := func(name, kind, help string, value interface{}) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+	boolValue := func(b bool) int {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	metric("dht_ready", "gauge", "Whether the node has joined and isn't shutting down.", boolValue(ready))
+	metric("dht_uptime_seconds", "gauge", "Seconds since the process started.", int64(uptime.Seconds()))
+	if !ready {
+		return
+	}
+
+	stats := d.Stats()
+	metric("dht_contacts", "gauge", "Contacts in the routing table.", stats.Contacts)
+	metric("dht_stored_hashes", "gauge", "Hashes that peers have announced to this node.", stats.StoredHashes)
+	metric("dht_active_transactions", "gauge", "Requests waiting for a response.", stats.ActiveTransactions)
+
+	dropped := filter.Stats()
+	metric("dht_packets_banned_total", "counter", "Packets dropped because the sender is banned.", dropped.Banned)
+	metric("dht_packets_rate_limited_total", "counter", "Packets dropped because the sender went over the rate limit.", dropped.RateLimited)
+
+	if r := stats.Reachability; r != nil {
+		metric("dht_reachability_peers_responded", "gauge", "Peers that responded to the last reachability check.", r.Responded)
+		metric("dht_reachability_peers_observed", "gauge", "Peers that have this node in their routing table.", len(r.Observed))
+		metric("dht_reachability_inbound_works", "gauge", "Whether peers have contacted this node first recently.", boolValue(r.InboundWorks))
+		metric("dht_reachability_unsolicited_inbound_total", "counter", "Requests from peers this node hadn't contacted.", r.UnsolicitedInbound)
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/schema/stake"
+	"github.com/lbryio/lbry.go/v2/schema/url"
+
+	pb "github.com/lbryio/types/v2/go"
+)
+
+func TestNameFromFile(t *testing.T) {
+	tests := []struct {
+		file string
+		name string
+	}{
+		{"video.mp4", "video"},
+		{"My Video (final).mp4", "My-Video-final"},
+		{"part #1: the start.mkv", "part-1-the-start"},
+		{"école $5 @home.webm", "école-5-home"},
+		{"archive.tar.gz", "archive.tar"},
+		{"  spaced  .txt", "spaced"},
+		{"#$@.mp4", ""},
+	}
+	for _, test := range tests {
+		name := nameFromFile(test.file)
+		if name != test.name {
+			t.Errorf("%s: expected %q, got %q", test.file, test.name, name)
+		}
+		if name != "" {
+			if err := url.ValidateName(name); err != nil {
+				t.Errorf("%s: made an invalid name: %v", test.file, err)
+			}
+		}
+	}
+}
+
+func TestPublish_InvalidNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		file    string
+	}{
+		{name: "has space", file: "video.mp4"},
+		{name: "has#hash", file: "video.mp4"},
+		{name: "has:colon", file: "video.mp4"},
+		{name: "video", channel: "@bad channel", file: "video.mp4"},
+		{name: "video", channel: "nochannel", file: "video.mp4"},
+		{file: "#$@.mp4"},
+	}
+	for _, test := range tests {
+		// these must fail before anything is encoded or sent, so no daemon or lbrycrd is needed
+		flags := &publishFlags{name: test.name, channelName: test.channel, bid: 0.01, logLevel: "info"}
+		err := publish(flags, test.file)
+		if err == nil {
+			t.Errorf("%+v: expected an error", test)
+		}
+	}
+
+	err := publish(&publishFlags{name: "has space", bid: 0.01, logLevel: "info"}, "video.mp4")
+	if !errors.Is(err, url.ErrInvalidName) {
+		t.Errorf("expected an invalid name error, got %v", err)
+	}
+}
+
+func TestCheckClaim(t *testing.T) {
+	valid, err := stake.NewStreamClaim().
+		Title("test").
+		SDHash(strings.Repeat("ab", 48)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkClaim(valid); err != nil {
+		t.Errorf("expected a valid claim, got %v", err)
+	}
+
+	invalid := &stake.StakeHelper{Claim: &pb.Claim{
+		Thumbnail: &pb.Source{Url: "not a url"},
+		Type:      &pb.Claim_Stream{Stream: &pb.Stream{Source: &pb.Source{SdHash: []byte{1, 2, 3}}}},
+	}}
+	err = checkClaim(invalid)
+	if err == nil {
+		t.Fatal("expected a claim with violations to be refused")
+	}
+	for _, field := range []string{"thumbnail.url", "stream.source.sd_hash"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected the error to mention %s, got %v", field, err)
+		}
+	}
+}
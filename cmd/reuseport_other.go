@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package cmd
+
+import (
+	"syscall"
+)
+
+// reusePortControl does nothing where SO_REUSEPORT isn't available, so restarts have a moment of downtime
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return nil
+}
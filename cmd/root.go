@@ -0,0 +1,33 @@
+// Package cmd implements the subcommands of the lbry binary.
+package cmd
+
+import (
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "lbry",
+	Short: "tools for the LBRY network",
+	// main logs the error, and usage is only worth printing for mistakes cobra catches itself
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+// Execute runs the subcommand named in args (usually os.Args[1:])
+func Execute(args []string) error {
+	rootCmd.SetArgs(args)
+	return rootCmd.Execute()
+}
+
+// setLogLevel sets the level of the standard logger, which the libraries log to by default
+func setLogLevel(level string) error {
+	l, err := log.ParseLevel(level)
+	if err != nil {
+		return errors.Err(err)
+	}
+	log.SetLevel(l)
+	return nil
+}
@@ -3,6 +3,7 @@ package bits
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
 	"math/big"
 	"strconv"
 	"strings"
@@ -33,11 +34,11 @@ func (b Bitmap) String() string {
 
 // BString returns the bitmap as a string of 0s and 1s
 func (b Bitmap) BString() string {
-	var s string
+	var s strings.Builder
 	for _, byte := range b {
-		s += strconv.FormatInt(int64(byte), 2)
+		s.WriteString(fmt.Sprintf("%08b", byte))
 	}
-	return s
+	return s.String()
 }
 
 // Hex returns a hexadecimal representation of the bitmap.
@@ -87,6 +88,16 @@ func (b Bitmap) Closer(x, y Bitmap) bool {
 	return x.Xor(b).Cmp(y.Xor(b)) < 0
 }
 
+// Distance returns the kademlia distance between b and other, which is their xor
+func (b Bitmap) Distance(other Bitmap) Bitmap {
+	return b.Xor(other)
+}
+
+// CommonPrefixLen returns the number of leading bits that b and other have in common
+func (b Bitmap) CommonPrefixLen(other Bitmap) int {
+	return b.Xor(other).PrefixLen()
+}
+
 // Equals returns true if every byte in bitmap are equal, false otherwise
 func (b Bitmap) Equals(other Bitmap) bool {
 	return b.Cmp(other) == 0
@@ -354,17 +365,18 @@ func Rand() Bitmap {
 	return id
 }
 
-// RandInRangeP generates a cryptographically random bitmap and while it is greater than the high threshold
-// bitmap will subtract the diff between high and low until it is no longer greater that the high.
+// RandInRangeP generates a cryptographically random bitmap between low and high, inclusive. It panics if low > high.
 func RandInRangeP(low, high Bitmap) Bitmap {
-	diff := high.Sub(low)
-	r := Rand()
-	for r.Cmp(diff) > 0 {
-		r = r.Sub(diff)
+	if low.Cmp(high) > 0 {
+		panic(errors.Err("low %s is greater than high %s", low.HexSimplified(), high.HexSimplified()))
+	}
+	size := high.Sub(low).Big()
+	size.Add(size, big.NewInt(1))
+	r, err := rand.Int(rand.Reader, size)
+	if err != nil {
+		panic(err)
 	}
-	//ToDo - Adding the low at this point doesn't gurantee it will be within the range. Consider bitmaps as numbers and
-	// I have a range of 50-100. If get to say 60, and add 50, I would be at 110. Should protect against this?
-	return r.Add(low)
+	return FromBigP(r).Add(low)
 }
 
 func getBit(b []byte, n int) bool {
@@ -389,11 +401,11 @@ func Closest(target Bitmap, bitmaps ...Bitmap) Bitmap {
 		return target
 	}
 
-	var closest *Bitmap
-	for _, b := range bitmaps {
-		if closest == nil || target.Closer(b, *closest) {
-			closest = &b
+	closest := bitmaps[0]
+	for _, b := range bitmaps[1:] {
+		if target.Closer(b, closest) {
+			closest = b
 		}
 	}
-	return *closest
+	return closest
 }
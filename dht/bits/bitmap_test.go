@@ -2,7 +2,10 @@ package bits
 
 import (
 	"fmt"
+	"math/big"
+	"strings"
 	"testing"
+	"testing/quick"
 
 	"github.com/lyoshenka/bencode"
 )
@@ -384,3 +387,96 @@ func assertPanic(t *testing.T, text string, f func()) {
 	}()
 	f()
 }
+
+func TestBitmap_BString(t *testing.T) {
+	b := FromShortHexP("1")
+	expected := strings.Repeat("0", NumBits-1) + "1"
+	if b.BString() != expected {
+		t.Errorf("expected %s, got %s", expected, b.BString())
+	}
+}
+
+func TestBitmap_Properties(t *testing.T) {
+	properties := map[string]interface{}{
+		"distance to self is zero": func(a Bitmap) bool {
+			return a.Distance(a).Equals(Bitmap{})
+		},
+		"distance is symmetric": func(a, b Bitmap) bool {
+			return a.Distance(b).Equals(b.Distance(a))
+		},
+		"distance obeys the triangle inequality": func(a, b, c Bitmap) bool {
+			sum := new(big.Int).Add(a.Distance(b).Big(), b.Distance(c).Big())
+			return a.Distance(c).Big().Cmp(sum) <= 0
+		},
+		"xor undoes itself": func(a, b Bitmap) bool {
+			return a.Xor(b).Xor(b).Equals(a)
+		},
+		"cmp agrees with big ints": func(a, b Bitmap) bool {
+			return a.Cmp(b) == a.Big().Cmp(b.Big()) && a.Cmp(b) == -b.Cmp(a)
+		},
+		"closer is strict": func(target, a, b Bitmap) bool {
+			return !(target.Closer(a, b) && target.Closer(b, a)) && !target.Closer(a, a)
+		},
+		"closest is closest": func(target, a, b, c Bitmap) bool {
+			closest := Closest(target, a, b, c)
+			for _, x := range []Bitmap{a, b, c} {
+				if target.Closer(x, closest) {
+					return false
+				}
+			}
+			return true
+		},
+		"sub undoes add": func(a, b Bitmap) bool {
+			if a.Big().Add(a.Big(), b.Big()).Cmp(MaxP().Big()) > 0 {
+				return true // would overflow
+			}
+			return a.Add(b).Sub(b).Equals(a)
+		},
+		"big round trips": func(a Bitmap) bool {
+			return FromBigP(a.Big()).Equals(a)
+		},
+		"hex round trips": func(a Bitmap) bool {
+			return FromHexP(a.Hex()).Equals(a) && FromShortHexP(a.HexSimplified()).Equals(a)
+		},
+		"not is its own inverse": func(a Bitmap) bool {
+			return a.Not().Not().Equals(a) && a.Xor(a.Not()).Equals(MaxP())
+		},
+		"common prefix matches bits": func(a, b Bitmap, n uint16) bool {
+			// make a and b share at least n bits
+			n %= NumBits + 1
+			for i := 0; i < int(n); i++ {
+				b = b.Set(i, a.Get(i))
+			}
+			l := a.CommonPrefixLen(b)
+			if l < int(n) || l != b.CommonPrefixLen(a) {
+				return false
+			}
+			return l == NumBits || a.Get(l) != b.Get(l)
+		},
+		"prefix and suffix cover everything": func(a Bitmap, n uint16) bool {
+			n %= NumBits + 1
+			return a.Prefix(int(n), true).Suffix(NumBits-int(n), true).Equals(MaxP()) &&
+				a.Prefix(int(n), false).Suffix(NumBits-int(n), false).Equals(Bitmap{})
+		},
+		"prefix len counts leading zeros": func(a Bitmap) bool {
+			return a.PrefixLen() == NumBits-a.Big().BitLen()
+		},
+		"rand in range is in range": func(a, b Bitmap, n uint16) bool {
+			if a.Cmp(b) > 0 {
+				a, b = b, a
+			}
+			// small ranges too, where a bad implementation would take forever or fall outside
+			if n%2 == 0 {
+				b = a.Xor(FromShortHexP(fmt.Sprintf("%x", n))).Or(a)
+			}
+			r := RandInRangeP(a, b)
+			return (Range{Start: a, End: b}).Contains(r)
+		},
+	}
+
+	for name, f := range properties {
+		if err := quick.Check(f, nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package bits
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/lbryio/lbry.go/v2/extras/errors"
@@ -12,6 +13,7 @@ type Range struct {
 	End   Bitmap
 }
 
+// MaxRange returns the range of all bitmaps
 func MaxRange() Range {
 	return Range{
 		Start: Bitmap{},
@@ -63,3 +65,30 @@ func (r Range) IntervalSize() *big.Int {
 func (r Range) Contains(b Bitmap) bool {
 	return r.Start.Cmp(b) <= 0 && r.End.Cmp(b) >= 0
 }
+
+// PrefixRange returns the range of bitmaps whose first n bits are the same as b's. Buckets in the routing table cover
+// prefix ranges of distances, and splitting one gives the two prefix ranges that are one bit longer.
+func PrefixRange(b Bitmap, n int) Range {
+	if n < 0 || n > NumBits {
+		panic(errors.Err("invalid prefix length %d", n))
+	}
+	return Range{
+		Start: b.Suffix(NumBits-n, false),
+		End:   b.Suffix(NumBits-n, true),
+	}
+}
+
+// Split divides the range into two halves. The first half is one bitmap larger if the range has an odd number of
+// bitmaps.
+func (r Range) Split() (Range, Range) {
+	return r.IntervalP(1, 2), r.IntervalP(2, 2)
+}
+
+// Overlaps returns true if any bitmap is in both ranges
+func (r Range) Overlaps(other Range) bool {
+	return r.Start.Cmp(other.End) <= 0 && other.Start.Cmp(r.End) <= 0
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("[%s, %s]", r.Start.HexSimplified(), r.End.HexSimplified())
+}
@@ -3,6 +3,7 @@ package bits
 import (
 	"math/big"
 	"testing"
+	"testing/quick"
 )
 
 func TestMaxRange(t *testing.T) {
@@ -46,3 +47,60 @@ func TestRange_IntervalP(t *testing.T) {
 		lastEnd = ival.End
 	}
 }
+
+func TestPrefixRange(t *testing.T) {
+	b := FromShortHexP("abc")
+	r := PrefixRange(b, NumBits-8)
+	if !r.Start.Equals(FromShortHexP("a00")) || !r.End.Equals(FromShortHexP("aff")) {
+		t.Errorf("unexpected range %s", r)
+	}
+	if !PrefixRange(b, 0).Start.Equals(MaxRange().Start) || !PrefixRange(b, 0).End.Equals(MaxRange().End) {
+		t.Errorf("a 0-bit prefix should cover everything")
+	}
+	if r := PrefixRange(b, NumBits); !r.Start.Equals(b) || !r.End.Equals(b) {
+		t.Errorf("a full prefix should only cover b, got %s", r)
+	}
+}
+
+func TestRange_Properties(t *testing.T) {
+	properties := map[string]interface{}{
+		"prefix range contains exactly the bitmaps with the prefix": func(a, b Bitmap, n uint16) bool {
+			n %= NumBits + 1
+			return PrefixRange(a, int(n)).Contains(b) == (a.CommonPrefixLen(b) >= int(n))
+		},
+		"splitting a prefix range gives the two longer prefix ranges": func(a Bitmap, n uint16) bool {
+			n %= NumBits
+			left, right := PrefixRange(a, int(n)).Split()
+			zero, one := PrefixRange(a.Set(int(n), false), int(n)+1), PrefixRange(a.Set(int(n), true), int(n)+1)
+			return left == zero && right == one
+		},
+		"split halves partition the range": func(a, b Bitmap) bool {
+			if a.Cmp(b) >= 0 {
+				a, b = b, a
+			}
+			if a.Equals(b) {
+				return true
+			}
+			left, right := Range{Start: a, End: b}.Split()
+			return left.Start.Equals(a) && right.End.Equals(b) && left.End.Add(FromShortHexP("1")).Equals(right.Start) &&
+				!left.Overlaps(right) && left.Overlaps(Range{Start: a, End: b})
+		},
+		"overlap is symmetric": func(a, b, c, d Bitmap) bool {
+			if a.Cmp(b) > 0 {
+				a, b = b, a
+			}
+			if c.Cmp(d) > 0 {
+				c, d = d, c
+			}
+			r1, r2 := Range{Start: a, End: b}, Range{Start: c, End: d}
+			overlaps := r1.Overlaps(r2)
+			return overlaps == r2.Overlaps(r1) && overlaps == (r1.Contains(c) || r2.Contains(a))
+		},
+	}
+
+	for name, f := range properties {
+		if err := quick.Check(f, nil); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
@@ -57,8 +57,9 @@ func (b *BootstrapNode) Connect(conn UDPConn) error {
 
 	log.Infof("[%s] bootstrap: node connected", b.id.HexShort())
 
-	go func() {
+	b.grp.Go("bootstrap node checks", func() {
 		t := time.NewTicker(b.checkInterval / 5)
+		defer t.Stop()
 		for {
 			select {
 			case <-t.C:
@@ -67,7 +68,7 @@ func (b *BootstrapNode) Connect(conn UDPConn) error {
 				return
 			}
 		}
-	}()
+	})
 
 	return nil
 }
@@ -183,13 +184,17 @@ func (b *BootstrapNode) handleRequest(addr *net.UDPAddr, request Request) {
 		}
 	}
 
-	go func() {
+	b.grp.Go("bootstrap node ping", func() {
 		b.nlock.RLock()
 		_, exists := b.peers[request.NodeID]
 		b.nlock.RUnlock()
 		if !exists {
 			log.Debugf("[%s] bootstrap: queuing %s to ping", b.id.HexShort(), request.NodeID.HexShort())
-			<-time.After(b.initialPingInterval)
+			select {
+			case <-time.After(b.initialPingInterval):
+			case <-b.grp.Ch():
+				return
+			}
 			b.nlock.RLock()
 			_, exists = b.peers[request.NodeID]
 			b.nlock.RUnlock()
@@ -197,7 +202,7 @@ func (b *BootstrapNode) handleRequest(addr *net.UDPAddr, request Request) {
 				b.ping(Contact{ID: request.NodeID, IP: addr.IP, Port: addr.Port})
 			}
 		}
-	}()
+	})
 }
 
 func randKeys(max int) []int {
@@ -15,6 +15,8 @@ const (
 	DefaultAnnounceRate   = 10               // send at most this many announces per second
 	DefaultReannounceTime = 50 * time.Minute // should be a bit less than hash expiration time
 
+	DefaultAnnouncedBlobsPollInterval = 5 * time.Minute // how often AnnouncedBlobsSource is checked for new or removed blobs
+
 	// TODO: all these constants should be defaults, and should be used to set values in the standard Config. then the code should use values in the config
 	// TODO: alternatively, have a global Config for constants. at least that way tests can modify the values
 	alpha           = 5             // this is the constant alpha in the spec
@@ -27,6 +29,9 @@ const (
 	udpMaxMessageLength = 4096 // bytes. I think our longest message is ~676 bytes, so I rounded up to 1024
 	//                            scratch that. a findValue could return more than K results if a lot of nodes are storing that value, so we need more buffer
 
+	packetWorkers     = 16   // number of goroutines handling incoming packets
+	packetQueueLength = 1024 // packets waiting to be handled. the reader blocks when this is full
+
 	maxPeerFails = 3 // after this many failures, a peer is considered bad and will be removed from the routing table
 	//tExpire     = 60 * time.Minute // the time after which a key/value pair expires; this is a time-to-live (TTL) from the original publication date
 	tRefresh = 1 * time.Hour // the time after which an otherwise unaccessed bucket must be refreshed
@@ -35,7 +40,29 @@ const (
 
 	compactNodeInfoLength = nodeIDLength + 6 // nodeID + 4 for IP + 2 for port
 
+	// the newest version of the protocol we speak. version 0 nodes don't send a version, and don't expect one in
+	// findValue responses. version 2 nodes accept cached peers in store requests
+	protocolVersion = 2
+	// the first version that accepts cached peers
+	cachedStoreVersion = 2
+	// remember the versions of at most this many nodes. an active node's version is learned again from its next message
+	maxRemoteVersions = 4096
+
+	maxCacheTTL      = 1 * time.Hour    // how long peers cached by a lookup are kept by the node closest to the target
+	minCacheTTL      = 1 * time.Minute  // don't bother caching peers for less time than this
+	maxCachedPeers   = bucketSize       // send at most this many peers in a cache store
+	storeCleanupRate = 10 * time.Minute // how often expired cached peers are removed from the store
+
+	reachabilityPeers         = bucketSize      // how many routing table peers CheckReachability asks about us
+	reachabilityWindow        = tRefresh        // inbound udp works if a peer contacted us first this recently
+	reachabilityFirstCheck    = 2 * time.Minute // how long after joining to check reachability for the first time
+	reachabilityCheckInterval = tRefresh        // how often to check reachability after that
+	natHoleLifetime           = 5 * time.Minute // how long a nat lets replies in after we send to an address
+	inboundTrackerMaxAddrs    = 4096            // start forgetting old addresses we sent to after this many
+
 	tokenSecretRotationInterval = 5 * time.Minute // how often the token-generating secret is rotated
+
+	shutdownWarnTimeout = 10 * time.Second // how long to wait on shutdown before logging the goroutines that are still running
 )
 
 // Config represents the configure of dht.
@@ -58,6 +85,24 @@ type Config struct {
 	AnnounceRate int
 	// channel that will receive notifications about announcements
 	AnnounceNotificationCh chan announceNotification
+	// channel that will receive the outcome of each attempt to join the network. it should be buffered, since an
+	// outcome is dropped if nothing is ready to receive it
+	BootstrapNotificationCh chan BootstrapStatus
+	// if set, the routing table is saved to this file on shutdown. if none of the seed nodes respond when joining,
+	// the contacts in this file are tried instead
+	RoutingTableFile string
+	// if set, this is notified about every message the node sends and receives, instead of the default debug logging
+	MessageObserver MessageObserver
+	// if true, try to forward the dht port on the local router using UPnP or NAT-PMP
+	NATTraversal bool
+	// if set, the blobs in this source are announced, and blobs that disappear from it stop being announced, so
+	// Add and Remove don't need to be called for each blob
+	AnnouncedBlobsSource AnnouncedBlobsSource
+	// how often to check AnnouncedBlobsSource. DefaultAnnouncedBlobsPollInterval if 0
+	AnnouncedBlobsPollInterval time.Duration
+	// how long to wait for a response to a request, and how often a lookup that's waiting on responses sends more
+	// requests. 5 seconds if 0. simulations on an in-memory network can make this much shorter
+	RequestTimeout time.Duration
 }
 
 // NewStandardConfig returns a Config pointer with default values.
@@ -0,0 +1,167 @@
+package dht
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// limiters for addresses that haven't sent anything in this long are forgotten
+	filterLimiterIdle = time.Minute
+	// how many addresses to keep limiters for before forgetting idle ones
+	filterMaxAddrs = 4096
+)
+
+// FilterConn wraps a UDPConn and drops packets from banned addresses, and from addresses that send more than the rate
+// limit, before the node parses them. It's meant for seed nodes that are exposed to everyone.
+type FilterConn struct {
+	UDPConn
+
+	lock     *sync.RWMutex
+	banned   []*net.IPNet
+	limit    rate.Limit
+	burst    int
+	limiters map[string]*filterLimiter
+
+	droppedBanned  *atomic.Uint64
+	droppedLimited *atomic.Uint64
+}
+
+type filterLimiter struct {
+	*rate.Limiter
+	lastSeen time.Time
+}
+
+// FilterStats counts the packets a FilterConn dropped
+type FilterStats struct {
+	Banned      uint64
+	RateLimited uint64
+}
+
+// NewFilterConn returns conn with no bans and no rate limit
+func NewFilterConn(conn UDPConn) *FilterConn {
+	return &FilterConn{
+		UDPConn:        conn,
+		lock:           &sync.RWMutex{},
+		limit:          rate.Inf,
+		limiters:       make(map[string]*filterLimiter),
+		droppedBanned:  atomic.NewUint64(0),
+		droppedLimited: atomic.NewUint64(0),
+	}
+}
+
+// SetBanList replaces the banned addresses. Entries are ips or cidr ranges.
+func (c *FilterConn) SetBanList(entries []string) error {
+	banned := make([]*net.IPNet, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if !strings.Contains(e, "/") {
+			ip := net.ParseIP(e)
+			if ip == nil {
+				return errors.Err("invalid ip in ban list: %s", e)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			banned = append(banned, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(e)
+		if err != nil {
+			return errors.Err("invalid range in ban list: %s", e)
+		}
+		banned = append(banned, ipNet)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.banned = banned
+	return nil
+}
+
+// SetRateLimit limits how many packets per second each ip can send, with bursts of up to burst packets. A limit of 0
+// turns rate limiting off.
+func (c *FilterConn) SetRateLimit(perSecond float64, burst int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.limit = rate.Limit(perSecond)
+	if perSecond <= 0 {
+		c.limit = rate.Inf
+	}
+	c.burst = burst
+	if c.burst < 1 {
+		c.burst = 1
+	}
+	c.limiters = make(map[string]*filterLimiter)
+}
+
+// Stats returns how many packets have been dropped
+func (c *FilterConn) Stats() FilterStats {
+	return FilterStats{Banned: c.droppedBanned.Load(), RateLimited: c.droppedLimited.Load()}
+}
+
+// ReadFromUDP returns the next packet that isn't filtered out
+func (c *FilterConn) ReadFromUDP(b []byte) (int, *net.UDPAddr, error) {
+	for {
+		n, addr, err := c.UDPConn.ReadFromUDP(b)
+		if err != nil || addr == nil {
+			return n, addr, err
+		}
+		if c.isBanned(addr.IP) {
+			c.droppedBanned.Inc()
+			continue
+		}
+		if !c.allow(addr.IP) {
+			c.droppedLimited.Inc()
+			continue
+		}
+		return n, addr, nil
+	}
+}
+
+func (c *FilterConn) isBanned(ip net.IP) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for _, b := range c.banned {
+		if b.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *FilterConn) allow(ip net.IP) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.limit == rate.Inf {
+		return true
+	}
+
+	now := time.Now()
+	key := ip.String()
+	l, ok := c.limiters[key]
+	if !ok {
+		if len(c.limiters) >= filterMaxAddrs {
+			for k, other := range c.limiters {
+				if now.Sub(other.lastSeen) > filterLimiterIdle {
+					delete(c.limiters, k)
+				}
+			}
+		}
+		l = &filterLimiter{Limiter: rate.NewLimiter(c.limit, c.burst)}
+		c.limiters[key] = l
+	}
+	l.lastSeen = now
+	return l.AllowN(now, 1)
+}
@@ -0,0 +1,86 @@
+package dht
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// queueConn returns queued packets from reads, and an error once they run out
+type queueConn struct {
+	packets []testUDPPacket
+}
+
+func (q *queueConn) ReadFromUDP(b []byte) (int, *net.UDPAddr, error) {
+	if len(q.packets) == 0 {
+		return 0, nil, errors.Err("no more packets")
+	}
+	p := q.packets[0]
+	q.packets = q.packets[1:]
+	return copy(b, p.data), p.addr, nil
+}
+func (q *queueConn) WriteToUDP(b []byte, addr *net.UDPAddr) (int, error) { return len(b), nil }
+func (q *queueConn) SetReadDeadline(t time.Time) error                   { return nil }
+func (q *queueConn) SetWriteDeadline(t time.Time) error                  { return nil }
+func (q *queueConn) Close() error                                        { return nil }
+
+func readAll(conn UDPConn) []string {
+	var from []string
+	buf := make([]byte, 16)
+	for {
+		_, addr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return from
+		}
+		from = append(from, addr.IP.String())
+	}
+}
+
+func TestFilterConn_BanList(t *testing.T) {
+	q := &queueConn{}
+	for _, ip := range []string{"1.2.3.4", "10.0.0.5", "5.6.7.8", "2001:db8::1", "10.1.0.1"} {
+		q.packets = append(q.packets, testUDPPacket{data: []byte("x"), addr: &net.UDPAddr{IP: net.ParseIP(ip), Port: 4444}})
+	}
+
+	conn := NewFilterConn(q)
+	err := conn.SetBanList([]string{"1.2.3.4", "10.0.0.0/16", " ", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := readAll(conn)
+	if len(from) != 2 || from[0] != "5.6.7.8" || from[1] != "10.1.0.1" {
+		t.Errorf("unexpected packets let through: %v", from)
+	}
+	if s := conn.Stats(); s.Banned != 3 || s.RateLimited != 0 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+
+	if err := conn.SetBanList([]string{"not an ip"}); err == nil {
+		t.Error("expected an error for an invalid entry")
+	}
+	if err := conn.SetBanList([]string{"1.2.3.4/99"}); err == nil {
+		t.Error("expected an error for an invalid range")
+	}
+}
+
+func TestFilterConn_RateLimit(t *testing.T) {
+	q := &queueConn{}
+	for i := 0; i < 5; i++ {
+		q.packets = append(q.packets, testUDPPacket{data: []byte("x"), addr: &net.UDPAddr{IP: net.ParseIP("1.1.1.1"), Port: 4444}})
+		q.packets = append(q.packets, testUDPPacket{data: []byte("x"), addr: &net.UDPAddr{IP: net.ParseIP("2.2.2.2"), Port: 4444}})
+	}
+
+	conn := NewFilterConn(q)
+	conn.SetRateLimit(0.001, 2)
+
+	from := readAll(conn)
+	if len(from) != 4 {
+		t.Errorf("expected 2 packets from each address, got %v", from)
+	}
+	if s := conn.Stats(); s.RateLimited != 6 {
+		t.Errorf("expected 6 packets to be rate limited, got %+v", s)
+	}
+}
@@ -3,7 +3,9 @@ package dht
 import (
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lbryio/lbry.go/v2/dht/bits"
@@ -42,31 +44,56 @@ type DHT struct {
 	tokenCache *tokenCache
 	// hashes that need to be put into the announce queue or removed from the queue
 	announceAddRemove chan queueEdit
+	// signals that AnnouncedBlobsSource should be checked now
+	blobsChanged chan struct{}
+	// port forwarding on the router, if NATTraversal is on
+	portMapping *natPortMapping
+	// the last reachability check
+	reachabilityLock *sync.RWMutex
+	reachability     *Reachability
+	// looks up seed node addresses
+	resolve func(addr string) (*net.UDPAddr, error)
+	// the address each seed node resolved to last. only used by the goroutine that joins the network
+	seedAddrs map[string]string
 }
 
-// New returns a DHT pointer. If config is nil, then config will be set to the default config.
-func New(config *Config) *DHT {
+// New returns a DHT pointer. If config is nil, then config will be set to the default config. An error is returned
+// if the node ID or address in the config are invalid.
+func New(config *Config) (*DHT, error) {
 	if config == nil {
 		config = NewStandardConfig()
 	}
 
+	contact, err := getContact(config.NodeID, config.Address)
+	if err != nil {
+		return nil, err
+	}
+
 	d := &DHT{
 		conf:              config,
-		grp:               stop.New(),
+		contact:           contact,
+		grp:               stop.NewNamed("dht"),
 		joined:            make(chan struct{}),
 		announceAddRemove: make(chan queueEdit),
+		blobsChanged:      make(chan struct{}, 1),
+		reachabilityLock:  &sync.RWMutex{},
+		seedAddrs:         make(map[string]string),
 	}
-	return d
+	d.resolve = func(addr string) (*net.UDPAddr, error) {
+		raddr, err := net.ResolveUDPAddr(Network, addr)
+		return raddr, errors.Err(err)
+	}
+	return d, nil
 }
 
 func (dht *DHT) connect(conn UDPConn) error {
-	contact, err := getContact(dht.conf.NodeID, dht.conf.Address)
-	if err != nil {
-		return err
+	dht.node = NewNode(dht.contact.ID)
+	if dht.conf.MessageObserver != nil {
+		dht.node.observer = dht.conf.MessageObserver
+	}
+	if dht.conf.RequestTimeout > 0 {
+		dht.node.timeout = dht.conf.RequestTimeout
 	}
-
-	dht.contact = contact
-	dht.node = NewNode(contact.ID)
 	dht.tokenCache = newTokenCache(dht.node, tokenSecretRotationInterval)
 
 	return dht.node.Connect(conn)
@@ -78,64 +105,80 @@ func (dht *DHT) Start() error {
 	if err != nil {
 		return errors.Err(err)
 	}
-	conn := listener.(*net.UDPConn)
+	return dht.StartWithConn(listener.(*net.UDPConn))
+}
 
-	err = dht.connect(conn)
+// StartWithConn starts the dht using the given connection instead of listening on the configured address. This lets
+// the dht run over something other than a real UDP socket, such as the in-memory network in dhttest.
+func (dht *DHT) StartWithConn(conn UDPConn) error {
+	err := dht.connect(conn)
 	if err != nil {
 		return err
 	}
 
+	if dht.conf.NATTraversal {
+		err = dht.mapPort()
+		if err != nil {
+			log.Error(errors.Prefix(fmt.Sprintf("[%s] nat traversal", dht.node.id.HexShort()), err))
+		}
+	}
+
 	dht.join()
+
+	if ip := dht.node.ExternalIP(); ip != nil && !ip.Equal(dht.contact.IP) && (dht.contact.IP.IsUnspecified() || dht.contact.IP.IsPrivate()) {
+		log.Infof("[%s] peers see us at %s", dht.node.id.HexShort(), ip.String())
+		dht.contact.IP = ip
+	}
+
 	log.Infof("[%s] DHT ready on %s (%d nodes found during join)",
 		dht.node.id.HexShort(), dht.contact.Addr().String(), dht.node.rt.Count())
 
-	dht.grp.Add(1)
-	go func() {
-		dht.runAnnouncer()
-		dht.grp.Done()
-	}()
+	dht.grp.Go("announcer", dht.runAnnouncer)
+	dht.grp.Go("reachability checks", dht.runReachabilityChecks)
+	if dht.conf.AnnouncedBlobsSource != nil {
+		dht.grp.Go("blob source", dht.runBlobSource)
+	}
 
 	if dht.conf.RPCPort > 0 {
-		dht.grp.Add(1)
-		go func() {
-			dht.runRPCServer(dht.conf.RPCPort)
-			dht.grp.Done()
-		}()
+		dht.grp.Go("rpc server", func() { dht.runRPCServer(dht.conf.RPCPort) })
 	}
 
 	return nil
 }
 
-// join makes current node join the dht network.
-func (dht *DHT) join() {
-	defer close(dht.joined) // if anyone's waiting for join to finish, they'll know its done
-
-	log.Infof("[%s] joining DHT network", dht.node.id.HexShort())
+// Run starts the dht and blocks until it is shut down. Errors binding the address or connecting are returned
+// immediately.
+func (dht *DHT) Run() error {
+	err := dht.Start()
+	if err != nil {
+		return err
+	}
+	<-dht.grp.Ch()
+	return nil
+}
 
-	// ping nodes, which gets their real node IDs and adds them to the routing table
-	atLeastOneNodeResponded := false
-	for _, addr := range dht.conf.SeedNodes {
-		err := dht.Ping(addr)
-		if err != nil {
-			log.Error(errors.Prefix(fmt.Sprintf("[%s] join", dht.node.id.HexShort()), err))
-		} else {
-			atLeastOneNodeResponded = true
-		}
+// mapPort forwards the dht port on the router and advertises the router's external address as our own
+func (dht *DHT) mapPort() error {
+	nat, err := discoverNAT()
+	if err != nil {
+		return err
 	}
 
-	if !atLeastOneNodeResponded {
-		log.Errorf("[%s] join: no nodes responded to initial ping", dht.node.id.HexShort())
-		return
+	ip, err := nat.ExternalIP()
+	if err != nil {
+		return err
 	}
 
-	// now call iterativeFind on yourself
-	_, _, err := FindContacts(dht.node, dht.node.id, false, dht.grp.Child())
+	mapping, err := mapPort(nat, "udp", dht.contact.Port, dht.grp)
 	if err != nil {
-		log.Errorf("[%s] join: %s", dht.node.id.HexShort(), err.Error())
+		return err
 	}
 
-	// TODO: after joining, refresh all buckets further away than our closest neighbor
-	// http://xlattice.sourceforge.net/components/protocol/kademlia/specs.html#join
+	log.Infof("[%s] mapped port %d to %s:%d using %s", dht.node.id.HexShort(), dht.contact.Port, ip.String(), mapping.externalPort, nat.String())
+	dht.portMapping = mapping
+	dht.contact.IP = ip
+	dht.contact.Port = mapping.externalPort
+	return nil
 }
 
 // WaitUntilJoined blocks until the node joins the network.
@@ -148,10 +191,23 @@ func (dht *DHT) WaitUntilJoined() {
 
 // Shutdown shuts down the dht
 func (dht *DHT) Shutdown() {
-	log.Debugf("[%s] DHT shutting down", dht.node.id.HexShort())
-	dht.grp.StopAndWait()
-	dht.node.Shutdown()
-	log.Debugf("[%s] DHT stopped", dht.node.id.HexShort())
+	log.Debugf("[%s] DHT shutting down", dht.contact.ID.HexShort())
+	err := dht.grp.StopAndWaitTimeout(shutdownWarnTimeout)
+	if err != nil {
+		log.Warnf("[%s] DHT is slow to shut down: %v", dht.contact.ID.HexShort(), err)
+		dht.grp.Wait()
+	}
+	err = dht.saveContacts()
+	if err != nil {
+		log.Error(errors.Prefix("saving routing table", err))
+	}
+	if dht.portMapping != nil {
+		dht.portMapping.Stop()
+	}
+	if dht.node != nil {
+		dht.node.Shutdown()
+	}
+	log.Debugf("[%s] DHT stopped", dht.contact.ID.HexShort())
 }
 
 // Ping pings a given address, creates a temporary contact for sending a message, and returns an error if communication
@@ -159,12 +215,14 @@ func (dht *DHT) Shutdown() {
 func (dht *DHT) Ping(addr string) error {
 	raddr, err := net.ResolveUDPAddr(Network, addr)
 	if err != nil {
-		return err
+		return errors.Err(err)
 	}
 
 	tmpNode := Contact{ID: bits.Rand(), IP: raddr.IP, Port: raddr.Port}
-	res := dht.node.Send(tmpNode, Request{Method: pingMethod}, SendOptions{skipIDCheck: true})
-	if res == nil {
+	res, err := dht.node.Send(tmpNode, Request{Method: pingMethod}, SendOptions{skipIDCheck: true})
+	if err != nil {
+		return err
+	} else if res == nil {
 		return errors.Err("no response from node %s", addr)
 	}
 
@@ -173,7 +231,7 @@ func (dht *DHT) Ping(addr string) error {
 
 // Get returns the list of nodes that have the blob for the given hash
 func (dht *DHT) Get(hash bits.Bitmap) ([]Contact, error) {
-	contacts, found, err := FindContacts(dht.node, hash, true, dht.grp.Child())
+	contacts, found, err := FindContacts(dht.node, hash, true, dht.grp.ChildNamed("get"))
 	if err != nil {
 		return nil, err
 	}
@@ -184,12 +242,66 @@ func (dht *DHT) Get(hash bits.Bitmap) ([]Contact, error) {
 	return nil, nil
 }
 
+// FindPeers returns the blob protocol addresses of the peers that have the blob with the given hex-encoded hash. It
+// makes the dht a stream.PeerFinder.
+func (dht *DHT) FindPeers(hash string) ([]string, error) {
+	h, err := bits.FromHex(hash)
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := dht.Get(h)
+	if err != nil {
+		return nil, err
+	}
+	peers := make([]string, 0, len(contacts))
+	for _, c := range contacts {
+		peers = append(peers, net.JoinHostPort(c.IP.String(), strconv.Itoa(c.PeerPort)))
+	}
+	return peers, nil
+}
+
+// Stats is a snapshot of the dht's state
+type Stats struct {
+	NodeID             string
+	Address            string
+	Contacts           int
+	StoredHashes       int
+	ActiveTransactions int
+	// the ip that enough peers agree they see us at, or nil
+	ExternalIP net.IP
+	// the last reachability check, or nil if there hasn't been one yet
+	Reachability *Reachability
+}
+
+// Stats returns a snapshot of the dht's state
+func (dht *DHT) Stats() Stats {
+	dht.reachabilityLock.RLock()
+	reachability := dht.reachability
+	dht.reachabilityLock.RUnlock()
+
+	return Stats{
+		NodeID:             dht.contact.ID.Hex(),
+		Address:            dht.contact.Addr().String(),
+		Contacts:           dht.node.rt.Count(),
+		StoredHashes:       dht.node.store.CountStoredHashes(),
+		ActiveTransactions: dht.node.CountActiveTransactions(),
+		ExternalIP:         dht.node.ExternalIP(),
+		Reachability:       reachability,
+	}
+}
+
 // PrintState prints the current state of the DHT including address, nr outstanding transactions, stored hashes as well
 // as current bucket information.
 func (dht *DHT) PrintState() {
 	log.Printf("DHT node %s at %s", dht.contact.String(), time.Now().Format(time.RFC822Z))
 	log.Printf("Outstanding transactions: %d", dht.node.CountActiveTransactions())
 	log.Printf("Stored hashes: %d", dht.node.store.CountStoredHashes())
+	if r := dht.Stats().Reachability; r != nil {
+		log.Printf("Reachability at %s: %s", r.CheckedAt.Format(time.RFC822Z), r)
+		for _, p := range r.Problems() {
+			log.Printf("  %s", p)
+		}
+	}
 	log.Printf("Buckets:")
 	for _, line := range strings.Split(dht.node.rt.BucketInfo(), "\n") {
 		log.Println(line)
@@ -205,7 +317,11 @@ func getContact(nodeID, addr string) (Contact, error) {
 	if nodeID == "" {
 		c.ID = bits.Rand()
 	} else {
-		c.ID = bits.FromHexP(nodeID)
+		id, err := bits.FromHex(nodeID)
+		if err != nil {
+			return c, err
+		}
+		c.ID = id
 	}
 
 	ip, port, err := net.SplitHostPort(addr)
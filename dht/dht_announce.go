@@ -2,7 +2,6 @@ package dht
 
 import (
 	"container/ring"
-	"context"
 	"math"
 	"sync"
 	"time"
@@ -10,6 +9,7 @@ import (
 	"github.com/lbryio/lbry.go/v2/dht/bits"
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 
+	"go.uber.org/atomic"
 	"golang.org/x/time/rate"
 )
 
@@ -31,12 +31,22 @@ type announceNotification struct {
 
 // Add adds the hash to the list of hashes this node is announcing
 func (dht *DHT) Add(hash bits.Bitmap) {
-	dht.announceAddRemove <- queueEdit{hash: hash, add: true}
+	dht.editAnnounceQueue(queueEdit{hash: hash, add: true})
 }
 
 // Remove removes the hash from the list of hashes this node is announcing
 func (dht *DHT) Remove(hash bits.Bitmap) {
-	dht.announceAddRemove <- queueEdit{hash: hash, add: false}
+	dht.editAnnounceQueue(queueEdit{hash: hash, add: false})
+}
+
+// editAnnounceQueue hands the edit to the announcer. It returns false if the dht shut down first.
+func (dht *DHT) editAnnounceQueue(edit queueEdit) bool {
+	select {
+	case dht.announceAddRemove <- edit:
+		return true
+	case <-dht.grp.Ch():
+		return false
+	}
 }
 
 func (dht *DHT) runAnnouncer() {
@@ -53,13 +63,14 @@ func (dht *DHT) runAnnouncer() {
 	timer.Stop()
 
 	limitCh := make(chan time.Time)
-	dht.grp.Add(1)
-	go func() {
-		defer dht.grp.Done()
+	dht.grp.Go("announce limiter", func() {
 		limiter := rate.NewLimiter(rate.Limit(dht.conf.AnnounceRate), dht.conf.AnnounceRate)
 		for {
-			err := limiter.Wait(context.Background()) // TODO: should use grp.ctx somehow? so when grp is closed, wait returns
+			err := limiter.Wait(dht.grp.Context())
 			if err != nil {
+				if dht.grp.Context().Err() != nil {
+					return
+				}
 				log.Error(errors.Prefix("rate limiter", err))
 				continue
 			}
@@ -69,7 +80,7 @@ func (dht *DHT) runAnnouncer() {
 				return
 			}
 		}
-	}()
+	})
 
 	maintenance := time.NewTicker(1 * time.Minute)
 
@@ -122,7 +133,6 @@ func (dht *DHT) runAnnouncer() {
 			}
 
 		case <-announceNextHash:
-			dht.grp.Add(1)
 			ht := queue.Value.(hashAndTime)
 
 			if !ht.lastAnnounce.IsZero() {
@@ -141,21 +151,21 @@ func (dht *DHT) runAnnouncer() {
 				}
 			}
 
-			go func(hash bits.Bitmap) {
-				defer dht.grp.Done()
-				err := dht.announce(hash)
+			hash := ht.hash
+			dht.grp.Go("announce", func() {
+				err := dht.Announce(hash)
 				if err != nil {
 					log.Error(errors.Prefix("announce", err))
 				}
 
 				if dht.conf.AnnounceNotificationCh != nil {
 					dht.conf.AnnounceNotificationCh <- announceNotification{
-						hash:   ht.hash,
+						hash:   hash,
 						action: announceFinishd,
 						err:    err,
 					}
 				}
-			}(ht.hash)
+			})
 
 			queue.Value = hashAndTime{hash: ht.hash, lastAnnounce: time.Now()}
 			queue = queue.Next()
@@ -164,11 +174,23 @@ func (dht *DHT) runAnnouncer() {
 	}
 }
 
-// Announce announces to the DHT that this node has the blob for the given hash
-func (dht *DHT) announce(hash bits.Bitmap) error {
-	contacts, _, err := FindContacts(dht.node, hash, false, dht.grp.Child())
+// Announce announces to the DHT that this node has the blob for the given hash. It doesn't wait for the nodes it
+// announces to to respond.
+func (dht *DHT) Announce(hash bits.Bitmap) error {
+	_, err := dht.announce(hash, false)
+	return err
+}
+
+// AnnounceAndWait announces like Announce, but waits until every node responds or times out, and returns how many
+// of them stored the hash. This node counts if it's one of the closest to the hash.
+func (dht *DHT) AnnounceAndWait(hash bits.Bitmap) (int, error) {
+	return dht.announce(hash, true)
+}
+
+func (dht *DHT) announce(hash bits.Bitmap, wait bool) (int, error) {
+	contacts, _, err := FindContacts(dht.node, hash, false, dht.grp.ChildNamed("announce"))
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// self-store if we found less than K contacts, or we're closer than the farthest contact
@@ -178,29 +200,38 @@ func (dht *DHT) announce(hash bits.Bitmap) error {
 		contacts[bucketSize-1] = dht.contact
 	}
 
+	stored := atomic.NewInt32(0)
 	wg := &sync.WaitGroup{}
 	for _, c := range contacts {
 		wg.Add(1)
 		go func(c Contact) {
-			dht.store(hash, c)
-			wg.Done()
+			defer wg.Done()
+			resCh := dht.store(hash, c)
+			if resCh == nil {
+				stored.Inc()
+			} else if wait {
+				if res := <-resCh; res != nil && res.Data == storeSuccessResponse {
+					stored.Inc()
+				}
+			}
 		}(c)
 	}
 
 	wg.Wait()
 
-	return nil
+	return int(stored.Load()), nil
 }
 
-func (dht *DHT) store(hash bits.Bitmap, c Contact) {
+// store stores the hash on c. It returns the channel the response will come on, or nil if c is this node
+func (dht *DHT) store(hash bits.Bitmap, c Contact) <-chan *Response {
 	if dht.contact.ID == c.ID {
 		// self-store
 		c.PeerPort = dht.conf.PeerProtocolPort
 		dht.node.Store(hash, c)
-		return
+		return nil
 	}
 
-	dht.node.SendAsync(c, Request{
+	return dht.node.SendAsync(c, Request{
 		Method: storeMethod,
 		StoreArgs: &storeArgs{
 			BlobHash: hash,
@@ -0,0 +1,78 @@
+package dht
+
+import (
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/dht/bits"
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// AnnouncedBlobsSource tells the dht which blobs this node can serve. store.DiskStore is one.
+type AnnouncedBlobsSource interface {
+	// Hashes returns the hex-encoded hashes of the blobs that the node has
+	Hashes() []string
+}
+
+// SyncAnnouncedBlobs makes the dht check Config.AnnouncedBlobsSource now instead of at the next poll, e.g. right
+// after a stream was stored
+func (dht *DHT) SyncAnnouncedBlobs() {
+	select {
+	case dht.blobsChanged <- struct{}{}:
+	default: // a check is already coming up
+	}
+}
+
+// runBlobSource keeps the announce queue the same as the blobs in the source until the dht shuts down
+func (dht *DHT) runBlobSource() {
+	interval := dht.conf.AnnouncedBlobsPollInterval
+	if interval <= 0 {
+		interval = DefaultAnnouncedBlobsPollInterval
+	}
+	poll := time.NewTicker(interval)
+	defer poll.Stop()
+
+	announced := make(map[bits.Bitmap]bool)
+	for {
+		if !dht.syncAnnouncedBlobs(announced) {
+			return
+		}
+		select {
+		case <-poll.C:
+		case <-dht.blobsChanged:
+		case <-dht.grp.Ch():
+			return
+		}
+	}
+}
+
+// syncAnnouncedBlobs adds blobs that are new in the source and removes blobs that are gone from it. announced is the
+// set of hashes that were added last time, and is updated. It returns false if the dht shut down.
+func (dht *DHT) syncAnnouncedBlobs(announced map[bits.Bitmap]bool) bool {
+	current := make(map[bits.Bitmap]bool)
+	for _, hash := range dht.conf.AnnouncedBlobsSource.Hashes() {
+		b, err := bits.FromHex(hash)
+		if err != nil {
+			log.Warn(errors.Prefix("blob source has an invalid hash "+hash, err))
+			continue
+		}
+		current[b] = true
+	}
+
+	for b := range current {
+		if !announced[b] {
+			if !dht.editAnnounceQueue(queueEdit{hash: b, add: true}) {
+				return false
+			}
+			announced[b] = true
+		}
+	}
+	for b := range announced {
+		if !current[b] {
+			if !dht.editAnnounceQueue(queueEdit{hash: b, add: false}) {
+				return false
+			}
+			delete(announced, b)
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package dht
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/dht/bits"
+	"github.com/lbryio/lbry.go/v2/store"
+)
+
+var _ AnnouncedBlobsSource = (*store.DiskStore)(nil)
+
+type testBlobSource struct {
+	mu     sync.Mutex
+	hashes []string
+}
+
+func (s *testBlobSource) Hashes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hashes
+}
+
+func (s *testBlobSource) set(hashes ...bits.Bitmap) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hashes = []string{"not a hash"}
+	for _, h := range hashes {
+		s.hashes = append(s.hashes, h.Hex())
+	}
+}
+
+func TestDHT_AnnouncedBlobsSource(t *testing.T) {
+	a, b, c := bits.Rand(), bits.Rand(), bits.Rand()
+	source := &testBlobSource{}
+	source.set(a, b)
+
+	conf := NewStandardConfig()
+	conf.AnnouncedBlobsSource = source
+	conf.AnnouncedBlobsPollInterval = time.Hour
+	dht, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dht.grp.Go("blob source", dht.runBlobSource)
+	defer dht.grp.StopAndWait()
+
+	expectEdits := func(expected map[queueEdit]bool) {
+		t.Helper()
+		for len(expected) > 0 {
+			select {
+			case edit := <-dht.announceAddRemove:
+				if !expected[edit] {
+					t.Fatalf("unexpected edit %s add=%t", edit.hash.HexShort(), edit.add)
+				}
+				delete(expected, edit)
+			case <-time.After(time.Second):
+				t.Fatalf("still waiting for %d edits", len(expected))
+			}
+		}
+	}
+
+	expectEdits(map[queueEdit]bool{{hash: a, add: true}: true, {hash: b, add: true}: true})
+
+	source.set(b, c)
+	dht.SyncAnnouncedBlobs()
+	expectEdits(map[queueEdit]bool{{hash: c, add: true}: true, {hash: a, add: false}: true})
+
+	dht.SyncAnnouncedBlobs()
+	select {
+	case edit := <-dht.announceAddRemove:
+		t.Errorf("nothing changed, but got edit %s add=%t", edit.hash.HexShort(), edit.add)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,242 @@
+package dht
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	"go.uber.org/atomic"
+)
+
+const (
+	bootstrapMinBackoff    = 1 * time.Second
+	bootstrapMaxBackoff    = 5 * time.Minute
+	bootstrapCheckInterval = 1 * time.Minute  // how often to check whether we've been cut off from the network
+	seedResolveInterval    = 15 * time.Minute // how often to look up the seed nodes again once we've joined
+	bootstrapPingWorkers   = 16               // how many nodes to ping at once when joining
+	maxSavedContacts       = 4 * bucketSize   // try at most this many contacts from the routing table file, closest first
+)
+
+// BootstrapStatus is the outcome of one attempt to join the network
+type BootstrapStatus struct {
+	// attempts are numbered starting at 1
+	Attempt int
+	// the number of nodes that responded to our initial ping
+	Responded int
+	// true if none of the seed nodes responded and contacts saved from a previous run were tried instead
+	UsedSavedContacts bool
+	// why the attempt failed, or nil if it succeeded
+	Err error
+}
+
+// join makes current node join the dht network. The first attempt happens right away. If it fails, it is retried in
+// the background with exponential backoff. Once joined, the node rejoins if its routing table ever empties out.
+func (dht *DHT) join() {
+	defer close(dht.joined) // if anyone's waiting for join to finish, they'll know its done
+
+	log.Infof("[%s] joining DHT network", dht.node.id.HexShort())
+
+	err := dht.bootstrap(1)
+
+	dht.grp.Go("bootstrap", func() { dht.maintainConnectivity(1, err == nil) })
+
+	// TODO: after joining, refresh all buckets further away than our closest neighbor
+	// http://xlattice.sourceforge.net/components/protocol/kademlia/specs.html#join
+}
+
+// maintainConnectivity retries bootstrapping until it succeeds, and bootstraps again if the node loses all its contacts
+func (dht *DHT) maintainConnectivity(attempt int, joined bool) {
+	backoff := bootstrapMinBackoff
+	lastResolve := time.Now()
+
+	for {
+		wait := bootstrapCheckInterval
+		if !joined {
+			wait = backoff
+			backoff *= 2
+			if backoff > bootstrapMaxBackoff {
+				backoff = bootstrapMaxBackoff
+			}
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-dht.grp.Ch():
+			return
+		}
+
+		if joined {
+			if time.Since(lastResolve) >= seedResolveInterval {
+				lastResolve = time.Now()
+				dht.refreshSeeds()
+			}
+			if dht.node.rt.Count() > 0 {
+				continue
+			}
+			log.Warnf("[%s] routing table is empty, rejoining", dht.node.id.HexShort())
+			backoff = bootstrapMinBackoff
+		}
+
+		attempt++
+		joined = dht.bootstrap(attempt) == nil
+	}
+}
+
+// bootstrap pings the seed nodes and then looks up our own id to fill the routing table. Seed addresses are resolved
+// on every attempt, so seeds that move to a new IP are picked up. If none of the seeds respond, contacts saved from a
+// previous run are tried instead.
+func (dht *DHT) bootstrap(attempt int) error {
+	status := BootstrapStatus{Attempt: attempt}
+
+	// ping nodes, which gets their real node IDs and adds them to the routing table
+	seeds, _ := dht.resolveSeeds()
+	status.Responded = dht.pingAll(seeds)
+
+	if status.Responded == 0 {
+		saved, err := dht.loadSavedContacts()
+		if err != nil {
+			log.Error(errors.Prefix(fmt.Sprintf("[%s] join: loading saved contacts", dht.node.id.HexShort()), err))
+		}
+		if len(saved) > 0 {
+			log.Infof("[%s] join: no seed nodes responded, trying %d saved contacts", dht.node.id.HexShort(), len(saved))
+			status.UsedSavedContacts = true
+			status.Responded = dht.pingAll(saved)
+		}
+	}
+
+	if status.Responded == 0 {
+		status.Err = errors.Err("no nodes responded to initial ping")
+		log.Errorf("[%s] join: attempt %d: %s", dht.node.id.HexShort(), attempt, status.Err.Error())
+	} else {
+		// now call iterativeFind on yourself
+		_, _, err := FindContacts(dht.node, dht.node.id, false, dht.grp.Child())
+		if err != nil {
+			log.Errorf("[%s] join: %s", dht.node.id.HexShort(), err.Error())
+		}
+	}
+
+	if dht.conf.BootstrapNotificationCh != nil {
+		select {
+		case dht.conf.BootstrapNotificationCh <- status:
+		default:
+			log.Warnf("[%s] join: nobody is reading BootstrapNotificationCh, dropping attempt %d", dht.node.id.HexShort(), attempt)
+		}
+	}
+
+	return status.Err
+}
+
+// resolveSeeds looks up the addresses of the seed nodes. It returns all of them, and the ones that weren't among the
+// addresses the seeds resolved to last time.
+func (dht *DHT) resolveSeeds() (all []string, changed []string) {
+	for _, seed := range dht.conf.SeedNodes {
+		raddr, err := dht.resolve(seed)
+		if err != nil {
+			log.Error(errors.Prefix(fmt.Sprintf("[%s] resolving seed %s", dht.node.id.HexShort(), seed), err))
+			continue
+		}
+		addr := raddr.String()
+		all = append(all, addr)
+		if dht.seedAddrs[seed] != addr {
+			dht.seedAddrs[seed] = addr
+			changed = append(changed, addr)
+		}
+	}
+	return all, changed
+}
+
+// refreshSeeds looks up the seed nodes again and pings the ones that moved to a new address, so they're added to the
+// routing table
+func (dht *DHT) refreshSeeds() {
+	_, changed := dht.resolveSeeds()
+	if len(changed) > 0 {
+		log.Infof("[%s] %d seed nodes have new addresses", dht.node.id.HexShort(), len(changed))
+		dht.pingAll(changed)
+	}
+}
+
+// pingAll pings the addresses, bootstrapPingWorkers at a time, and returns how many of them responded
+func (dht *DHT) pingAll(addrs []string) int {
+	responded := atomic.NewInt32(0)
+	sem := make(chan struct{}, bootstrapPingWorkers)
+	var wg sync.WaitGroup
+	for _, addr := range addrs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(addr string) {
+			defer func() { <-sem; wg.Done() }()
+			err := dht.Ping(addr)
+			if err != nil {
+				log.Error(errors.Prefix(fmt.Sprintf("[%s] join", dht.node.id.HexShort()), err))
+			} else {
+				responded.Inc()
+			}
+		}(addr)
+	}
+	wg.Wait()
+	return int(responded.Load())
+}
+
+// loadSavedContacts returns the addresses of the contacts in the routing table file that are closest to us, if there is
+// a file. The contacts are read as they were saved rather than put back into a routing table, which would drop the
+// ones that don't fit in their bucket.
+func (dht *DHT) loadSavedContacts() ([]string, error) {
+	if dht.conf.RoutingTableFile == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(dht.conf.RoutingTableFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Err(err)
+	}
+
+	var saved rtSave
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+
+	contacts := make([]Contact, 0, len(saved.Contacts))
+	for _, s := range saved.Contacts {
+		c, err := parseSavedContact(s)
+		if err != nil {
+			return nil, err
+		}
+		if !c.ID.Equals(dht.node.id) {
+			contacts = append(contacts, c)
+		}
+	}
+	sort.Slice(contacts, func(i, j int) bool { return dht.node.id.Closer(contacts[i].ID, contacts[j].ID) })
+	if len(contacts) > maxSavedContacts {
+		contacts = contacts[:maxSavedContacts]
+	}
+
+	addrs := make([]string, len(contacts))
+	for i, c := range contacts {
+		addrs[i] = c.Addr().String()
+	}
+	return addrs, nil
+}
+
+// saveContacts writes the routing table to the routing table file, so the contacts can be used to rejoin the network
+// if the seed nodes are down next time
+func (dht *DHT) saveContacts() error {
+	if dht.conf.RoutingTableFile == "" || dht.node == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(dht.node.rt)
+	if err != nil {
+		return errors.Err(err)
+	}
+
+	return errors.Err(ioutil.WriteFile(dht.conf.RoutingTableFile, data, 0644))
+}
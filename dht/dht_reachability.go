@@ -0,0 +1,230 @@
+package dht
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/dht/bits"
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// ObservedAddr is the address that a peer has us at in its routing table
+type ObservedAddr struct {
+	Peer bits.Bitmap
+	IP   net.IP
+	Port int
+}
+
+// Reachability is what the network sees of this node. There's no way to ask a peer to ping us, so inbound UDP is
+// judged by whether peers we haven't recently sent anything to have sent us requests. Those requests can't have come
+// through a hole that our own traffic opened in a NAT.
+type Reachability struct {
+	CheckedAt time.Time
+
+	// the address we tell peers to reach us at
+	AdvertisedIP   net.IP
+	AdvertisedPort int
+
+	// how many routing table peers were asked about us, and how many answered
+	Asked     int
+	Responded int
+	// where the peers that know about us have us. lbrynet's python nodes leave the requester out of find_node
+	// results, so against the real network this is usually empty even when we're reachable. it's only a hint, and an
+	// empty list isn't counted as a problem
+	Observed []ObservedAddr
+	// the address most peers have us at, if any do
+	ExternalIP   net.IP
+	ExternalPort int
+	// true if peers have us at a different port than the one we advertise, which usually means a NAT is rewriting it
+	PortMismatch bool
+
+	// when a peer last sent us a request without us having contacted it first, and how many have done it
+	LastUnsolicitedInbound time.Time
+	UnsolicitedInbound     int
+	// true if an unsolicited request arrived within the last reachabilityWindow
+	InboundWorks bool
+}
+
+// Problems describes what looks wrong, or returns nil if nothing does
+func (r *Reachability) Problems() []string {
+	var problems []string
+	if r.Responded == 0 {
+		problems = append(problems, fmt.Sprintf("none of the %d peers asked responded", r.Asked))
+	}
+	if !r.InboundWorks {
+		if r.LastUnsolicitedInbound.IsZero() {
+			problems = append(problems, "no peer has contacted us first, so inbound udp may be blocked")
+		} else {
+			problems = append(problems, fmt.Sprintf("no peer has contacted us first since %s, so inbound udp may be blocked",
+				r.LastUnsolicitedInbound.Format(time.RFC3339)))
+		}
+	}
+	if r.PortMismatch {
+		problems = append(problems, fmt.Sprintf("we advertise port %d but peers see port %d, probably because of a nat",
+			r.AdvertisedPort, r.ExternalPort))
+	}
+	if r.ExternalIP != nil && r.AdvertisedIP != nil && !r.AdvertisedIP.IsUnspecified() && !r.ExternalIP.Equal(r.AdvertisedIP) {
+		problems = append(problems, fmt.Sprintf("we advertise %s but peers see %s", r.AdvertisedIP, r.ExternalIP))
+	}
+	return problems
+}
+
+func (r *Reachability) String() string {
+	seen := "nobody"
+	if r.ExternalIP != nil {
+		seen = net.JoinHostPort(r.ExternalIP.String(), strconv.Itoa(r.ExternalPort))
+	}
+	return fmt.Sprintf("%d of %d peers responded, %d have us, peers see us at %s, inbound works: %t",
+		r.Responded, r.Asked, len(r.Observed), seen, r.InboundWorks)
+}
+
+// CheckReachability asks peers from the routing table where they have us, and reports that along with whether
+// inbound udp reaches us. The result is also kept for Stats.
+//
+// Peers are asked with a find_node for our own id. Nodes that leave the requester out of their results, like
+// lbrynet's, never report where they have us, so whether inbound udp works is judged only by unsolicited requests.
+func (dht *DHT) CheckReachability() (*Reachability, error) {
+	peers := dht.node.rt.GetClosest(dht.contact.ID, reachabilityPeers)
+	if len(peers) == 0 {
+		return nil, errors.Err("no peers in routing table")
+	}
+
+	r := &Reachability{
+		AdvertisedIP:   dht.contact.IP,
+		AdvertisedPort: dht.contact.Port,
+		Asked:          len(peers),
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for _, p := range peers {
+		wg.Add(1)
+		go func(p Contact) {
+			defer wg.Done()
+			res := <-dht.node.SendAsync(p, Request{Method: findNodeMethod, Arg: &dht.contact.ID})
+			if res == nil {
+				return
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			r.Responded++
+			for _, c := range res.Contacts {
+				if c.ID.Equals(dht.contact.ID) {
+					r.Observed = append(r.Observed, ObservedAddr{Peer: p.ID, IP: c.IP, Port: c.Port})
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	r.ExternalIP, r.ExternalPort = mostObserved(r.Observed)
+	r.PortMismatch = r.ExternalIP != nil && r.ExternalPort != r.AdvertisedPort
+
+	r.LastUnsolicitedInbound, r.UnsolicitedInbound = dht.node.inbound.Unsolicited()
+	r.InboundWorks = !r.LastUnsolicitedInbound.IsZero() && time.Since(r.LastUnsolicitedInbound) < reachabilityWindow
+	r.CheckedAt = time.Now()
+
+	dht.reachabilityLock.Lock()
+	dht.reachability = r
+	dht.reachabilityLock.Unlock()
+
+	return r, nil
+}
+
+// runReachabilityChecks checks reachability every so often and logs the result. the first check waits a bit, so
+// peers have a chance to contact us after we join.
+func (dht *DHT) runReachabilityChecks() {
+	wait := reachabilityFirstCheck
+	for {
+		select {
+		case <-time.After(wait):
+		case <-dht.grp.Ch():
+			return
+		}
+		wait = reachabilityCheckInterval
+
+		r, err := dht.CheckReachability()
+		if err != nil {
+			log.Warnf("[%s] reachability check: %s", dht.contact.ID.HexShort(), err.Error())
+			continue
+		}
+		if problems := r.Problems(); len(problems) > 0 {
+			log.Warnf("[%s] reachability: %s. %v", dht.contact.ID.HexShort(), r, problems)
+		} else {
+			log.Infof("[%s] reachability: %s", dht.contact.ID.HexShort(), r)
+		}
+	}
+}
+
+// mostObserved returns the address that the most peers have us at
+func mostObserved(observed []ObservedAddr) (net.IP, int) {
+	counts := make(map[string]int)
+	var best *ObservedAddr
+	bestCount := 0
+	for i, o := range observed {
+		key := net.JoinHostPort(o.IP.String(), strconv.Itoa(o.Port))
+		counts[key]++
+		if counts[key] > bestCount {
+			best = &observed[i]
+			bestCount = counts[key]
+		}
+	}
+	if best == nil {
+		return nil, 0
+	}
+	return best.IP, best.Port
+}
+
+// inboundTracker tells requests that prove we're reachable apart from ones that could have come through a hole that
+// our own traffic opened in a NAT
+type inboundTracker struct {
+	lock sync.Mutex
+	// when we last sent something to each address
+	sent map[string]time.Time
+
+	lastUnsolicited time.Time
+	unsolicited     int
+}
+
+func newInboundTracker() *inboundTracker {
+	return &inboundTracker{sent: make(map[string]time.Time)}
+}
+
+// Sent records that we sent something to addr
+func (t *inboundTracker) Sent(addr *net.UDPAddr) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := time.Now()
+	if len(t.sent) >= inboundTrackerMaxAddrs {
+		for a, at := range t.sent {
+			if now.Sub(at) > natHoleLifetime {
+				delete(t.sent, a)
+			}
+		}
+	}
+	t.sent[addr.String()] = now
+}
+
+// Received records a request from addr
+func (t *inboundTracker) Received(addr *net.UDPAddr) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := time.Now()
+	if at, ok := t.sent[addr.String()]; ok && now.Sub(at) <= natHoleLifetime {
+		return
+	}
+	t.lastUnsolicited = now
+	t.unsolicited++
+}
+
+// Unsolicited returns when the last unsolicited request arrived, and how many have
+func (t *inboundTracker) Unsolicited() (time.Time, int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.lastUnsolicited, t.unsolicited
+}
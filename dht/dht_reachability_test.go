@@ -0,0 +1,116 @@
+package dht
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/dht/bits"
+)
+
+func TestInboundTracker(t *testing.T) {
+	tr := newInboundTracker()
+	a := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 4444}
+	b := &net.UDPAddr{IP: net.IPv4(5, 6, 7, 8), Port: 4444}
+
+	tr.Sent(a)
+	tr.Received(a) // a reply through the hole we just opened
+	if last, n := tr.Unsolicited(); !last.IsZero() || n != 0 {
+		t.Errorf("request from an address we sent to counted as unsolicited")
+	}
+
+	tr.Received(b)
+	if last, n := tr.Unsolicited(); last.IsZero() || n != 1 {
+		t.Errorf("expected 1 unsolicited request, got %d", n)
+	}
+
+	tr.sent[a.String()] = time.Now().Add(-2 * natHoleLifetime)
+	tr.Received(a)
+	if _, n := tr.Unsolicited(); n != 2 {
+		t.Errorf("request after the nat hole closed should be unsolicited")
+	}
+}
+
+func TestMostObserved(t *testing.T) {
+	ip := net.IPv4(1, 2, 3, 4)
+	observed := []ObservedAddr{
+		{Peer: bits.Rand(), IP: ip, Port: 5555},
+		{Peer: bits.Rand(), IP: ip, Port: 4444},
+		{Peer: bits.Rand(), IP: ip, Port: 4444},
+	}
+	gotIP, gotPort := mostObserved(observed)
+	if !gotIP.Equal(ip) || gotPort != 4444 {
+		t.Errorf("expected %s:4444, got %s:%d", ip, gotIP, gotPort)
+	}
+	if gotIP, _ := mostObserved(nil); gotIP != nil {
+		t.Error("expected no address when nothing was observed")
+	}
+}
+
+func TestReachability_Problems(t *testing.T) {
+	r := &Reachability{
+		AdvertisedIP:   net.IPv4(1, 2, 3, 4),
+		AdvertisedPort: 4444,
+		Asked:          8,
+		Responded:      8,
+		Observed:       []ObservedAddr{{IP: net.IPv4(1, 2, 3, 4), Port: 4444}},
+		ExternalIP:     net.IPv4(1, 2, 3, 4),
+		ExternalPort:   4444,
+		InboundWorks:   true,
+	}
+	if p := r.Problems(); len(p) != 0 {
+		t.Errorf("expected no problems, got %v", p)
+	}
+
+	// python nodes never return the requester, so nobody reporting us isn't a problem by itself
+	r.Observed, r.ExternalIP, r.ExternalPort = nil, nil, 0
+	if p := r.Problems(); len(p) != 0 {
+		t.Errorf("expected no problems without observed addresses, got %v", p)
+	}
+
+	r.ExternalIP = net.IPv4(1, 2, 3, 4)
+	r.ExternalPort = 50123
+	r.PortMismatch = true
+	r.InboundWorks = false
+	if p := r.Problems(); len(p) != 2 {
+		t.Errorf("expected a nat and an inbound problem, got %v", p)
+	}
+}
+
+func TestDHT_CheckReachability(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping slow reachability test")
+	}
+
+	bs, dhts := TestingCreateNetwork(t, 3, true, false)
+	defer func() {
+		for i := range dhts {
+			dhts[i].Shutdown()
+		}
+		bs.Shutdown()
+	}()
+
+	d := dhts[0]
+	r, err := d.CheckReachability()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Asked == 0 || r.Responded != r.Asked {
+		t.Errorf("expected all peers to respond, %d of %d did", r.Responded, r.Asked)
+	}
+	// the later nodes joined through us, so they contacted us first
+	if !r.InboundWorks {
+		t.Error("expected inbound to work on localhost")
+	}
+	for _, o := range r.Observed {
+		if o.Port != d.contact.Port {
+			t.Errorf("peer %s has us at port %d, expected %d", o.Peer.HexShort(), o.Port, d.contact.Port)
+		}
+	}
+	if r.PortMismatch {
+		t.Error("unexpected port mismatch")
+	}
+	if d.Stats().Reachability != r {
+		t.Error("stats don't have the last reachability check")
+	}
+}
@@ -1,7 +1,12 @@
 package dht
 
 import (
+	"encoding/json"
+	"io/ioutil"
 	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -111,6 +116,37 @@ func TestNodeFinder_FindValue(t *testing.T) {
 	}
 }
 
+func TestDHT_AnnounceAndWait(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping slow announce test")
+	}
+
+	bs, dhts := TestingCreateNetwork(t, 3, true, false)
+	defer func() {
+		for i := range dhts {
+			dhts[i].Shutdown()
+		}
+		bs.Shutdown()
+	}()
+
+	hash := bits.Rand()
+	stored, err := dhts[0].AnnounceAndWait(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// everything the announcing node counted should have the hash by the time it returns
+	have := 0
+	for _, d := range dhts {
+		if len(d.node.store.Get(hash)) > 0 {
+			have++
+		}
+	}
+	if stored < 3 || have != 3 {
+		t.Errorf("expected all 3 dht nodes to store the hash, %d said they did and %d have it", stored, have)
+	}
+}
+
 func TestDHT_LargeDHT(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping large DHT test")
@@ -133,7 +169,7 @@ func TestDHT_LargeDHT(t *testing.T) {
 		wg.Add(1)
 		go func(index int) {
 			defer wg.Done()
-			err := dhts[index].announce(ids[index])
+			err := dhts[index].Announce(ids[index])
 			if err != nil {
 				t.Error("error announcing random bitmap - ", err)
 			}
@@ -179,3 +215,217 @@ func TestDHT_LargeDHT(t *testing.T) {
 		}
 	}
 }
+
+func TestNew_InvalidConfig(t *testing.T) {
+	cases := []struct {
+		name string
+		conf *Config
+	}{
+		{"bad node id", &Config{Address: "127.0.0.1:21216", NodeID: "nothex"}},
+		{"short node id", &Config{Address: "127.0.0.1:21216", NodeID: "abcd"}},
+		{"no port", &Config{Address: "127.0.0.1"}},
+		{"no ip", &Config{Address: ":4444"}},
+		{"bad ip", &Config{Address: "not.an.ip:4444"}},
+		{"bad port", &Config{Address: "127.0.0.1:port"}},
+	}
+
+	for _, c := range cases {
+		_, err := New(c.conf)
+		if err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
+
+func TestDHT_JoinFromSavedContacts(t *testing.T) {
+	bs, _ := TestingCreateNetwork(t, 0, true, false)
+	defer bs.Shutdown()
+
+	rt := newRoutingTable(bits.Rand())
+	rt.Update(Contact{ID: bits.Rand(), IP: net.ParseIP(testingDHTIP), Port: testingDHTFirstPort})
+	data, err := json.Marshal(rt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rtFile := filepath.Join(t.TempDir(), "routing_table.json")
+	err = ioutil.WriteFile(rtFile, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewStandardConfig()
+	c.Address = testingDHTIP + ":" + strconv.Itoa(testingDHTFirstPort+1)
+	c.SeedNodes = nil
+	c.RoutingTableFile = rtFile
+	c.BootstrapNotificationCh = make(chan BootstrapStatus, 1)
+
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Shutdown()
+
+	status := <-c.BootstrapNotificationCh
+	if status.Err != nil {
+		t.Fatal(status.Err)
+	}
+	if !status.UsedSavedContacts {
+		t.Error("expected saved contacts to be used")
+	}
+	if status.Responded != 1 {
+		t.Errorf("expected 1 node to respond, got %d", status.Responded)
+	}
+}
+
+func TestDHT_PingAllConcurrently(t *testing.T) {
+	c := NewStandardConfig()
+	c.Address = testingDHTIP + ":" + strconv.Itoa(testingDHTFirstPort+1)
+	c.RequestTimeout = 100 * time.Millisecond
+
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.ListenUDP(Network, &net.UDPAddr{IP: net.ParseIP(testingDHTIP), Port: testingDHTFirstPort + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.connect(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Shutdown()
+
+	// nothing listens on these, so every ping times out
+	var addrs []string
+	for i := 0; i < 3*bootstrapPingWorkers; i++ {
+		addrs = append(addrs, testingDHTIP+":"+strconv.Itoa(testingDHTFirstPort+100+i))
+	}
+
+	start := time.Now()
+	if responded := d.pingAll(addrs); responded != 0 {
+		t.Errorf("expected no responses, got %d", responded)
+	}
+	// one at a time, this takes len(addrs) * (udpRetry+1) * 100ms
+	if took := time.Since(start); took > 2*time.Second {
+		t.Errorf("pinging %d nodes took %s", len(addrs), took)
+	}
+}
+
+func TestDHT_LoadSavedContactsIsCapped(t *testing.T) {
+	id := bits.Rand()
+	saved := rtSave{ID: bits.Rand().Hex()}
+	for i := 0; i < 2*maxSavedContacts; i++ {
+		// contact i differs from us first at bit i, so later contacts are closer
+		c := Contact{ID: id.Xor(bits.Bitmap{}.Set(i, true)), IP: net.ParseIP(testingDHTIP), Port: 10000 + i}
+		saved.Contacts = append(saved.Contacts, strings.Join([]string{c.ID.Hex(), c.IP.String(), strconv.Itoa(c.Port)}, rtContactSep))
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rtFile := filepath.Join(t.TempDir(), "routing_table.json")
+	err = ioutil.WriteFile(rtFile, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewStandardConfig()
+	c.NodeID = id.Hex()
+	c.RoutingTableFile = rtFile
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.node = NewNode(id)
+
+	addrs, err := d.loadSavedContacts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != maxSavedContacts {
+		t.Fatalf("expected %d saved contacts, got %d", maxSavedContacts, len(addrs))
+	}
+	for i, addr := range addrs {
+		expected := testingDHTIP + ":" + strconv.Itoa(10000+2*maxSavedContacts-1-i)
+		if addr != expected {
+			t.Errorf("contact %d: expected %s, got %s", i, expected, addr)
+		}
+	}
+}
+
+func TestDHT_BootstrapNotificationDoesNotBlock(t *testing.T) {
+	bs, _ := TestingCreateNetwork(t, 0, true, false)
+	defer bs.Shutdown()
+
+	c := NewStandardConfig()
+	c.Address = testingDHTIP + ":" + strconv.Itoa(testingDHTFirstPort+1)
+	c.SeedNodes = []string{testingDHTIP + ":" + strconv.Itoa(testingDHTFirstPort)}
+	c.BootstrapNotificationCh = make(chan BootstrapStatus) // unbuffered, and nobody reads it
+
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan error, 1)
+	go func() { started <- d.Start() }()
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("start blocked on the bootstrap notification")
+	}
+	d.Shutdown()
+}
+
+func TestDHT_RefreshSeeds(t *testing.T) {
+	bs, _ := TestingCreateNetwork(t, 0, true, false)
+	defer bs.Shutdown()
+
+	c := NewStandardConfig()
+	c.Address = testingDHTIP + ":" + strconv.Itoa(testingDHTFirstPort+1)
+	c.SeedNodes = []string{"seed.example.com:4444"}
+
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the seed's hostname points somewhere that doesn't answer, and later moves to the bootstrap node
+	seedAddr := &net.UDPAddr{IP: net.ParseIP(testingDHTIP), Port: testingDHTFirstPort + 2}
+	d.resolve = func(addr string) (*net.UDPAddr, error) { return seedAddr, nil }
+
+	conn, err := net.ListenUDP(Network, &net.UDPAddr{IP: net.ParseIP(testingDHTIP), Port: testingDHTFirstPort + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.connect(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Shutdown()
+
+	all, changed := d.resolveSeeds()
+	if len(all) != 1 || len(changed) != 1 {
+		t.Fatalf("expected the first lookup to find 1 new seed address, got %v and %v", all, changed)
+	}
+	_, changed = d.resolveSeeds()
+	if len(changed) != 0 {
+		t.Errorf("expected no changed seeds, got %v", changed)
+	}
+
+	seedAddr = &net.UDPAddr{IP: net.ParseIP(testingDHTIP), Port: testingDHTFirstPort}
+	d.refreshSeeds()
+	if _, ok := d.node.rt.Get(bs.id); !ok {
+		t.Error("expected the seed at its new address to be added to the routing table")
+	}
+}
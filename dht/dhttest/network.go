@@ -0,0 +1,186 @@
+// Package dhttest runs DHT nodes over an in-memory network, so lookups and stores can be tested without real sockets.
+package dhttest
+
+import (
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/dht"
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// inboxSize is how many packets a conn can hold before new ones are dropped, like a full socket buffer
+const inboxSize = 1024
+
+type packet struct {
+	data []byte
+	from *net.UDPAddr
+}
+
+// Network delivers packets between Conns. It behaves like UDP: packets to unknown addresses are silently dropped,
+// and packets can be dropped at random if DropRate is set.
+type Network struct {
+	mu       sync.Mutex
+	conns    map[string]*Conn
+	rand     *rand.Rand
+	dropRate float64
+	nextHost int
+}
+
+// NewNetwork returns an empty network. The seed makes random packet drops repeatable.
+func NewNetwork(seed int64) *Network {
+	return &Network{
+		conns: make(map[string]*Conn),
+		rand:  rand.New(rand.NewSource(seed)),
+	}
+}
+
+// SetDropRate sets the fraction of packets (between 0 and 1) that are lost in transit
+func (n *Network) SetDropRate(rate float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.dropRate = rate
+}
+
+// NextAddr returns an unused address on the network. Each address gets its own IP, like nodes on separate hosts.
+func (n *Network) NextAddr() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.nextHost++
+	return net.IPv4(10, 0, byte(n.nextHost/256), byte(n.nextHost%256)).String() + ":" + strconv.Itoa(dht.DefaultPort)
+}
+
+// Listen returns a conn that receives packets sent to addr
+func (n *Network) Listen(addr string) (*Conn, error) {
+	udpAddr, err := net.ResolveUDPAddr(dht.Network, addr)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, exists := n.conns[udpAddr.String()]; exists {
+		return nil, errors.Err("address %s is already in use", udpAddr.String())
+	}
+
+	c := &Conn{
+		network: n,
+		addr:    udpAddr,
+		inbox:   make(chan packet, inboxSize),
+		closed:  make(chan struct{}),
+	}
+	n.conns[udpAddr.String()] = c
+	return c, nil
+}
+
+func (n *Network) send(from, to *net.UDPAddr, data []byte) {
+	n.mu.Lock()
+	dest, ok := n.conns[to.String()]
+	dropped := n.dropRate > 0 && n.rand.Float64() < n.dropRate
+	n.mu.Unlock()
+
+	if !ok || dropped {
+		return
+	}
+
+	select {
+	case dest.inbox <- packet{data: data, from: from}:
+	case <-dest.closed:
+	default: // inbox is full
+	}
+}
+
+func (n *Network) remove(c *Conn) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conns[c.addr.String()] == c {
+		delete(n.conns, c.addr.String())
+	}
+}
+
+// Conn is an in-memory implementation of dht.UDPConn
+type Conn struct {
+	network *Network
+	addr    *net.UDPAddr
+	inbox   chan packet
+
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	mu           sync.Mutex
+	readDeadline time.Time
+}
+
+// LocalAddr returns the address the conn is listening on
+func (c *Conn) LocalAddr() *net.UDPAddr {
+	return c.addr
+}
+
+// ReadFromUDP blocks until a packet arrives, the read deadline passes, or the conn is closed
+func (c *Conn) ReadFromUDP(b []byte) (int, *net.UDPAddr, error) {
+	c.mu.Lock()
+	deadline := c.readDeadline
+	c.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		t := time.NewTimer(time.Until(deadline))
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case p := <-c.inbox:
+		return copy(b, p.data), p.from, nil
+	case <-c.closed:
+		return 0, nil, &net.OpError{Op: "read", Net: dht.Network, Addr: c.addr, Err: net.ErrClosed}
+	case <-timeout:
+		return 0, nil, &net.OpError{Op: "read", Net: dht.Network, Addr: c.addr, Err: timeoutError{}}
+	}
+}
+
+// WriteToUDP sends a copy of b to addr
+func (c *Conn) WriteToUDP(b []byte, addr *net.UDPAddr) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, &net.OpError{Op: "write", Net: dht.Network, Addr: addr, Err: net.ErrClosed}
+	default:
+	}
+
+	data := make([]byte, len(b))
+	copy(data, b)
+	c.network.send(c.addr, addr, data)
+	return len(b), nil
+}
+
+// SetReadDeadline sets the time after which reads fail with a timeout error. A zero time means no deadline.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	return nil
+}
+
+// SetWriteDeadline does nothing, since writes never block
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// Close removes the conn from the network and unblocks any pending reads
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.network.remove(c)
+	})
+	return nil
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
@@ -0,0 +1,130 @@
+package dhttest
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/dht"
+	"github.com/lbryio/lbry.go/v2/dht/bits"
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// packets on the in-memory network arrive almost immediately, so nodes don't need to wait long for responses
+const requestTimeout = 200 * time.Millisecond
+
+// Simulation is a DHT network running entirely in memory. A bootstrap node acts as the seed for every other node.
+type Simulation struct {
+	Network   *Network
+	Bootstrap *dht.BootstrapNode
+	Nodes     []*dht.DHT
+
+	bootstrapAddr string
+	addrs         []string
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewSimulation starts a bootstrap node and numNodes DHT nodes, and waits for all of them to join. Node IDs, hashes,
+// and packet drops are generated from seed, so the same seed always builds the same network.
+func NewSimulation(numNodes int, seed int64) (*Simulation, error) {
+	s := &Simulation{
+		Network: NewNetwork(seed),
+		rand:    rand.New(rand.NewSource(seed)),
+	}
+
+	s.bootstrapAddr = s.Network.NextAddr()
+	conn, err := s.Network.Listen(s.bootstrapAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Bootstrap = dht.NewBootstrapNode(s.RandomHash(), 0, 15*time.Minute)
+	err = s.Bootstrap.Connect(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < numNodes; i++ {
+		_, err := s.AddNode()
+		if err != nil {
+			s.Shutdown()
+			return nil, errors.Prefix("adding node", err)
+		}
+	}
+
+	return s, nil
+}
+
+// RandomHash returns a bitmap from the simulation's random source
+func (s *Simulation) RandomHash() bits.Bitmap {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var b bits.Bitmap
+	s.rand.Read(b[:])
+	return b
+}
+
+// AddNode starts a new node, waits for it to join the network, and appends it to Nodes
+func (s *Simulation) AddNode() (*dht.DHT, error) {
+	c := dht.NewStandardConfig()
+	c.Address = s.Network.NextAddr()
+	c.NodeID = s.RandomHash().Hex()
+	c.SeedNodes = []string{s.bootstrapAddr}
+	c.RequestTimeout = requestTimeout
+
+	d, err := dht.New(c)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := s.Network.Listen(c.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	err = d.StartWithConn(conn)
+	if err != nil {
+		return nil, err
+	}
+	d.WaitUntilJoined()
+
+	s.Nodes = append(s.Nodes, d)
+	s.addrs = append(s.addrs, c.Address)
+	return d, nil
+}
+
+// Addr returns the network address of the node at index i
+func (s *Simulation) Addr(i int) string {
+	return s.addrs[i]
+}
+
+// WaitForValue looks up hash from the node at index i until at least one peer has it, or the timeout passes
+func (s *Simulation) WaitForValue(i int, hash bits.Bitmap, timeout time.Duration) ([]dht.Contact, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		contacts, err := s.Nodes[i].Get(hash)
+		if err != nil {
+			return nil, err
+		}
+		if len(contacts) > 0 {
+			return contacts, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.Err("no peers found for %s after %s", hash.HexShort(), timeout.String())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Shutdown stops every node in the simulation
+func (s *Simulation) Shutdown() {
+	for _, d := range s.Nodes {
+		d.Shutdown()
+	}
+	if s.Bootstrap != nil {
+		s.Bootstrap.Shutdown()
+	}
+}
@@ -0,0 +1,106 @@
+package dhttest
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/dht"
+)
+
+func TestConn(t *testing.T) {
+	n := NewNetwork(1)
+
+	a, err := n.Listen("10.0.0.1:4444")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := n.Listen("10.0.0.2:4444")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := n.Listen("10.0.0.1:4444"); err == nil {
+		t.Error("expected an error listening on an address that's in use")
+	}
+
+	_, err = a.WriteToUDP([]byte("hello"), b.LocalAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 100)
+	read, from, err := b.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:read]) != "hello" {
+		t.Errorf("expected 'hello', got '%s'", string(buf[:read]))
+	}
+	if from.String() != a.LocalAddr().String() {
+		t.Errorf("expected packet from %s, got %s", a.LocalAddr().String(), from.String())
+	}
+
+	err = b.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = b.ReadFromUDP(buf)
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+
+	err = b.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = b.ReadFromUDP(buf)
+	if err == nil {
+		t.Error("expected an error reading from a closed conn")
+	}
+
+	// packets to closed or unknown addresses are dropped, just like UDP
+	_, err = a.WriteToUDP([]byte("hello"), b.LocalAddr())
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSimulation_AnnounceAndFind(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping simulation")
+	}
+
+	s, err := NewSimulation(10, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown()
+
+	hash := s.RandomHash()
+
+	err = s.Nodes[3].Announce(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contacts, err := s.WaitForValue(7, hash, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	host, _, err := net.SplitHostPort(s.Addr(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, c := range contacts {
+		if c.IP.String() == host && c.PeerPort == dht.DefaultPeerPort {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("announcing node was not among the %d peers found", len(contacts))
+	}
+}
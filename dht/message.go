@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/lbryio/lbry.go/v2/dht/bits"
 	"github.com/lbryio/lbry.go/v2/extras/errors"
@@ -95,14 +96,21 @@ type Request struct {
 
 // MarshalBencode returns the serialized byte slice representation of the request
 func (r Request) MarshalBencode() ([]byte, error) {
-	var args interface{}
+	args := []interface{}{} // request must always have keys 0-4, so we use an empty list for PING
 	if r.StoreArgs != nil {
-		args = r.StoreArgs
+		var err error
+		args, err = r.StoreArgs.list()
+		if err != nil {
+			return nil, err
+		}
 	} else if r.Arg != nil {
-		args = []bits.Bitmap{*r.Arg}
-	} else {
-		args = []string{} // request must always have keys 0-4, so we use an empty list for PING
+		args = append(args, *r.Arg)
+	}
+
+	if r.ProtocolVersion > 0 {
+		args = append(args, map[string]int{protocolVersionField: r.ProtocolVersion})
 	}
+
 	return bencode.EncodeBytes(map[string]interface{}{
 		headerTypeField:      requestType,
 		headerMessageIDField: r.ID,
@@ -130,8 +138,25 @@ func (r *Request) UnmarshalBencode(b []byte) error {
 	r.Method = raw.Method
 
 	if r.Method == storeMethod {
+		var args []bencode.RawMessage
+		err = bencode.DecodeBytes(raw.Args, &args)
+		if err != nil {
+			return errors.Prefix("request unmarshal", err)
+		}
+		args, r.ProtocolVersion = splitProtoVersion(args)
+
+		// re-encode without the version so storeArgs sees exactly the fields it expects
+		list := make([]interface{}, len(args))
+		for i := range args {
+			list[i] = args[i]
+		}
+		rawStoreArgs, err := bencode.EncodeBytes(list)
+		if err != nil {
+			return errors.Prefix("request unmarshal", err)
+		}
+
 		r.StoreArgs = &storeArgs{} // bencode wont find the unmarshaler on a null pointer. need to fix it.
-		err = bencode.DecodeBytes(raw.Args, &r.StoreArgs)
+		err = bencode.DecodeBytes(rawStoreArgs, &r.StoreArgs)
 		if err != nil {
 			return errors.Prefix("request unmarshal", err)
 		}
@@ -152,18 +177,7 @@ func processArgsAndProtoVersion(raw bencode.RawMessage) (arg *bits.Bitmap, versi
 		return nil, 0, err
 	}
 
-	if len(args) == 0 {
-		return nil, 0, nil
-	}
-
-	var extras map[string]int
-	err = bencode.DecodeBytes(args[len(args)-1], &extras)
-	if err == nil {
-		if v, exists := extras[protocolVersionField]; exists {
-			version = v
-			args = args[:len(args)-1]
-		}
-	}
+	args, version = splitProtoVersion(args)
 
 	if len(args) > 0 {
 		var b bits.Bitmap
@@ -177,6 +191,23 @@ func processArgsAndProtoVersion(raw bencode.RawMessage) (arg *bits.Bitmap, versi
 	return arg, version, nil
 }
 
+// splitProtoVersion removes the protocol version dict from the end of the args, if it's there, and returns the version
+func splitProtoVersion(args []bencode.RawMessage) ([]bencode.RawMessage, int) {
+	if len(args) == 0 {
+		return args, 0
+	}
+
+	var extras map[string]int
+	err := bencode.DecodeBytes(args[len(args)-1], &extras)
+	if err == nil {
+		if v, exists := extras[protocolVersionField]; exists {
+			return args[:len(args)-1], v
+		}
+	}
+
+	return args, 0
+}
+
 func (r Request) argsDebug() string {
 	if r.StoreArgs != nil {
 		return r.StoreArgs.BlobHash.HexShort() + ", " + r.StoreArgs.Value.LbryID.HexShort() + ":" + strconv.Itoa(r.StoreArgs.Value.Port)
@@ -197,10 +228,71 @@ type storeArgs struct {
 	Value     storeArgsValue
 	NodeID    bits.Bitmap // original publisher id? I think this is getting fixed in the new dht stuff
 	SelfStore bool        // this is an int on the wire
+	// if set, this isn't an announcement. the sender is passing on peers it found while looking up the hash. only
+	// sent to nodes that speak cachedStoreVersion or newer
+	Cached *cachedPeers
+}
+
+// cachedPeers are peers for a hash that get stored at the closest node that didn't know about them, so the next
+// lookup for that hash finishes sooner
+type cachedPeers struct {
+	Peers []Contact
+	TTL   time.Duration
+}
+
+type rawCachedPeers struct {
+	Peers [][]byte `bencode:"peers"`
+	TTL   int      `bencode:"ttl"` // seconds
+}
+
+// MarshalBencode returns the serialized byte slice representation of the cached peers. peers are in compact form,
+// the same as in findValue responses
+func (c cachedPeers) MarshalBencode() ([]byte, error) {
+	raw := rawCachedPeers{TTL: int(c.TTL / time.Second)}
+	for _, p := range c.Peers {
+		compact, err := p.MarshalCompact()
+		if err != nil {
+			return nil, err
+		}
+		raw.Peers = append(raw.Peers, compact)
+	}
+	return bencode.EncodeBytes(raw)
+}
+
+// UnmarshalBencode unmarshals the serialized byte slice into the cached peers
+func (c *cachedPeers) UnmarshalBencode(b []byte) error {
+	var raw rawCachedPeers
+	err := bencode.DecodeBytes(b, &raw)
+	if err != nil {
+		return err
+	}
+	if raw.TTL < 0 {
+		return errors.Err("negative ttl")
+	}
+	c.TTL = time.Duration(raw.TTL) * time.Second
+	c.Peers = nil
+	for _, compact := range raw.Peers {
+		var p Contact
+		err = p.UnmarshalCompact(compact)
+		if err != nil {
+			return err
+		}
+		c.Peers = append(c.Peers, p)
+	}
+	return nil
 }
 
 // MarshalBencode returns the serialized byte slice representation of the storage arguments.
 func (s storeArgs) MarshalBencode() ([]byte, error) {
+	args, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+	return bencode.EncodeBytes(args)
+}
+
+// list returns the store arguments in the order they go on the wire
+func (s storeArgs) list() ([]interface{}, error) {
 	encodedValue, err := bencode.EncodeString(s.Value)
 	if err != nil {
 		return nil, err
@@ -211,12 +303,16 @@ func (s storeArgs) MarshalBencode() ([]byte, error) {
 		selfStoreStr = 1
 	}
 
-	return bencode.EncodeBytes([]interface{}{
+	args := []interface{}{
 		s.BlobHash,
 		bencode.RawMessage(encodedValue),
 		s.NodeID,
 		selfStoreStr,
-	})
+	}
+	if s.Cached != nil {
+		args = append(args, *s.Cached)
+	}
+	return args, nil
 }
 
 // UnmarshalBencode unmarshals the serialized byte slice into the appropriate fields of the store arguments.
@@ -227,7 +323,7 @@ func (s *storeArgs) UnmarshalBencode(b []byte) error {
 		return errors.Prefix("storeArgs unmarshal", err)
 	}
 
-	if len(argsInt) != 4 {
+	if len(argsInt) != 4 && len(argsInt) != 5 {
 		return errors.Err("unexpected number of fields for store args. got " + cast.ToString(len(argsInt)))
 	}
 
@@ -259,6 +355,14 @@ func (s *storeArgs) UnmarshalBencode(b []byte) error {
 		return errors.Err("selfstore must be 1 or 0")
 	}
 
+	if len(argsInt) == 5 {
+		s.Cached = &cachedPeers{}
+		err = bencode.DecodeBytes(argsInt[4], s.Cached)
+		if err != nil {
+			return errors.Prefix("storeArgs unmarshal", err)
+		}
+	}
+
 	return nil
 }
 
@@ -322,16 +426,24 @@ func (r Response) MarshalBencode() ([]byte, error) {
 			}
 			contacts = append(contacts, compact)
 		}
-		data[headerPayloadField] = map[string]interface{}{
+		payload := map[string]interface{}{
 			r.FindValueKey: contacts,
 			tokenField:     r.Token,
 		}
+		if r.ProtocolVersion > 0 {
+			payload[protocolVersionField] = r.ProtocolVersion
+		}
+		data[headerPayloadField] = payload
 	} else if r.Token != "" {
 		// findValue failure falling back to findNode
-		data[headerPayloadField] = map[string]interface{}{
+		payload := map[string]interface{}{
 			contactsField: r.Contacts,
 			tokenField:    r.Token,
 		}
+		if r.ProtocolVersion > 0 {
+			payload[protocolVersionField] = r.ProtocolVersion
+		}
+		data[headerPayloadField] = payload
 	} else {
 		// straight up findNode
 		data[headerPayloadField] = r.Contacts
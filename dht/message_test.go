@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/lbryio/lbry.go/v2/dht/bits"
 
@@ -222,3 +223,80 @@ func compareResponses(t *testing.T, res, res2 Response) {
 		t.Errorf("expected FindNodeData %s, got %s", spew.Sdump(res.Contacts), spew.Sdump(res2.Contacts))
 	}
 }
+
+func TestRequestProtocolVersionRoundTrip(t *testing.T) {
+	arg := bits.Rand()
+	requests := []Request{
+		{ID: newMessageID(), NodeID: bits.Rand(), Method: pingMethod, ProtocolVersion: 1},
+		{ID: newMessageID(), NodeID: bits.Rand(), Method: findNodeMethod, Arg: &arg, ProtocolVersion: 1},
+		{ID: newMessageID(), NodeID: bits.Rand(), Method: findValueMethod, Arg: &arg},
+		{ID: newMessageID(), NodeID: bits.Rand(), Method: storeMethod, ProtocolVersion: 1, StoreArgs: &storeArgs{
+			BlobHash: bits.Rand(),
+			Value:    storeArgsValue{Token: "token", LbryID: bits.Rand(), Port: 3333},
+			NodeID:   bits.Rand(),
+		}},
+		{ID: newMessageID(), NodeID: bits.Rand(), Method: storeMethod, ProtocolVersion: 2, StoreArgs: &storeArgs{
+			BlobHash: bits.Rand(),
+			Value:    storeArgsValue{Token: "token", LbryID: bits.Rand()},
+			NodeID:   bits.Rand(),
+			Cached: &cachedPeers{
+				Peers: []Contact{{ID: bits.Rand(), IP: net.IPv4(1, 2, 3, 4).To4(), PeerPort: 3333}},
+				TTL:   15 * time.Minute,
+			},
+		}},
+	}
+
+	for _, req := range requests {
+		encoded, err := bencode.EncodeBytes(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded Request
+		err = bencode.DecodeBytes(encoded, &decoded)
+		if err != nil {
+			t.Fatalf("%s: %s", req.Method, err)
+		}
+
+		if decoded.ProtocolVersion != req.ProtocolVersion {
+			t.Errorf("%s: expected protocol version %d, got %d", req.Method, req.ProtocolVersion, decoded.ProtocolVersion)
+		}
+		if (req.Arg == nil) != (decoded.Arg == nil) || (req.Arg != nil && !req.Arg.Equals(*decoded.Arg)) {
+			t.Errorf("%s: arg mismatch", req.Method)
+		}
+		if req.StoreArgs != nil && (decoded.StoreArgs == nil || !reflect.DeepEqual(*req.StoreArgs, *decoded.StoreArgs)) {
+			t.Errorf("%s: store args mismatch", req.Method)
+		}
+	}
+}
+
+func TestResponseProtocolVersionRoundTrip(t *testing.T) {
+	for _, version := range []int{0, 1} {
+		res := Response{
+			ID:              newMessageID(),
+			NodeID:          bits.Rand(),
+			Token:           "arst",
+			Contacts:        []Contact{{ID: bits.Rand(), IP: net.IPv4(1, 2, 3, 4).To4(), Port: 5678}},
+			ProtocolVersion: version,
+		}
+
+		encoded, err := bencode.EncodeBytes(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if version == 0 && strings.Contains(string(encoded), protocolVersionField) {
+			t.Error("version 0 response should not include a protocol version")
+		}
+
+		var decoded Response
+		err = bencode.DecodeBytes(encoded, &decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.ProtocolVersion != version {
+			t.Errorf("expected protocol version %d, got %d", version, decoded.ProtocolVersion)
+		}
+		compareResponses(t, res, decoded)
+	}
+}
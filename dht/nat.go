@@ -0,0 +1,536 @@
+package dht
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/dht/bits"
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/stop"
+)
+
+const (
+	natDiscoveryTimeout = 3 * time.Second
+	natMappingLifetime  = 1 * time.Hour
+	natMappingDesc      = "lbry dht"
+
+	natPMPPort = 5351
+
+	ssdpAddr         = "239.255.255.250:1900"
+	upnpGatewayType  = "urn:schemas-upnp-org:device:InternetGatewayDevice:1"
+	upnpWANIPService = "WANIPConnection"
+	upnpPPPService   = "WANPPPConnection"
+
+	// this many peers must report the same ip for us before we believe it
+	externalIPMinVotes = 3
+)
+
+// natMapper maps ports on the local network's gateway so that peers outside the NAT can reach us
+type natMapper interface {
+	// ExternalIP returns the gateway's public ip
+	ExternalIP() (net.IP, error)
+	// AddPortMapping forwards the external port to the internal port. it returns the external port that was actually
+	// mapped, which may be different from the one requested
+	AddPortMapping(protocol string, internalPort, externalPort int, lifetime time.Duration) (int, error)
+	// DeletePortMapping removes a mapping created by AddPortMapping
+	DeletePortMapping(protocol string, internalPort, externalPort int) error
+	String() string
+}
+
+// discoverNAT looks for a gateway that supports UPnP or NAT-PMP, in that order
+func discoverNAT() (natMapper, error) {
+	u, err := discoverUPnP(natDiscoveryTimeout)
+	if err == nil {
+		return u, nil
+	}
+	log.Debugf("upnp discovery failed: %s", err.Error())
+
+	gw, err := defaultGateway()
+	if err != nil {
+		return nil, errors.Prefix("finding gateway for nat-pmp", err)
+	}
+
+	p := &natPMP{gateway: &net.UDPAddr{IP: gw, Port: natPMPPort}, timeout: natDiscoveryTimeout}
+	if _, err := p.ExternalIP(); err != nil {
+		return nil, errors.Prefix("nat-pmp", err)
+	}
+
+	return p, nil
+}
+
+// natPortMapping keeps a port mapped on the gateway until it is stopped
+type natPortMapping struct {
+	nat          natMapper
+	protocol     string
+	internalPort int
+	externalPort int
+	grp          *stop.Group
+}
+
+// mapPort asks the gateway to forward a port to us and keeps renewing the mapping in the background until
+// Stop is called
+func mapPort(nat natMapper, protocol string, port int, parent *stop.Group) (*natPortMapping, error) {
+	external, err := nat.AddPortMapping(protocol, port, port, natMappingLifetime)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &natPortMapping{
+		nat:          nat,
+		protocol:     protocol,
+		internalPort: port,
+		externalPort: external,
+		grp:          stop.New(parent),
+	}
+
+	m.grp.Go("port mapping renewal", func() {
+		t := time.NewTicker(natMappingLifetime / 2)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				_, err := nat.AddPortMapping(protocol, port, m.externalPort, natMappingLifetime)
+				if err != nil {
+					log.Error(errors.Prefix("renewing port mapping", err))
+				}
+			case <-m.grp.Ch():
+				err := nat.DeletePortMapping(protocol, port, m.externalPort)
+				if err != nil {
+					log.Error(errors.Prefix("removing port mapping", err))
+				}
+				return
+			}
+		}
+	})
+
+	return m, nil
+}
+
+// Stop removes the mapping from the gateway
+func (m *natPortMapping) Stop() {
+	m.grp.StopAndWait()
+}
+
+// externalIPVotes tracks the ip that other nodes see us at. peers return our contact in their findNode responses,
+// so once enough of them agree we can use that ip as our own.
+type externalIPVotes struct {
+	lock  sync.Mutex
+	votes map[string]map[bits.Bitmap]bool // ip -> set of peers who reported it
+	ip    net.IP
+}
+
+func newExternalIPVotes() *externalIPVotes {
+	return &externalIPVotes{votes: make(map[string]map[bits.Bitmap]bool)}
+}
+
+// Add records that `voter` sees us at `ip`
+func (e *externalIPVotes) Add(voter bits.Bitmap, ip net.IP) {
+	if ip == nil || ip.IsUnspecified() || ip.IsLoopback() {
+		return
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	key := ip.String()
+	if _, ok := e.votes[key]; !ok {
+		e.votes[key] = make(map[bits.Bitmap]bool)
+	}
+	e.votes[key][voter] = true
+
+	if len(e.votes[key]) >= externalIPMinVotes && (e.ip == nil || len(e.votes[key]) > len(e.votes[e.ip.String()])) {
+		e.ip = ip
+	}
+}
+
+// Get returns the ip that most peers agree on, or nil if there's not enough agreement yet
+func (e *externalIPVotes) Get() net.IP {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.ip
+}
+
+// natPMP implements natMapper for gateways that speak NAT-PMP (RFC 6886)
+type natPMP struct {
+	gateway *net.UDPAddr
+	timeout time.Duration
+}
+
+const (
+	natPMPOpExternalAddress = 0
+	natPMPOpMapUDP          = 1
+	natPMPOpMapTCP          = 2
+)
+
+func (p *natPMP) String() string {
+	return "nat-pmp(" + p.gateway.String() + ")"
+}
+
+func (p *natPMP) ExternalIP() (net.IP, error) {
+	res, err := p.call([]byte{0, natPMPOpExternalAddress}, 12)
+	if err != nil {
+		return nil, err
+	}
+	return net.IPv4(res[8], res[9], res[10], res[11]), nil
+}
+
+func (p *natPMP) AddPortMapping(protocol string, internalPort, externalPort int, lifetime time.Duration) (int, error) {
+	op := byte(natPMPOpMapUDP)
+	if strings.ToLower(protocol) == "tcp" {
+		op = natPMPOpMapTCP
+	}
+
+	req := make([]byte, 12)
+	req[1] = op
+	binary.BigEndian.PutUint16(req[4:6], uint16(internalPort))
+	binary.BigEndian.PutUint16(req[6:8], uint16(externalPort))
+	binary.BigEndian.PutUint32(req[8:12], uint32(lifetime/time.Second))
+
+	res, err := p.call(req, 16)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint16(res[10:12])), nil
+}
+
+func (p *natPMP) DeletePortMapping(protocol string, internalPort, externalPort int) error {
+	// a mapping with zero lifetime and zero external port is a delete
+	_, err := p.AddPortMapping(protocol, internalPort, 0, 0)
+	return err
+}
+
+// call sends a request to the gateway, retrying with exponential backoff until it gets a response or times out
+func (p *natPMP) call(req []byte, resLen int) ([]byte, error) {
+	conn, err := net.DialUDP("udp4", nil, p.gateway)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(p.timeout)
+	wait := 250 * time.Millisecond
+	res := make([]byte, 16)
+
+	for time.Now().Before(deadline) {
+		_, err = conn.Write(req)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+
+		readUntil := time.Now().Add(wait)
+		if readUntil.After(deadline) {
+			readUntil = deadline
+		}
+		err = conn.SetReadDeadline(readUntil)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+
+		n, err := conn.Read(res)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				wait *= 2
+				continue
+			}
+			return nil, errors.Err(err)
+		}
+
+		if n < resLen {
+			return nil, errors.Err("nat-pmp response too short: %d bytes", n)
+		} else if res[0] != 0 || res[1] != 128+req[1] {
+			return nil, errors.Err("unexpected nat-pmp response opcode %d", res[1])
+		} else if code := binary.BigEndian.Uint16(res[2:4]); code != 0 {
+			return nil, errors.Err("nat-pmp error code %d", code)
+		}
+		return res[:n], nil
+	}
+
+	return nil, errors.Err("nat-pmp request timed out")
+}
+
+// defaultGateway returns the ip of the default route. it only works on linux. elsewhere it guesses that the
+// gateway is at .1 on our local network
+func defaultGateway() (net.IP, error) {
+	f, err := os.Open("/proc/net/route")
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 3 || fields[1] != "00000000" {
+				continue
+			}
+			gw, err := strconv.ParseUint(fields[2], 16, 32)
+			if err != nil {
+				continue
+			}
+			ip := make(net.IP, 4)
+			binary.LittleEndian.PutUint32(ip, uint32(gw))
+			return ip, nil
+		}
+	}
+
+	local, err := localIP(&net.UDPAddr{IP: net.IPv4(8, 8, 8, 8), Port: 53})
+	if err != nil {
+		return nil, err
+	}
+	local = local.To4()
+	return net.IPv4(local[0], local[1], local[2], 1), nil
+}
+
+// localIP returns the ip of the interface we would use to reach the given address
+func localIP(remote *net.UDPAddr) (net.IP, error) {
+	conn, err := net.DialUDP("udp4", nil, remote)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
+
+// upnp implements natMapper for UPnP internet gateway devices
+type upnp struct {
+	serviceType string
+	controlURL  string
+	localIP     net.IP
+	client      *http.Client
+}
+
+type upnpService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+type upnpDevice struct {
+	DeviceType string        `xml:"deviceType"`
+	Services   []upnpService `xml:"serviceList>service"`
+	Devices    []upnpDevice  `xml:"deviceList>device"`
+}
+
+type upnpRoot struct {
+	URLBase string     `xml:"URLBase"`
+	Device  upnpDevice `xml:"device"`
+}
+
+// findService searches the device tree for a WAN connection service
+func (d upnpDevice) findService() *upnpService {
+	for i := range d.Services {
+		if strings.Contains(d.Services[i].ServiceType, upnpWANIPService) || strings.Contains(d.Services[i].ServiceType, upnpPPPService) {
+			return &d.Services[i]
+		}
+	}
+	for _, child := range d.Devices {
+		if s := child.findService(); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+// discoverUPnP finds a gateway with SSDP and looks up its WAN connection service
+func discoverUPnP(timeout time.Duration) (*upnp, error) {
+	ssdp, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	defer conn.Close()
+
+	search := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddr + "\r\n" +
+		"ST: " + upnpGatewayType + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n\r\n"
+
+	_, err = conn.WriteToUDP([]byte(search), ssdp)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+
+	err = conn.SetReadDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+
+	buf := make([]byte, 2048)
+	for {
+		n, raddr, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return nil, errors.Prefix("ssdp", err)
+		}
+
+		res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil || !strings.Contains(res.Header.Get("St"), "InternetGatewayDevice") {
+			continue
+		}
+
+		location := res.Header.Get("Location")
+		if location == "" {
+			continue
+		}
+
+		u, err := newUPnP(location, timeout)
+		if err != nil {
+			log.Debugf("upnp gateway %s: %s", raddr.String(), err.Error())
+			continue
+		}
+		return u, nil
+	}
+}
+
+func newUPnP(location string, timeout time.Duration) (*upnp, error) {
+	client := &http.Client{Timeout: timeout}
+
+	res, err := client.Get(location)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	defer res.Body.Close()
+
+	var root upnpRoot
+	err = xml.NewDecoder(res.Body).Decode(&root)
+	if err != nil {
+		return nil, errors.Prefix("decoding device description", err)
+	}
+
+	service := root.Device.findService()
+	if service == nil {
+		return nil, errors.Err("gateway has no WAN connection service")
+	}
+
+	base := location
+	if root.URLBase != "" {
+		base = root.URLBase
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	controlURL, err := baseURL.Parse(service.ControlURL)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+
+	gwAddr, err := net.ResolveUDPAddr("udp4", baseURL.Host)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	local, err := localIP(gwAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &upnp{
+		serviceType: service.ServiceType,
+		controlURL:  controlURL.String(),
+		localIP:     local,
+		client:      client,
+	}, nil
+}
+
+func (u *upnp) String() string {
+	return "upnp(" + u.controlURL + ")"
+}
+
+func (u *upnp) ExternalIP() (net.IP, error) {
+	var res struct {
+		IP string `xml:"Body>GetExternalIPAddressResponse>NewExternalIPAddress"`
+	}
+	err := u.soap("GetExternalIPAddress", nil, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	ip := net.ParseIP(res.IP)
+	if ip == nil {
+		return nil, errors.Err("gateway returned invalid external ip '%s'", res.IP)
+	}
+	return ip, nil
+}
+
+func (u *upnp) AddPortMapping(protocol string, internalPort, externalPort int, lifetime time.Duration) (int, error) {
+	err := u.soap("AddPortMapping", [][2]string{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", strconv.Itoa(externalPort)},
+		{"NewProtocol", strings.ToUpper(protocol)},
+		{"NewInternalPort", strconv.Itoa(internalPort)},
+		{"NewInternalClient", u.localIP.String()},
+		{"NewEnabled", "1"},
+		{"NewPortMappingDescription", natMappingDesc},
+		{"NewLeaseDuration", strconv.Itoa(int(lifetime / time.Second))},
+	}, nil)
+	if err != nil {
+		return 0, err
+	}
+	return externalPort, nil
+}
+
+func (u *upnp) DeletePortMapping(protocol string, internalPort, externalPort int) error {
+	return u.soap("DeletePortMapping", [][2]string{
+		{"NewRemoteHost", ""},
+		{"NewExternalPort", strconv.Itoa(externalPort)},
+		{"NewProtocol", strings.ToUpper(protocol)},
+	}, nil)
+}
+
+// soap calls an action on the gateway's WAN connection service. args are in order because some gateways care
+func (u *upnp) soap(action string, args [][2]string, result interface{}) error {
+	body := &bytes.Buffer{}
+	body.WriteString(`<?xml version="1.0"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<s:Body><u:` + action + ` xmlns:u="` + u.serviceType + `">`)
+	for _, arg := range args {
+		body.WriteString("<" + arg[0] + ">")
+		err := xml.EscapeText(body, []byte(arg[1]))
+		if err != nil {
+			return errors.Err(err)
+		}
+		body.WriteString("</" + arg[0] + ">")
+	}
+	body.WriteString(`</u:` + action + `></s:Body></s:Envelope>`)
+
+	req, err := http.NewRequest(http.MethodPost, u.controlURL, body)
+	if err != nil {
+		return errors.Err(err)
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, u.serviceType, action))
+
+	res, err := u.client.Do(req)
+	if err != nil {
+		return errors.Err(err)
+	}
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return errors.Err(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return errors.Err("upnp %s failed with status %d: %s", action, res.StatusCode, string(resBody))
+	}
+
+	if result != nil {
+		err = xml.Unmarshal(resBody, result)
+		if err != nil {
+			return errors.Prefix("decoding "+action+" response", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package dht
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/dht/bits"
+)
+
+func TestExternalIPVotes(t *testing.T) {
+	v := newExternalIPVotes()
+	ip := net.ParseIP("1.2.3.4")
+
+	voter := bits.Rand()
+	for i := 0; i < externalIPMinVotes; i++ {
+		v.Add(voter, ip) // same voter doesn't count twice
+	}
+	if v.Get() != nil {
+		t.Fatal("one peer should not be enough to set the external ip")
+	}
+
+	v.Add(bits.Rand(), net.ParseIP("127.0.0.1"))
+	for i := 1; i < externalIPMinVotes; i++ {
+		v.Add(bits.Rand(), ip)
+	}
+	if !v.Get().Equal(ip) {
+		t.Fatalf("expected external ip %s, got %s", ip.String(), v.Get().String())
+	}
+
+	other := net.ParseIP("5.6.7.8")
+	for i := 0; i < externalIPMinVotes; i++ {
+		v.Add(bits.Rand(), other)
+	}
+	if !v.Get().Equal(ip) {
+		t.Error("a tie should not change the external ip")
+	}
+	v.Add(bits.Rand(), other)
+	if !v.Get().Equal(other) {
+		t.Error("the ip with the most votes should win")
+	}
+}
+
+func TestNatPMP(t *testing.T) {
+	gw, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gw.Close()
+
+	go func() {
+		buf := make([]byte, 12)
+		for {
+			n, raddr, err := gw.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+
+			var res []byte
+			switch {
+			case n == 2 && buf[1] == natPMPOpExternalAddress:
+				res = []byte{0, 128, 0, 0, 0, 0, 0, 1, 1, 2, 3, 4}
+			case n == 12 && buf[1] == natPMPOpMapUDP:
+				res = make([]byte, 16)
+				res[1] = 128 + natPMPOpMapUDP
+				copy(res[8:10], buf[4:6])                     // internal port
+				binary.BigEndian.PutUint16(res[10:12], 40000) // mapped external port
+				copy(res[12:16], buf[8:12])                   // lifetime
+			default:
+				res = []byte{0, 128 + buf[1], 0, 5} // unsupported opcode
+			}
+
+			_, err = gw.WriteToUDP(res, raddr)
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	p := &natPMP{gateway: gw.LocalAddr().(*net.UDPAddr), timeout: 2 * time.Second}
+
+	ip, err := p.ExternalIP()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ip.Equal(net.IPv4(1, 2, 3, 4)) {
+		t.Errorf("expected external ip 1.2.3.4, got %s", ip.String())
+	}
+
+	port, err := p.AddPortMapping("udp", 4444, 4444, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != 40000 {
+		t.Errorf("expected mapped port 40000, got %d", port)
+	}
+
+	_, err = p.AddPortMapping("tcp", 3333, 3333, time.Hour)
+	if err == nil {
+		t.Error("expected an error for a failed mapping")
+	}
+}
@@ -12,16 +12,10 @@ import (
 	"github.com/lbryio/lbry.go/v2/extras/stop"
 	"github.com/lbryio/lbry.go/v2/extras/util"
 
-	"github.com/davecgh/go-spew/spew"
 	"github.com/lyoshenka/bencode"
+	"go.uber.org/atomic"
 )
 
-// packet represents the information receive from udp.
-type packet struct {
-	data  []byte
-	raddr *net.UDPAddr
-}
-
 // UDPConn allows using a mocked connection to test sending/receiving data
 // TODO: stop mocking this and use the real thing
 type UDPConn interface {
@@ -35,6 +29,14 @@ type UDPConn interface {
 // RequestHandlerFunc is exported handler for requests.
 type RequestHandlerFunc func(addr *net.UDPAddr, request Request)
 
+// requestHandlers maps each request method to the function that handles it
+var requestHandlers = map[string]func(n *Node, addr *net.UDPAddr, request Request){
+	pingMethod:      (*Node).handlePing,
+	storeMethod:     (*Node).handleStore,
+	findNodeMethod:  (*Node).handleFindNode,
+	findValueMethod: (*Node).handleFindValue,
+}
+
 // Node is a type representation of a node on the network.
 type Node struct {
 	// the node's id
@@ -42,7 +44,7 @@ type Node struct {
 	// UDP connection for sending and receiving data
 	conn UDPConn
 	// true if we've closed the connection on purpose
-	connClosed bool
+	connClosed *atomic.Bool
 	// token manager
 	tokens *tokenManager
 
@@ -54,9 +56,21 @@ type Node struct {
 	rt *routingTable
 	// data store
 	store *contactStore
+	// the ip other nodes see us at
+	externalIP *externalIPVotes
+	// whether nodes reach us without us contacting them first
+	inbound *inboundTracker
+	// the protocol version each node we've heard from speaks
+	versionsLock *sync.RWMutex
+	versions     map[bits.Bitmap]int
+
+	// how long to wait for a response
+	timeout time.Duration
 
 	// overrides for request handlers
 	requestHandler RequestHandlerFunc
+	// notified about all traffic
+	observer MessageObserver
 
 	// stop the node neatly and clean up after itself
 	grp *stop.Group
@@ -69,10 +83,21 @@ func NewNode(id bits.Bitmap) *Node {
 		rt:    newRoutingTable(id),
 		store: newStore(),
 
+		externalIP: newExternalIPVotes(),
+		inbound:    newInboundTracker(),
+
+		versionsLock: &sync.RWMutex{},
+		versions:     make(map[bits.Bitmap]int),
+
+		observer: logObserver{id: id},
+		timeout:  udpTimeout,
+
 		txLock:       &sync.RWMutex{},
 		transactions: make(map[messageID]*transaction),
 
-		grp:    stop.New(),
+		connClosed: atomic.NewBool(false),
+
+		grp:    stop.NewNamed("node"),
 		tokens: &tokenManager{},
 	}
 }
@@ -81,73 +106,35 @@ func NewNode(id bits.Bitmap) *Node {
 func (n *Node) Connect(conn UDPConn) error {
 	n.conn = conn
 
-	n.tokens.Start(tokenSecretRotationInterval)
+	err := n.tokens.Start(tokenSecretRotationInterval)
+	if err != nil {
+		return err
+	}
 
-	go func() {
+	n.grp.Go("connection closer", func() {
 		// stop tokens and close the connection when we're shutting down
 		<-n.grp.Ch()
 		n.tokens.Stop()
-		n.connClosed = true
+		n.connClosed.Store(true)
 		err := n.conn.Close()
 		if err != nil {
 			log.Error("error closing node connection on shutdown - ", err)
 		}
-	}()
-
-	packets := make(chan packet)
-
-	n.grp.Add(1)
-	go func() {
-		defer n.grp.Done()
-
-		buf := make([]byte, udpMaxMessageLength)
-
-		for {
-			bytesRead, raddr, err := n.conn.ReadFromUDP(buf)
-			if err != nil {
-				if n.connClosed {
-					return
-				}
-				log.Errorf("udp read error: %v", err)
-				continue
-			} else if raddr == nil {
-				log.Errorf("udp read with no raddr")
-				continue
-			}
+	})
 
-			data := make([]byte, bytesRead)
-			copy(data, buf[:bytesRead]) // slices use the same underlying array, so we need a new one for each packet
+	// buffered so a burst of packets doesn't block the reader while all the workers are busy
+	packets := make(chan packet, packetQueueLength)
 
-			select { // needs select here because packet consumer can quit and the packets channel gets filled up and blocks
-			case packets <- packet{data: data, raddr: raddr}:
-			case <-n.grp.Ch():
-				return
-			}
-		}
-	}()
+	n.grp.Go("packet reader", func() { readPackets(n.conn, n.connClosed, packets, n.grp.Ch()) })
 
-	n.grp.Add(1)
-	go func() {
-		defer n.grp.Done()
-
-		var pkt packet
-
-		for {
-			select {
-			case pkt = <-packets:
-				n.handlePacket(pkt)
-			case <-n.grp.Ch():
-				return
-			}
-		}
-	}()
+	for i := 0; i < packetWorkers; i++ {
+		n.grp.Go("packet worker", func() { n.runPacketWorker(packets) })
+	}
 
 	// TODO: turn this back on when you're sure it works right
-	n.grp.Add(1)
-	go func() {
-		defer n.grp.Done()
-		n.startRoutingTableGrooming()
-	}()
+	n.grp.Go("routing table grooming", n.startRoutingTableGrooming)
+
+	n.grp.Go("store cleanup", n.startStoreCleanup)
 
 	return nil
 }
@@ -155,16 +142,35 @@ func (n *Node) Connect(conn UDPConn) error {
 // Shutdown shuts down the node
 func (n *Node) Shutdown() {
 	log.Debugf("[%s] node shutting down", n.id.HexShort())
-	n.grp.StopAndWait()
+	err := n.grp.StopAndWaitTimeout(shutdownWarnTimeout)
+	if err != nil {
+		log.Warnf("[%s] node is slow to shut down: %v", n.id.HexShort(), err)
+		n.grp.Wait()
+	}
 	log.Debugf("[%s] node stopped", n.id.HexShort())
 }
 
+// runPacketWorker handles packets from the queue until the node shuts down. several workers run at once, so one
+// slow handler doesn't stall the whole node.
+func (n *Node) runPacketWorker(packets <-chan packet) {
+	var pkt packet
+	for {
+		select {
+		case pkt = <-packets:
+			n.handlePacket(pkt)
+			pkt.release()
+		case <-n.grp.Ch():
+			return
+		}
+	}
+}
+
 // handlePacket handles packets received from udp.
 func (n *Node) handlePacket(pkt packet) {
-	//log.Debugf("[%s] Received message from %s (%d bytes) %s", n.id.HexShort(), pkt.raddr.String(), len(pkt.data), hex.EncodeToString(pkt.data))
+	n.observer.PacketReceived(pkt.raddr, pkt.data)
 
-	if !util.InSlice(string(pkt.data[0:5]), []string{"d1:0i", "di0ei"}) {
-		log.Errorf("[%s] data is not a well-formatted dict: (%d bytes) %s", n.id.HexShort(), len(pkt.data), hex.EncodeToString(pkt.data))
+	if len(pkt.data) < 6 || !util.InSlice(string(pkt.data[0:5]), []string{"d1:0i", "di0ei"}) {
+		n.observer.Error(pkt.raddr, errors.Err("data is not a well-formatted dict: (%d bytes) %s", len(pkt.data), hex.EncodeToString(pkt.data)))
 		return
 	}
 
@@ -177,34 +183,34 @@ func (n *Node) handlePacket(pkt packet) {
 		request := Request{}
 		err := bencode.DecodeBytes(pkt.data, &request)
 		if err != nil {
-			log.Errorf("[%s] error decoding request from %s: %s: (%d bytes) %s", n.id.HexShort(), pkt.raddr.String(), err.Error(), len(pkt.data), hex.EncodeToString(pkt.data))
+			n.observer.Error(pkt.raddr, errors.Err("error decoding request: %s: (%d bytes) %s", err.Error(), len(pkt.data), hex.EncodeToString(pkt.data)))
 			return
 		}
-		log.Debugf("[%s] query %s: received request from %s: %s(%s)", n.id.HexShort(), request.ID.HexShort(), request.NodeID.HexShort(), request.Method, request.argsDebug())
+		n.observer.MessageReceived(pkt.raddr, request)
 		n.handleRequest(pkt.raddr, request)
 
 	case '0' + responseType:
 		response := Response{}
 		err := bencode.DecodeBytes(pkt.data, &response)
 		if err != nil {
-			log.Errorf("[%s] error decoding response from %s: %s: (%d bytes) %s", n.id.HexShort(), pkt.raddr.String(), err.Error(), len(pkt.data), hex.EncodeToString(pkt.data))
+			n.observer.Error(pkt.raddr, errors.Err("error decoding response: %s: (%d bytes) %s", err.Error(), len(pkt.data), hex.EncodeToString(pkt.data)))
 			return
 		}
-		log.Debugf("[%s] query %s: received response from %s: %s", n.id.HexShort(), response.ID.HexShort(), response.NodeID.HexShort(), response.argsDebug())
+		n.observer.MessageReceived(pkt.raddr, response)
 		n.handleResponse(pkt.raddr, response)
 
 	case '0' + errorType:
 		e := Error{}
 		err := bencode.DecodeBytes(pkt.data, &e)
 		if err != nil {
-			log.Errorf("[%s] error decoding error from %s: %s: (%d bytes) %s", n.id.HexShort(), pkt.raddr.String(), err.Error(), len(pkt.data), hex.EncodeToString(pkt.data))
+			n.observer.Error(pkt.raddr, errors.Err("error decoding error: %s: (%d bytes) %s", err.Error(), len(pkt.data), hex.EncodeToString(pkt.data)))
 			return
 		}
-		log.Debugf("[%s] query %s: received error from %s: %s", n.id.HexShort(), e.ID.HexShort(), e.NodeID.HexShort(), e.ExceptionType)
+		n.observer.MessageReceived(pkt.raddr, e)
 		n.handleError(pkt.raddr, e)
 
 	default:
-		log.Errorf("[%s] invalid message type: %s", n.id.HexShort(), string(pkt.data[5]))
+		n.observer.Error(pkt.raddr, errors.Err("invalid message type: %s", string(pkt.data[5])))
 		return
 	}
 }
@@ -216,82 +222,136 @@ func (n *Node) handleRequest(addr *net.UDPAddr, request Request) {
 		return
 	}
 
+	// every request can carry a version, so no version means the node is on version 0
+	n.setRemoteVersion(request.NodeID, request.ProtocolVersion)
+	n.inbound.Received(addr)
+
 	// if a handler is overridden, call it instead
 	if n.requestHandler != nil {
 		n.requestHandler(addr, request)
+		n.observer.RequestHandled(addr, request)
 		return
 	}
 
-	switch request.Method {
-	default:
+	handler, ok := requestHandlers[request.Method]
+	if !ok {
 		//n.sendMessage(addr, Error{ID: request.ID, NodeID: n.id, ExceptionType: "invalid-request-method"})
 		log.Errorln("invalid request method")
 		return
-	case pingMethod:
-		err := n.sendMessage(addr, Response{ID: request.ID, NodeID: n.id, Data: pingSuccessResponse})
-		if err != nil {
-			log.Error("error sending 'pingmethod' response message - ", err)
-		}
-	case storeMethod:
-		// TODO: we should be sending the IP in the request, not just using the sender's IP
-		// TODO: should we be using StoreArgs.NodeID or StoreArgs.Value.LbryID ???
-		if n.tokens.Verify(request.StoreArgs.Value.Token, request.NodeID, addr) {
-			n.Store(request.StoreArgs.BlobHash, Contact{ID: request.StoreArgs.NodeID, IP: addr.IP, Port: addr.Port, PeerPort: request.StoreArgs.Value.Port})
+	}
+	handler(n, addr, request)
+	n.observer.RequestHandled(addr, request)
 
-			err := n.sendMessage(addr, Response{ID: request.ID, NodeID: n.id, Data: storeSuccessResponse})
-			if err != nil {
-				log.Error("error sending 'storemethod' response message - ", err)
-			}
+	// nodes that send us requests should not be inserted, only refreshed.
+	// the routing table must only contain "good" nodes, which are nodes that reply to our requests
+	// if a node is already good (aka in the table), its fine to refresh it
+	// http://www.bittorrent.org/beps/bep_0005.html#routing-table
+	n.rt.Fresh(Contact{ID: request.NodeID, IP: addr.IP, Port: addr.Port})
+}
+
+func (n *Node) handlePing(addr *net.UDPAddr, request Request) {
+	err := n.sendMessage(addr, Response{ID: request.ID, NodeID: n.id, Data: pingSuccessResponse})
+	if err != nil {
+		log.Error("error sending 'pingmethod' response message - ", err)
+	}
+}
+
+func (n *Node) handleStore(addr *net.UDPAddr, request Request) {
+	// TODO: we should be sending the IP in the request, not just using the sender's IP
+	// TODO: should we be using StoreArgs.NodeID or StoreArgs.Value.LbryID ???
+	if n.tokens.Verify(request.StoreArgs.Value.Token, request.NodeID, addr) {
+		if cached := request.StoreArgs.Cached; cached != nil {
+			n.storeCached(request.StoreArgs.BlobHash, *cached, Contact{ID: request.NodeID, IP: addr.IP})
 		} else {
-			err := n.sendMessage(addr, Error{ID: request.ID, NodeID: n.id, ExceptionType: "invalid-token"})
-			if err != nil {
-				log.Error("error sending 'storemethod'response message for invalid-token - ", err)
-			}
+			n.Store(request.StoreArgs.BlobHash, Contact{ID: request.StoreArgs.NodeID, IP: addr.IP, Port: addr.Port, PeerPort: request.StoreArgs.Value.Port})
 		}
-	case findNodeMethod:
-		if request.Arg == nil {
-			log.Errorln("request is missing arg")
-			return
+
+		err := n.sendMessage(addr, Response{ID: request.ID, NodeID: n.id, Data: storeSuccessResponse})
+		if err != nil {
+			log.Error("error sending 'storemethod' response message - ", err)
 		}
-		err := n.sendMessage(addr, Response{
-			ID:       request.ID,
-			NodeID:   n.id,
-			Contacts: n.rt.GetClosest(*request.Arg, bucketSize),
-		})
+	} else {
+		err := n.sendMessage(addr, Error{ID: request.ID, NodeID: n.id, ExceptionType: "invalid-token"})
 		if err != nil {
-			log.Error("error sending 'findnodemethod' response message - ", err)
+			log.Error("error sending 'storemethod'response message for invalid-token - ", err)
 		}
+	}
+}
 
-	case findValueMethod:
-		if request.Arg == nil {
-			log.Errorln("request is missing arg")
-			return
-		}
+// storeCached stores peers that another node found while looking up the hash. anyone with a token could send us any
+// addresses, so a peer is only kept if it's the sender or a node we know at that ip. and we don't keep them for long
+// or take too many of them.
+func (n *Node) storeCached(hash bits.Bitmap, cached cachedPeers, sender Contact) {
+	ttl := cached.TTL
+	if ttl > maxCacheTTL {
+		ttl = maxCacheTTL
+	}
+	if ttl < minCacheTTL {
+		return
+	}
 
-		res := Response{
-			ID:     request.ID,
-			NodeID: n.id,
-			Token:  n.tokens.Get(request.NodeID, addr),
-		}
+	peers := cached.Peers
+	if len(peers) > maxCachedPeers {
+		peers = peers[:maxCachedPeers]
+	}
 
-		if contacts := n.store.Get(*request.Arg); len(contacts) > 0 {
-			res.FindValueKey = request.Arg.RawString()
-			res.Contacts = contacts
-		} else {
-			res.Contacts = n.rt.GetClosest(*request.Arg, bucketSize)
+	expires := time.Now().Add(ttl)
+	for _, c := range peers {
+		if !n.verifiedPeer(c, sender) {
+			continue
 		}
+		n.store.UpsertCached(hash, c, expires)
+	}
+}
 
-		err := n.sendMessage(addr, res)
-		if err != nil {
-			log.Error("error sending 'findvaluemethod' response message - ", err)
-		}
+// verifiedPeer returns whether c is the sender, or a node in our routing table at the same ip
+func (n *Node) verifiedPeer(c Contact, sender Contact) bool {
+	if c.ID == sender.ID {
+		return c.IP.Equal(sender.IP)
 	}
+	known, ok := n.rt.Get(c.ID)
+	return ok && known.IP.Equal(c.IP)
+}
 
-	// nodes that send us requests should not be inserted, only refreshed.
-	// the routing table must only contain "good" nodes, which are nodes that reply to our requests
-	// if a node is already good (aka in the table), its fine to refresh it
-	// http://www.bittorrent.org/beps/bep_0005.html#routing-table
-	n.rt.Fresh(Contact{ID: request.NodeID, IP: addr.IP, Port: addr.Port})
+func (n *Node) handleFindNode(addr *net.UDPAddr, request Request) {
+	if request.Arg == nil {
+		log.Errorln("request is missing arg")
+		return
+	}
+	err := n.sendMessage(addr, Response{
+		ID:       request.ID,
+		NodeID:   n.id,
+		Contacts: n.rt.GetClosest(*request.Arg, bucketSize),
+	})
+	if err != nil {
+		log.Error("error sending 'findnodemethod' response message - ", err)
+	}
+}
+
+func (n *Node) handleFindValue(addr *net.UDPAddr, request Request) {
+	if request.Arg == nil {
+		log.Errorln("request is missing arg")
+		return
+	}
+
+	res := Response{
+		ID:              request.ID,
+		NodeID:          n.id,
+		Token:           n.tokens.Get(request.NodeID, addr),
+		ProtocolVersion: negotiateVersion(request.ProtocolVersion),
+	}
+
+	if contacts := n.store.Get(*request.Arg); len(contacts) > 0 {
+		res.FindValueKey = request.Arg.RawString()
+		res.Contacts = contacts
+	} else {
+		res.Contacts = n.rt.GetClosest(*request.Arg, bucketSize)
+	}
+
+	err := n.sendMessage(addr, res)
+	if err != nil {
+		log.Error("error sending 'findvaluemethod' response message - ", err)
+	}
 }
 
 // handleResponse handles responses received from udp.
@@ -305,12 +365,23 @@ func (n *Node) handleResponse(addr *net.UDPAddr, response Response) {
 		}
 	}
 
+	// only findValue responses carry a version, so the lack of one doesn't tell us anything
+	if response.ProtocolVersion > 0 {
+		n.setRemoteVersion(response.NodeID, response.ProtocolVersion)
+	}
+
+	// if the node included us in its contacts, that tells us what our ip looks like from the outside
+	for _, c := range response.Contacts {
+		if c.ID.Equals(n.id) {
+			n.externalIP.Add(response.NodeID, c.IP)
+		}
+	}
+
 	n.rt.Update(Contact{ID: response.NodeID, IP: addr.IP, Port: addr.Port})
 }
 
 // handleError handles errors received from udp.
 func (n *Node) handleError(addr *net.UDPAddr, e Error) {
-	spew.Dump(e)
 	n.rt.Fresh(Contact{ID: e.NodeID, IP: addr.IP, Port: addr.Port})
 }
 
@@ -321,26 +392,22 @@ func (n *Node) sendMessage(addr *net.UDPAddr, data Message) error {
 		return errors.Err(err)
 	}
 
-	if req, ok := data.(Request); ok {
-		log.Debugf("[%s] query %s: sending request to %s (%d bytes) %s(%s)",
-			n.id.HexShort(), req.ID.HexShort(), addr.String(), len(encoded), req.Method, req.argsDebug())
-	} else if res, ok := data.(Response); ok {
-		log.Debugf("[%s] query %s: sending response to %s (%d bytes) %s",
-			n.id.HexShort(), res.ID.HexShort(), addr.String(), len(encoded), res.argsDebug())
-	} else {
-		log.Debugf("[%s] (%d bytes) %s", n.id.HexShort(), len(encoded), spew.Sdump(data))
-	}
-
 	err = n.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
 	if err != nil {
-		if n.connClosed {
+		if n.connClosed.Load() {
 			return nil
 		}
-		log.Error("error setting write deadline - ", err)
+		return errors.Prefix("setting write deadline", err)
 	}
 
 	_, err = n.conn.WriteToUDP(encoded, addr)
-	return errors.Err(err)
+	if err != nil {
+		return errors.Err(err)
+	}
+	n.inbound.Sent(addr)
+
+	n.observer.MessageSent(addr, data, encoded)
+	return nil
 }
 
 // transaction represents a single query to the dht. it stores the queried contact, the request, and the response channel
@@ -388,60 +455,75 @@ type SendOptions struct {
 func (n *Node) SendAsync(contact Contact, req Request, options ...SendOptions) <-chan *Response {
 	ch := make(chan *Response, 1)
 
-	if contact.ID.Equals(n.id) {
-		log.Error("sending query to self")
-		close(ch)
-		return ch
-	}
-
 	go func() {
 		defer close(ch)
-
-		req.ID = newMessageID()
-		req.NodeID = n.id
-		tx := &transaction{
-			contact: contact,
-			req:     req,
-			res:     make(chan Response),
+		res, err := n.Send(contact, req, options...)
+		if err != nil {
+			if !strings.Contains(err.Error(), "use of closed network connection") { // this only happens on localhost. real UDP has no connections
+				log.Error("send error: ", err)
+			}
+			return
 		}
-
-		if len(options) > 0 && options[0].skipIDCheck {
-			tx.skipIDCheck = true
+		if res != nil {
+			ch <- res
 		}
+	}()
 
-		n.txInsert(tx)
-		defer n.txDelete(tx.req.ID)
+	return ch
+}
 
-		for i := 0; i < udpRetry; i++ {
-			err := n.sendMessage(contact.Addr(), tx.req)
-			if err != nil {
-				if !strings.Contains(err.Error(), "use of closed network connection") { // this only happens on localhost. real UDP has no connections
-					log.Error("send error: ", err)
-				}
-				continue
-			}
+// Send sends a transaction and blocks until the response is available. It returns a response, or nil
+// if the transaction timed out. An error is returned if the request could not be sent.
+func (n *Node) Send(contact Contact, req Request, options ...SendOptions) (*Response, error) {
+	if contact.ID.Equals(n.id) {
+		return nil, errors.Err("sending query to self")
+	}
 
-			select {
-			case res := <-tx.res:
-				ch <- &res
-				return
-			case <-n.grp.Ch():
-				return
-			case <-time.After(udpTimeout):
-			}
+	req.ID = newMessageID()
+	req.NodeID = n.id
+	req.ProtocolVersion = protocolVersion
+	if v, ok := n.RemoteProtocolVersion(contact.ID); ok {
+		req.ProtocolVersion = negotiateVersion(v)
+	} else if req.Method == storeMethod {
+		// nodes from before version negotiation reject store args with anything after the 4th field
+		req.ProtocolVersion = 0
+	}
+	tx := &transaction{
+		contact: contact,
+		req:     req,
+		res:     make(chan Response),
+	}
+
+	if len(options) > 0 && options[0].skipIDCheck {
+		tx.skipIDCheck = true
+	}
+
+	n.txInsert(tx)
+	defer n.txDelete(tx.req.ID)
+
+	var sendErr error
+	for i := 0; i < udpRetry; i++ {
+		sendErr = n.sendMessage(contact.Addr(), tx.req)
+		if sendErr != nil {
+			continue
 		}
 
-		// notify routing table about a failure to respond
-		n.rt.Fail(tx.contact)
-	}()
+		select {
+		case res := <-tx.res:
+			return &res, nil
+		case <-n.grp.Ch():
+			return nil, nil
+		case <-time.After(n.timeout):
+		}
+	}
 
-	return ch
-}
+	if sendErr != nil {
+		return nil, sendErr
+	}
 
-// Send sends a transaction and blocks until the response is available. It returns a response, or nil
-// if the transaction timed out.
-func (n *Node) Send(contact Contact, req Request, options ...SendOptions) *Response {
-	return <-n.SendAsync(contact, req, options...)
+	// notify routing table about a failure to respond
+	n.rt.Fail(tx.contact)
+	return nil, nil
 }
 
 // CountActiveTransactions returns the number of transactions in the manager
@@ -456,7 +538,20 @@ func (n *Node) startRoutingTableGrooming() {
 	for {
 		select {
 		case <-refreshTicker.C:
-			RoutingTableRefresh(n, tRefresh, n.grp.Child())
+			RoutingTableRefresh(n, tRefresh, n.grp.ChildNamed("refresh"))
+		case <-n.grp.Ch():
+			return
+		}
+	}
+}
+
+func (n *Node) startStoreCleanup() {
+	ticker := time.NewTicker(storeCleanupRate)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			n.store.RemoveExpired()
 		case <-n.grp.Ch():
 			return
 		}
@@ -468,7 +563,41 @@ func (n *Node) Store(hash bits.Bitmap, c Contact) {
 	n.store.Upsert(hash, c)
 }
 
-//AddKnownNode adds a known-good node to the routing table
+// AddKnownNode adds a known-good node to the routing table
 func (n *Node) AddKnownNode(c Contact) {
 	n.rt.Update(c)
 }
+
+// ExternalIP returns the ip that other nodes see this node at, once enough of them agree on it. It returns nil
+// until then.
+func (n *Node) ExternalIP() net.IP {
+	return n.externalIP.Get()
+}
+
+// RemoteProtocolVersion returns the protocol version that a remote node last sent, and whether we've heard from it
+func (n *Node) RemoteProtocolVersion(id bits.Bitmap) (int, bool) {
+	n.versionsLock.RLock()
+	defer n.versionsLock.RUnlock()
+	v, ok := n.versions[id]
+	return v, ok
+}
+
+func (n *Node) setRemoteVersion(id bits.Bitmap, version int) {
+	n.versionsLock.Lock()
+	defer n.versionsLock.Unlock()
+	if _, ok := n.versions[id]; !ok && len(n.versions) >= maxRemoteVersions {
+		for old := range n.versions {
+			delete(n.versions, old)
+			break
+		}
+	}
+	n.versions[id] = version
+}
+
+// negotiateVersion returns the highest protocol version that both we and the remote node speak
+func negotiateVersion(remote int) int {
+	if remote < protocolVersion {
+		return remote
+	}
+	return protocolVersion
+}
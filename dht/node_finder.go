@@ -35,9 +35,11 @@ type contactFinder struct {
 
 	findValueMutex  *sync.Mutex
 	findValueResult []Contact
+	findValueFrom   *Contact // the node that had the value
 
 	activeContactsMutex *sync.Mutex
 	activeContacts      []Contact
+	tokens              map[bits.Bitmap]string // store tokens from the active contacts
 
 	shortlistMutex *sync.Mutex
 	shortlist      []Contact
@@ -55,6 +57,7 @@ func FindContacts(node *Node, target bits.Bitmap, findValue bool, parentGrp *sto
 		findValue:           findValue,
 		findValueMutex:      &sync.Mutex{},
 		activeContactsMutex: &sync.Mutex{},
+		tokens:              make(map[bits.Bitmap]string),
 		shortlistMutex:      &sync.Mutex{},
 		shortlistAdded:      make(map[bits.Bitmap]bool),
 		grp:                 stop.New(parentGrp),
@@ -82,11 +85,10 @@ func (cf *contactFinder) Find() ([]Contact, bool, error) {
 	}
 
 	go cf.cycle(false)
-	timeout := 5 * time.Second
 CycleLoop:
 	for {
 		select {
-		case <-time.After(timeout):
+		case <-time.After(cf.node.timeout):
 			go cf.cycle(false)
 		case <-cf.grp.Ch():
 			break CycleLoop
@@ -100,6 +102,7 @@ CycleLoop:
 	if cf.findValue && len(cf.findValueResult) > 0 {
 		contacts = cf.findValueResult
 		found = true
+		cf.cacheValue()
 	} else {
 		contacts = cf.activeContacts
 		if len(contacts) > bucketSize {
@@ -226,14 +229,17 @@ func (cf *contactFinder) probe(cycleID string) *Contact {
 	if cf.findValue && res.FindValueKey != "" {
 		cf.debug("|%s| probe %s: got value", cycleID, c.ID.HexShort())
 		cf.findValueMutex.Lock()
-		cf.findValueResult = res.Contacts
+		if cf.findValueFrom == nil {
+			cf.findValueResult = res.Contacts
+			cf.findValueFrom = &c
+		}
 		cf.findValueMutex.Unlock()
 		cf.grp.Stop()
 		return nil
 	}
 
 	cf.debug("|%s| probe %s: got %s", cycleID, c.ID.HexShort(), res.argsDebug())
-	cf.insertIntoActiveList(c)
+	cf.insertIntoActiveList(c, res.Token)
 	cf.appendNewToShortlist(res.Contacts)
 
 	cf.activeContactsMutex.Lock()
@@ -281,10 +287,12 @@ func (cf *contactFinder) popFromShortlist() *Contact {
 }
 
 // insertIntoActiveList inserts the contact into appropriate place in the list of active contacts (sorted by distance)
-func (cf *contactFinder) insertIntoActiveList(contact Contact) {
+func (cf *contactFinder) insertIntoActiveList(contact Contact, token string) {
 	cf.activeContactsMutex.Lock()
 	defer cf.activeContactsMutex.Unlock()
 
+	cf.tokens[contact.ID] = token
+
 	inserted := false
 	for i, n := range cf.activeContacts {
 		if cf.target.Closer(contact.ID, n.ID) {
@@ -298,6 +306,79 @@ func (cf *contactFinder) insertIntoActiveList(contact Contact) {
 	}
 }
 
+// cacheValue stores the peers that were found at the closest node that was asked for them and didn't have them, like
+// the Kademlia paper says. the next lookup for the hash will probably pass through that node and stop there. the
+// cached entry expires sooner the farther that node is from the one that had the value, so popular hashes end up
+// cached along all the paths to them without old entries piling up far from the target.
+func (cf *contactFinder) cacheValue() {
+	cf.findValueMutex.Lock()
+	from := cf.findValueFrom
+	peers := cf.findValueResult
+	cf.findValueMutex.Unlock()
+	if from == nil {
+		return
+	}
+
+	cf.activeContactsMutex.Lock()
+	if len(cf.activeContacts) == 0 {
+		cf.activeContactsMutex.Unlock()
+		return
+	}
+	closest := cf.activeContacts[0]
+	token := cf.tokens[closest.ID]
+	cf.activeContactsMutex.Unlock()
+
+	if token == "" {
+		return
+	}
+	if v, ok := cf.node.RemoteProtocolVersion(closest.ID); !ok || v < cachedStoreVersion {
+		return // older nodes reject store requests with cached peers
+	}
+
+	ttl := cacheTTL(cf.target, from.ID, closest.ID)
+	if ttl < minCacheTTL {
+		return
+	}
+
+	var cached []Contact
+	for _, p := range peers {
+		if p.IP.To4() != nil && len(cached) < maxCachedPeers {
+			cached = append(cached, p)
+		}
+	}
+	if len(cached) == 0 {
+		return
+	}
+
+	cf.debug("caching %d peers at %s for %s", len(cached), closest.ID.HexShort(), ttl)
+	cf.node.SendAsync(closest, Request{
+		Method: storeMethod,
+		StoreArgs: &storeArgs{
+			BlobHash: cf.target,
+			Value: storeArgsValue{
+				Token:  token,
+				LbryID: cf.node.id,
+			},
+			NodeID: cf.node.id,
+			Cached: &cachedPeers{Peers: cached, TTL: ttl},
+		},
+	})
+}
+
+// cacheTTL is how long to cache a value at a node, given the node that had the value. there are about twice as many
+// nodes between an id and the target for every bit of prefix that it doesn't share with the target, so the ttl is
+// halved for each bit of prefix the caching node has fewer than the node with the value
+func cacheTTL(target, holder, cacher bits.Bitmap) time.Duration {
+	gap := target.CommonPrefixLen(holder) - target.CommonPrefixLen(cacher)
+	if gap <= 0 {
+		return maxCacheTTL
+	}
+	if gap >= 63 {
+		return 0
+	}
+	return maxCacheTTL >> uint(gap)
+}
+
 // isSearchFinished returns true if the search is done and should be stopped
 func (cf *contactFinder) isSearchFinished() bool {
 	if cf.findValue && len(cf.findValueResult) > 0 {
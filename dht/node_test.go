@@ -2,11 +2,15 @@ package dht
 
 import (
 	"net"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/lbryio/lbry.go/v2/dht/bits"
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
 	"github.com/lyoshenka/bencode"
+	"github.com/sirupsen/logrus"
 )
 
 func TestPing(t *testing.T) {
@@ -15,9 +19,12 @@ func TestPing(t *testing.T) {
 
 	conn := newTestUDPConn("127.0.0.1:21217")
 
-	dht := New(&Config{Address: "127.0.0.1:21216", NodeID: dhtNodeID.Hex()})
+	dht, err := New(&Config{Address: "127.0.0.1:21216", NodeID: dhtNodeID.Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	err := dht.connect(conn)
+	err = dht.connect(conn)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -109,9 +116,12 @@ func TestStore(t *testing.T) {
 
 	conn := newTestUDPConn("127.0.0.1:21217")
 
-	dht := New(&Config{Address: "127.0.0.1:21216", NodeID: dhtNodeID.Hex()})
+	dht, err := New(&Config{Address: "127.0.0.1:21216", NodeID: dhtNodeID.Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	err := dht.connect(conn)
+	err = dht.connect(conn)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -198,15 +208,205 @@ func TestStore(t *testing.T) {
 	}
 }
 
+// decodeStoreArgsV0 decodes store args the way nodes from before version negotiation do. they only accept
+// exactly 4 fields
+func decodeStoreArgsV0(b []byte) (*storeArgs, error) {
+	var args []bencode.RawMessage
+	err := bencode.DecodeBytes(b, &args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 4 {
+		return nil, errors.Err("unexpected number of fields for store args. got %d", len(args))
+	}
+	s := &storeArgs{}
+	for i, field := range []interface{}{&s.BlobHash, &s.Value, &s.NodeID, new(int)} {
+		err = bencode.DecodeBytes(args[i], field)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func TestStoreVersionCompatibility(t *testing.T) {
+	conn := newTestUDPConn("127.0.0.1:21217")
+
+	dht, err := New(&Config{Address: "127.0.0.1:21216", NodeID: bits.Rand().Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dht.connect(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dht.Shutdown()
+
+	unknown := Contact{ID: bits.Rand(), IP: net.ParseIP("127.0.0.1"), Port: 21217}
+	upgraded := Contact{ID: bits.Rand(), IP: net.ParseIP("127.0.0.1"), Port: 21217}
+	dht.node.setRemoteVersion(upgraded.ID, 1)
+
+	for _, c := range []Contact{unknown, upgraded} {
+		blobHash := bits.Rand()
+		dht.node.SendAsync(c, Request{
+			Method: storeMethod,
+			StoreArgs: &storeArgs{
+				BlobHash: blobHash,
+				Value:    storeArgsValue{Token: "token", LbryID: dht.node.id, Port: 3333},
+				NodeID:   dht.node.id,
+			},
+		})
+
+		var sent struct {
+			Args bencode.RawMessage `bencode:"4"`
+		}
+		var data []byte
+		select {
+		case <-time.After(3 * time.Second):
+			t.Fatal("timeout")
+		case req := <-conn.writes:
+			data = req.data
+		}
+		err = bencode.DecodeBytes(data, &sent)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if c.ID == unknown.ID {
+			args, err := decodeStoreArgsV0(sent.Args)
+			if err != nil {
+				t.Fatalf("a node that doesn't know about versions can't decode the store: %v", err)
+			}
+			if !args.BlobHash.Equals(blobHash) {
+				t.Error("wrong blob hash")
+			}
+			continue
+		}
+
+		var decoded Request
+		err = bencode.DecodeBytes(data, &decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.ProtocolVersion != 1 {
+			t.Errorf("expected protocol version 1 in a store to a version 1 node, got %d", decoded.ProtocolVersion)
+		}
+	}
+}
+
+func TestRemoteVersionsBounded(t *testing.T) {
+	n := NewNode(bits.Rand())
+	for i := 0; i < maxRemoteVersions+10; i++ {
+		n.setRemoteVersion(bits.Rand(), 1)
+	}
+	if len(n.versions) != maxRemoteVersions {
+		t.Errorf("expected %d remembered versions, got %d", maxRemoteVersions, len(n.versions))
+	}
+}
+
+func TestStoreCached(t *testing.T) {
+	dhtNodeID := bits.Rand()
+	testNodeID := bits.Rand()
+
+	conn := newTestUDPConn("127.0.0.1:21217")
+
+	dht, err := New(&Config{Address: "127.0.0.1:21216", NodeID: dhtNodeID.Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dht.connect(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dht.Shutdown()
+
+	messageID := newMessageID()
+	blobHash := bits.Rand()
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1).To4(), Port: 21217}
+	peer := Contact{ID: bits.Rand(), IP: net.IPv4(1, 2, 3, 4).To4(), PeerPort: 3333}
+	dht.node.AddKnownNode(Contact{ID: peer.ID, IP: peer.IP, Port: 4444})
+	// not in the routing table, and a known node at the wrong ip
+	forged := Contact{ID: bits.Rand(), IP: net.IPv4(6, 6, 6, 6).To4(), PeerPort: 3333}
+	moved := Contact{ID: bits.Rand(), IP: net.IPv4(6, 6, 6, 7).To4(), PeerPort: 3333}
+	dht.node.AddKnownNode(Contact{ID: moved.ID, IP: net.IPv4(5, 6, 7, 8).To4(), Port: 4444})
+	// the sender can cache itself
+	sender := Contact{ID: testNodeID, IP: addr.IP, PeerPort: 5555}
+
+	data, err := bencode.EncodeBytes(Request{
+		ID:              messageID,
+		NodeID:          testNodeID,
+		Method:          storeMethod,
+		ProtocolVersion: cachedStoreVersion,
+		StoreArgs: &storeArgs{
+			BlobHash: blobHash,
+			Value: storeArgsValue{
+				Token:  dht.node.tokens.Get(testNodeID, addr),
+				LbryID: testNodeID,
+			},
+			NodeID: testNodeID,
+			Cached: &cachedPeers{Peers: []Contact{forged, peer, moved, sender}, TTL: 2 * maxCacheTTL},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.toRead <- testUDPPacket{addr: addr, data: data}
+
+	var response map[string]interface{}
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout")
+	case resp := <-conn.writes:
+		err := bencode.DecodeBytes(resp.data, &response)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	verifyResponse(t, response, messageID, dhtNodeID.RawString())
+	if response[headerPayloadField] != storeSuccessResponse {
+		t.Errorf("expected %s, got %v", storeSuccessResponse, response[headerPayloadField])
+	}
+
+	// the cached peers we could verify are stored, not the node that sent them
+	items := dht.node.store.Get(blobHash)
+	if len(items) != 2 {
+		t.Fatalf("expected 2 stored peers, got %d: %v", len(items), items)
+	}
+	for _, item := range items {
+		if item.ID.Equals(forged.ID) || item.ID.Equals(moved.ID) {
+			t.Errorf("stored a forged peer: %s", item)
+		}
+		if item.ID.Equals(peer.ID) && (!item.IP.Equal(peer.IP) || item.PeerPort != peer.PeerPort) {
+			t.Errorf("wrong peer stored: %s", item)
+		}
+		if item.ID.Equals(sender.ID) && item.PeerPort != sender.PeerPort {
+			t.Errorf("wrong sender stored: %s", item)
+		}
+	}
+
+	// the ttl is capped
+	expires := dht.node.store.hashes[blobHash][peer.ID]
+	if time.Until(expires) > maxCacheTTL {
+		t.Errorf("cached peer expires in %s, more than %s", time.Until(expires), maxCacheTTL)
+	}
+}
+
 func TestFindNode(t *testing.T) {
 	dhtNodeID := bits.Rand()
 	testNodeID := bits.Rand()
 
 	conn := newTestUDPConn("127.0.0.1:21217")
 
-	dht := New(&Config{Address: "127.0.0.1:21216", NodeID: dhtNodeID.Hex()})
+	dht, err := New(&Config{Address: "127.0.0.1:21216", NodeID: dhtNodeID.Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	err := dht.connect(conn)
+	err = dht.connect(conn)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -270,9 +470,12 @@ func TestFindValueExisting(t *testing.T) {
 
 	conn := newTestUDPConn("127.0.0.1:21217")
 
-	dht := New(&Config{Address: "127.0.0.1:21216", NodeID: dhtNodeID.Hex()})
+	dht, err := New(&Config{Address: "127.0.0.1:21216", NodeID: dhtNodeID.Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	err := dht.connect(conn)
+	err = dht.connect(conn)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -351,9 +554,12 @@ func TestFindValueFallbackToFindNode(t *testing.T) {
 
 	conn := newTestUDPConn("127.0.0.1:21217")
 
-	dht := New(&Config{Address: "127.0.0.1:21216", NodeID: dhtNodeID.Hex()})
+	dht, err := New(&Config{Address: "127.0.0.1:21216", NodeID: dhtNodeID.Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	err := dht.connect(conn)
+	err = dht.connect(conn)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -420,3 +626,226 @@ func TestFindValueFallbackToFindNode(t *testing.T) {
 
 	verifyContacts(t, contacts, nodes)
 }
+
+func TestConcurrentRequests(t *testing.T) {
+	dhtNodeID := bits.Rand()
+
+	conn := newTestUDPConn("127.0.0.1:21217")
+
+	dht, err := New(&Config{Address: "127.0.0.1:21216", NodeID: dhtNodeID.Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dht.connect(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dht.Shutdown()
+
+	// every request touches the routing table, stores write to the contact store and
+	// findValues read from it, so running with -race exercises all the shared state.
+	// half the senders are already in the routing table, so their requests update known
+	// contacts while findNodes and the inserts below read and split the buckets
+	numSenders := 4 * packetWorkers
+	blobHash := bits.Rand()
+
+	nodeIDs := make([]bits.Bitmap, numSenders)
+	for i := range nodeIDs {
+		nodeIDs[i] = bits.Rand()
+		if i%2 == 0 {
+			dht.node.AddKnownNode(Contact{ID: nodeIDs[i], IP: net.ParseIP("127.0.0.1"), Port: 10000 + i})
+		}
+	}
+
+	inserted := make(chan struct{})
+	go func() {
+		defer close(inserted)
+		for i := 0; i < numSenders; i++ {
+			dht.node.AddKnownNode(Contact{ID: bits.Rand(), IP: net.ParseIP("127.0.0.2"), Port: 10000 + i})
+			dht.node.rt.Count()
+		}
+	}()
+
+	go func() {
+		for i := 0; i < numSenders; i++ {
+			testNodeID := nodeIDs[i]
+			addr := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 10000 + i}
+
+			var request Request
+			switch i % 4 {
+			case 0:
+				request = Request{ID: newMessageID(), NodeID: testNodeID, Method: pingMethod}
+			case 3:
+				request = Request{ID: newMessageID(), NodeID: testNodeID, Method: findNodeMethod, Arg: &blobHash}
+			case 1:
+				request = Request{
+					ID:     newMessageID(),
+					NodeID: testNodeID,
+					Method: storeMethod,
+					StoreArgs: &storeArgs{
+						BlobHash: blobHash,
+						Value: storeArgsValue{
+							Token:  dht.node.tokens.Get(testNodeID, addr),
+							LbryID: testNodeID,
+							Port:   9999,
+						},
+						NodeID: testNodeID,
+					},
+				}
+			case 2:
+				request = Request{ID: newMessageID(), NodeID: testNodeID, Method: findValueMethod, Arg: &blobHash}
+			}
+
+			data, err := bencode.EncodeBytes(request)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			conn.toRead <- testUDPPacket{addr: addr, data: data}
+		}
+	}()
+
+	timer := time.NewTimer(10 * time.Second)
+	for i := 0; i < numSenders; i++ {
+		select {
+		case <-timer.C:
+			t.Fatalf("timeout after %d of %d responses", i, numSenders)
+		case resp := <-conn.writes:
+			var response map[string]interface{}
+			err := bencode.DecodeBytes(resp.data, &response)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rType, ok := response[headerTypeField].(int64); !ok || rType != responseType {
+				t.Errorf("unexpected response type %v", response[headerTypeField])
+			}
+		}
+	}
+
+	<-inserted
+
+	items := dht.node.store.Get(blobHash)
+	if len(items) != numSenders/4 {
+		t.Errorf("expected %d stored contacts, got %d", numSenders/4, len(items))
+	}
+}
+
+type testObserver struct {
+	mu       sync.Mutex
+	received []Message
+	sent     []Message
+	errs     []error
+	handled  chan Request
+}
+
+func (o *testObserver) PacketReceived(from *net.UDPAddr, data []byte) {}
+
+func (o *testObserver) MessageReceived(from *net.UDPAddr, msg Message) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.received = append(o.received, msg)
+}
+
+func (o *testObserver) RequestHandled(from *net.UDPAddr, request Request) {
+	o.handled <- request
+}
+
+func (o *testObserver) MessageSent(to *net.UDPAddr, msg Message, data []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.sent = append(o.sent, msg)
+}
+
+func (o *testObserver) Error(addr *net.UDPAddr, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.errs = append(o.errs, err)
+}
+
+func TestMessageObserver(t *testing.T) {
+	dhtNodeID := bits.Rand()
+	testNodeID := bits.Rand()
+
+	conn := newTestUDPConn("127.0.0.1:21217")
+	observer := &testObserver{handled: make(chan Request, 1)}
+
+	dht, err := New(&Config{Address: "127.0.0.1:21216", NodeID: dhtNodeID.Hex(), MessageObserver: observer})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dht.connect(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dht.Shutdown()
+
+	conn.toRead <- testUDPPacket{addr: conn.addr, data: []byte("garbage")}
+
+	data, err := bencode.EncodeBytes(Request{ID: newMessageID(), NodeID: testNodeID, Method: pingMethod})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.toRead <- testUDPPacket{addr: conn.addr, data: data}
+
+	select {
+	case <-conn.writes:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for response")
+	}
+
+	select {
+	case req := <-observer.handled:
+		if req.Method != pingMethod {
+			t.Errorf("expected handled request to be a ping, got %s", req.Method)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timeout waiting for request to be handled")
+	}
+
+	// the garbage packet may be handled by a different worker than the ping, so give it a moment
+	for start := time.Now(); time.Since(start) < 3*time.Second; time.Sleep(10 * time.Millisecond) {
+		observer.mu.Lock()
+		numErrs := len(observer.errs)
+		observer.mu.Unlock()
+		if numErrs > 0 {
+			break
+		}
+	}
+
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+
+	if len(observer.errs) != 1 {
+		t.Errorf("expected 1 error, got %d", len(observer.errs))
+	}
+	if len(observer.received) != 1 {
+		t.Errorf("expected 1 received message, got %d", len(observer.received))
+	} else if _, ok := observer.received[0].(Request); !ok {
+		t.Errorf("expected received message to be a request, got %T", observer.received[0])
+	}
+	if len(observer.sent) != 1 {
+		t.Errorf("expected 1 sent message, got %d", len(observer.sent))
+	} else if res, ok := observer.sent[0].(Response); !ok || res.Data != pingSuccessResponse {
+		t.Errorf("expected sent message to be a pong, got %v", observer.sent[0])
+	}
+}
+
+func TestLogObserver_NoDebug(t *testing.T) {
+	level := log.GetLevel()
+	log.SetLevel(logrus.InfoLevel)
+	defer log.SetLevel(level)
+
+	o := logObserver{id: bits.Rand()}
+	addr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 4444}
+	var msg Message = Error{ID: newMessageID(), NodeID: bits.Rand(), ExceptionType: "invalid-token"}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		o.MessageSent(addr, msg, nil)
+		o.MessageReceived(addr, msg)
+	})
+	if allocs > 0 {
+		t.Errorf("expected no work without debug logging, got %v allocations per message", allocs)
+	}
+}
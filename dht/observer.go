@@ -0,0 +1,77 @@
+package dht
+
+import (
+	"encoding/hex"
+	"net"
+
+	"github.com/lbryio/lbry.go/v2/dht/bits"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/sirupsen/logrus"
+)
+
+// MessageObserver is notified about the traffic a node sends and receives. It can be used to wire up structured
+// logging, tracing, or packet capture. Methods are called from the goroutines that handle packets, so they
+// must be safe for concurrent use and should return quickly.
+type MessageObserver interface {
+	// PacketReceived is called with the raw bytes of every packet, before it is decoded. data is reused for later
+	// packets once the packet is handled, so it must be copied if it's kept around
+	PacketReceived(from *net.UDPAddr, data []byte)
+	// MessageReceived is called with every message that was decoded successfully. msg is a Request, Response, or Error
+	MessageReceived(from *net.UDPAddr, msg Message)
+	// RequestHandled is called after this node has finished handling a request from another node
+	RequestHandled(from *net.UDPAddr, request Request)
+	// MessageSent is called after a message is encoded and written to the connection
+	MessageSent(to *net.UDPAddr, msg Message, data []byte)
+	// Error is called when a received packet can't be decoded
+	Error(addr *net.UDPAddr, err error)
+}
+
+// logObserver is the default MessageObserver. It logs traffic at debug level and errors at error level. Traffic isn't
+// formatted at all unless debug logging is on, so it costs next to nothing otherwise.
+type logObserver struct {
+	id bits.Bitmap
+}
+
+func (l logObserver) PacketReceived(from *net.UDPAddr, data []byte) {
+	if !log.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+	log.Debugf("[%s] received packet from %s (%d bytes) %s", l.id.HexShort(), from.String(), len(data), hex.EncodeToString(data))
+}
+
+func (l logObserver) MessageReceived(from *net.UDPAddr, msg Message) {
+	if !log.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+	switch m := msg.(type) {
+	case Request:
+		log.Debugf("[%s] query %s: received request from %s: %s(%s)", l.id.HexShort(), m.ID.HexShort(), m.NodeID.HexShort(), m.Method, m.argsDebug())
+	case Response:
+		log.Debugf("[%s] query %s: received response from %s: %s", l.id.HexShort(), m.ID.HexShort(), m.NodeID.HexShort(), m.argsDebug())
+	case Error:
+		log.Debugf("[%s] query %s: received error from %s: %s", l.id.HexShort(), m.ID.HexShort(), m.NodeID.HexShort(), m.ExceptionType)
+	}
+}
+
+func (l logObserver) RequestHandled(from *net.UDPAddr, request Request) {}
+
+func (l logObserver) MessageSent(to *net.UDPAddr, msg Message, data []byte) {
+	if !log.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+	switch m := msg.(type) {
+	case Request:
+		log.Debugf("[%s] query %s: sending request to %s (%d bytes) %s(%s)",
+			l.id.HexShort(), m.ID.HexShort(), to.String(), len(data), m.Method, m.argsDebug())
+	case Response:
+		log.Debugf("[%s] query %s: sending response to %s (%d bytes) %s",
+			l.id.HexShort(), m.ID.HexShort(), to.String(), len(data), m.argsDebug())
+	default:
+		log.Debugf("[%s] (%d bytes) %s", l.id.HexShort(), len(data), spew.Sdump(msg))
+	}
+}
+
+func (l logObserver) Error(addr *net.UDPAddr, err error) {
+	log.Errorf("[%s] %s: %s", l.id.HexShort(), addr.String(), err.Error())
+}
@@ -0,0 +1,63 @@
+package dht
+
+import (
+	"net"
+	"sync"
+
+	"go.uber.org/atomic"
+)
+
+// packetBuffers holds read buffers between packets, so a busy node doesn't allocate a new buffer for every packet
+var packetBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, udpMaxMessageLength)
+		return &b
+	},
+}
+
+// packet represents the information receive from udp.
+type packet struct {
+	data  []byte
+	raddr *net.UDPAddr
+	buf   *[]byte // the pooled buffer that data points into
+}
+
+// release returns the packet's buffer to the pool. the packet data must not be used after this, so anything that
+// needs to outlive the handler has to be copied out first.
+func (p *packet) release() {
+	if p.buf == nil {
+		return
+	}
+	packetBuffers.Put(p.buf)
+	p.buf = nil
+	p.data = nil
+}
+
+// readPackets reads from conn into pooled buffers and sends each packet on the packets channel, until stop is closed
+// or the connection is closed. whoever receives a packet owns its buffer and must release it.
+func readPackets(conn UDPConn, connClosed *atomic.Bool, packets chan<- packet, stop <-chan struct{}) {
+	for {
+		buf := packetBuffers.Get().(*[]byte)
+
+		bytesRead, raddr, err := conn.ReadFromUDP(*buf)
+		if err != nil {
+			packetBuffers.Put(buf)
+			if connClosed.Load() {
+				return
+			}
+			log.Errorf("udp read error: %v", err)
+			continue
+		} else if raddr == nil {
+			packetBuffers.Put(buf)
+			log.Errorf("udp read with no raddr")
+			continue
+		}
+
+		select { // needs select here because packet consumer can quit and the packets channel gets filled up and blocks
+		case packets <- packet{data: (*buf)[:bytesRead], raddr: raddr, buf: buf}:
+		case <-stop:
+			packetBuffers.Put(buf)
+			return
+		}
+	}
+}
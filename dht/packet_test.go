@@ -0,0 +1,152 @@
+package dht
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/dht/bits"
+
+	"go.uber.org/atomic"
+)
+
+// loopConn returns the same packet from every read, as fast as it can, until it's closed
+type loopConn struct {
+	data   []byte
+	addr   *net.UDPAddr
+	closed *atomic.Bool
+}
+
+func (l loopConn) ReadFromUDP(b []byte) (int, *net.UDPAddr, error) {
+	if l.closed.Load() {
+		return 0, nil, net.ErrClosed
+	}
+	return copy(b, l.data), l.addr, nil
+}
+func (l loopConn) WriteToUDP(b []byte, addr *net.UDPAddr) (int, error) { return len(b), nil }
+func (l loopConn) SetReadDeadline(t time.Time) error                   { return nil }
+func (l loopConn) SetWriteDeadline(t time.Time) error                  { return nil }
+func (l loopConn) Close() error                                        { l.closed.Store(true); return nil }
+
+func TestReadPackets_BuffersNotShared(t *testing.T) {
+	conn := newTestUDPConn("127.0.0.1:21217")
+	packets := make(chan packet, 2)
+	stop := make(chan struct{})
+	defer close(stop)
+	connClosed := atomic.NewBool(false)
+	defer func() {
+		connClosed.Store(true)
+		conn.Close()
+	}()
+
+	go readPackets(conn, connClosed, packets, stop)
+
+	conn.toRead <- testUDPPacket{addr: conn.addr, data: []byte("first")}
+	conn.toRead <- testUDPPacket{addr: conn.addr, data: []byte("second")}
+
+	// both packets are queued before either is handled, so they must not share a buffer
+	first := <-packets
+	second := <-packets
+	if string(first.data) != "first" {
+		t.Errorf("expected 'first', got '%s'", string(first.data))
+	}
+	if string(second.data) != "second" {
+		t.Errorf("expected 'second', got '%s'", string(second.data))
+	}
+
+	first.release()
+	second.release()
+	if first.data != nil || first.buf != nil {
+		t.Error("expected release to clear the packet")
+	}
+	second.release() // releasing twice is a no-op
+}
+
+func benchmarkConn(b *testing.B) loopConn {
+	data, err := Request{
+		ID:     newMessageID(),
+		NodeID: bits.Rand(),
+		Method: pingMethod,
+	}.MarshalBencode()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return loopConn{
+		data:   data,
+		addr:   &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4444},
+		closed: atomic.NewBool(false),
+	}
+}
+
+// BenchmarkReadPackets measures reading and dispatching packets with pooled buffers
+func BenchmarkReadPackets(b *testing.B) {
+	conn := benchmarkConn(b)
+	packets := make(chan packet, packetQueueLength)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go readPackets(conn, conn.closed, packets, stop)
+	defer conn.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pkt := <-packets
+		pkt.release()
+	}
+}
+
+// BenchmarkReadPackets_Alloc is the same as BenchmarkReadPackets, but allocates a new buffer for every packet, which
+// is what the node did before buffers were pooled
+func BenchmarkReadPackets_Alloc(b *testing.B) {
+	conn := benchmarkConn(b)
+	packets := make(chan packet, packetQueueLength)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		buf := make([]byte, udpMaxMessageLength)
+		for {
+			bytesRead, raddr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			data := make([]byte, bytesRead)
+			copy(data, buf[:bytesRead])
+			select {
+			case packets <- packet{data: data, raddr: raddr}:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	defer conn.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		<-packets
+	}
+}
+
+// BenchmarkHandlePacket measures a node handling ping requests end to end, from the read to the response write
+func BenchmarkHandlePacket(b *testing.B) {
+	conn := benchmarkConn(b)
+	dhtNode := NewNode(bits.Rand())
+	err := dhtNode.tokens.Start(tokenSecretRotationInterval)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer dhtNode.tokens.Stop()
+	dhtNode.conn = conn
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf := packetBuffers.Get().(*[]byte)
+		n, raddr, _ := conn.ReadFromUDP(*buf)
+		pkt := packet{data: (*buf)[:n], raddr: raddr, buf: buf}
+		dhtNode.handlePacket(pkt)
+		pkt.release()
+	}
+}
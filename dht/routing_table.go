@@ -72,13 +72,13 @@ func newBucket(r bits.Range) *bucket {
 }
 
 // Len returns the number of peers in the bucket
-func (b bucket) Len() int {
+func (b *bucket) Len() int {
 	b.lock.RLock()
 	defer b.lock.RUnlock()
 	return len(b.peers)
 }
 
-func (b bucket) Has(c Contact) bool {
+func (b *bucket) Has(c Contact) bool {
 	b.lock.RLock()
 	defer b.lock.RUnlock()
 	for _, p := range b.peers {
@@ -89,8 +89,19 @@ func (b bucket) Has(c Contact) bool {
 	return false
 }
 
+// Get returns the bucket's contact with the given id, if it has one
+func (b *bucket) Get(id bits.Bitmap) (Contact, bool) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	i := find(id, b.peers)
+	if i < 0 {
+		return Contact{}, false
+	}
+	return b.peers[i].Contact, true
+}
+
 // Contacts returns a slice of the bucket's contacts
-func (b bucket) Contacts() []Contact {
+func (b *bucket) Contacts() []Contact {
 	b.lock.RLock()
 	defer b.lock.RUnlock()
 	contacts := make([]Contact, len(b.peers))
@@ -171,8 +182,8 @@ func (b *bucket) Split() (*bucket, *bucket) {
 	b.lock.Lock()
 	defer b.lock.Unlock()
 
-	left := newBucket(b.Range.IntervalP(1, 2))
-	right := newBucket(b.Range.IntervalP(2, 2))
+	leftRange, rightRange := b.Range.Split()
+	left, right := newBucket(leftRange), newBucket(rightRange)
 	left.lastUpdate = b.lastUpdate
 	right.lastUpdate = b.lastUpdate
 
@@ -286,6 +297,13 @@ func (rt *routingTable) Fail(c Contact) {
 	rt.bucketFor(c.ID).FailContact(c.ID)
 }
 
+// Get returns the contact with the given id, if it's in the routing table
+func (rt *routingTable) Get(id bits.Bitmap) (Contact, bool) {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	return rt.bucketFor(id).Get(id)
+}
+
 // GetClosest returns the closest `limit` contacts from the routing table.
 // This is a locking wrapper around getClosest()
 func (rt *routingTable) GetClosest(target bits.Bitmap, limit int) []Contact {
@@ -406,25 +424,15 @@ func (rt *routingTable) UnmarshalJSON(b []byte) error {
 	if err != nil {
 		return errors.Prefix("decoding ID", err)
 	}
+	if rt.mu == nil {
+		rt.mu = &sync.RWMutex{}
+	}
 	rt.reset()
 
 	for _, s := range data.Contacts {
-		parts := strings.Split(s, rtContactSep)
-		if len(parts) != 3 {
-			return errors.Err("decoding contact %s: wrong number of parts", s)
-		}
-		var c Contact
-		c.ID, err = bits.FromHex(parts[0])
+		c, err := parseSavedContact(s)
 		if err != nil {
-			return errors.Err("decoding contact %s: invalid ID: %s", s, err)
-		}
-		c.IP = net.ParseIP(parts[1])
-		if c.IP == nil {
-			return errors.Err("decoding contact %s: invalid IP", s)
-		}
-		c.Port, err = strconv.Atoi(parts[2])
-		if err != nil {
-			return errors.Err("decoding contact %s: invalid port: %s", s, err)
+			return err
 		}
 		rt.Update(c)
 	}
@@ -432,6 +440,28 @@ func (rt *routingTable) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+func parseSavedContact(s string) (Contact, error) {
+	var c Contact
+	parts := strings.Split(s, rtContactSep)
+	if len(parts) != 3 {
+		return c, errors.Err("decoding contact %s: wrong number of parts", s)
+	}
+	var err error
+	c.ID, err = bits.FromHex(parts[0])
+	if err != nil {
+		return c, errors.Err("decoding contact %s: invalid ID: %s", s, err)
+	}
+	c.IP = net.ParseIP(parts[1])
+	if c.IP == nil {
+		return c, errors.Err("decoding contact %s: invalid IP", s)
+	}
+	c.Port, err = strconv.Atoi(parts[2])
+	if err != nil {
+		return c, errors.Err("decoding contact %s: invalid port: %s", s, err)
+	}
+	return c, nil
+}
+
 // RoutingTableRefresh refreshes any buckets that need to be refreshed
 func RoutingTableRefresh(n *Node, refreshInterval time.Duration, parentGrp *stop.Group) {
 	done := stop.New()
@@ -58,7 +58,10 @@ func (rpc *rpcReceiver) FindNode(r *http.Request, args *RpcFindArgs, result *[]C
 	c := Contact{ID: toQuery, IP: net.ParseIP(args.IP), Port: args.Port}
 	req := Request{Method: findNodeMethod, Arg: &key}
 
-	nodeResponse := rpc.dht.node.Send(c, req)
+	nodeResponse, err := rpc.dht.node.Send(c, req)
+	if err != nil {
+		return err
+	}
 	if nodeResponse != nil && nodeResponse.Contacts != nil {
 		*result = nodeResponse.Contacts
 	}
@@ -82,7 +85,10 @@ func (rpc *rpcReceiver) FindValue(r *http.Request, args *RpcFindArgs, result *Rp
 	c := Contact{ID: toQuery, IP: net.ParseIP(args.IP), Port: args.Port}
 	req := Request{Arg: &key, Method: findValueMethod}
 
-	nodeResponse := rpc.dht.node.Send(c, req)
+	nodeResponse, err := rpc.dht.node.Send(c, req)
+	if err != nil {
+		return err
+	}
 	if nodeResponse != nil && nodeResponse.FindValueKey != "" {
 		*result = RpcFindValueResult{Value: nodeResponse.FindValueKey}
 		return nil
@@ -2,6 +2,7 @@ package dht
 
 import (
 	"sync"
+	"time"
 
 	"github.com/lbryio/lbry.go/v2/dht/bits"
 )
@@ -9,8 +10,9 @@ import (
 // TODO: expire stored data after tExpire time
 
 type contactStore struct {
-	// map of blob hashes to (map of node IDs to bools)
-	hashes map[bits.Bitmap]map[bits.Bitmap]bool
+	// map of blob hashes to (map of node IDs to when the entry expires). peers that announced themselves to us never
+	// expire, and have a zero time
+	hashes map[bits.Bitmap]map[bits.Bitmap]time.Time
 	// stores the peers themselves, so they can be updated in one place
 	contacts map[bits.Bitmap]Contact
 	lock     sync.RWMutex
@@ -18,7 +20,7 @@ type contactStore struct {
 
 func newStore() *contactStore {
 	return &contactStore{
-		hashes:   make(map[bits.Bitmap]map[bits.Bitmap]bool),
+		hashes:   make(map[bits.Bitmap]map[bits.Bitmap]time.Time),
 		contacts: make(map[bits.Bitmap]Contact),
 	}
 }
@@ -28,19 +30,46 @@ func (s *contactStore) Upsert(blobHash bits.Bitmap, contact Contact) {
 	defer s.lock.Unlock()
 
 	if _, ok := s.hashes[blobHash]; !ok {
-		s.hashes[blobHash] = make(map[bits.Bitmap]bool)
+		s.hashes[blobHash] = make(map[bits.Bitmap]time.Time)
 	}
-	s.hashes[blobHash][contact.ID] = true
+	s.hashes[blobHash][contact.ID] = time.Time{}
 	s.contacts[contact.ID] = contact
 }
 
+// UpsertCached stores a peer that another node found in a lookup, rather than one that announced itself to us. The
+// entry is dropped once it expires, unless the peer announces itself in the meantime.
+func (s *contactStore) UpsertCached(blobHash bits.Bitmap, contact Contact, expires time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ids, ok := s.hashes[blobHash]
+	if !ok {
+		ids = make(map[bits.Bitmap]time.Time)
+		s.hashes[blobHash] = ids
+	}
+
+	current, exists := ids[contact.ID]
+	if exists && (current.IsZero() || current.After(expires)) {
+		return
+	}
+	ids[contact.ID] = expires
+	// don't clobber what the peer told us about itself
+	if _, ok := s.contacts[contact.ID]; !ok {
+		s.contacts[contact.ID] = contact
+	}
+}
+
 func (s *contactStore) Get(blobHash bits.Bitmap) []Contact {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 
 	var contacts []Contact
 	if ids, ok := s.hashes[blobHash]; ok {
-		for id := range ids {
+		now := time.Now()
+		for id, expires := range ids {
+			if !expires.IsZero() && now.After(expires) {
+				continue
+			}
 			contact, ok := s.contacts[id]
 			if !ok {
 				panic("node id in IDs list, but not in nodeInfo")
@@ -51,6 +80,36 @@ func (s *contactStore) Get(blobHash bits.Bitmap) []Contact {
 	return contacts
 }
 
+// RemoveExpired drops cached entries that have expired, and any hashes and peers that are left with no entries
+func (s *contactStore) RemoveExpired() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	for hash, ids := range s.hashes {
+		for id, expires := range ids {
+			if !expires.IsZero() && now.After(expires) {
+				delete(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			delete(s.hashes, hash)
+		}
+	}
+
+	used := make(map[bits.Bitmap]bool, len(s.contacts))
+	for _, ids := range s.hashes {
+		for id := range ids {
+			used[id] = true
+		}
+	}
+	for id := range s.contacts {
+		if !used[id] {
+			delete(s.contacts, id)
+		}
+	}
+}
+
 func (s *contactStore) RemoveTODO(contact Contact) {
 	// TODO: remove peer from everywhere
 }
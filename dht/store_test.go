@@ -0,0 +1,70 @@
+package dht
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/dht/bits"
+)
+
+func TestContactStore_Cached(t *testing.T) {
+	s := newStore()
+	hash := bits.Rand()
+	announced := Contact{ID: bits.Rand(), IP: net.IPv4(1, 2, 3, 4), PeerPort: 3333}
+	cached := Contact{ID: bits.Rand(), IP: net.IPv4(5, 6, 7, 8), PeerPort: 3333}
+	expired := Contact{ID: bits.Rand(), IP: net.IPv4(9, 10, 11, 12), PeerPort: 3333}
+
+	s.Upsert(hash, announced)
+	s.UpsertCached(hash, announced, time.Now().Add(-time.Minute)) // doesn't expire a peer that announced itself
+	s.UpsertCached(hash, cached, time.Now().Add(time.Minute))
+	s.UpsertCached(hash, expired, time.Now().Add(-time.Minute))
+
+	got := s.Get(hash)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 peers, got %d", len(got))
+	}
+	for _, c := range got {
+		if c.ID.Equals(expired.ID) {
+			t.Error("got an expired peer")
+		}
+	}
+
+	s.RemoveExpired()
+	if _, ok := s.contacts[expired.ID]; ok {
+		t.Error("expired peer was not removed")
+	}
+	if len(s.Get(hash)) != 2 {
+		t.Error("RemoveExpired removed unexpired peers")
+	}
+
+	other := bits.Rand()
+	s.UpsertCached(other, cached, time.Now().Add(-time.Minute))
+	s.RemoveExpired()
+	if s.CountStoredHashes() != 1 {
+		t.Errorf("expected 1 stored hash, got %d", s.CountStoredHashes())
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	target := bits.Rand()
+	holder := target.Set(bits.NumBits-1, !target.Get(bits.NumBits-1)) // shares all but the last bit
+
+	if ttl := cacheTTL(target, holder, holder); ttl != maxCacheTTL {
+		t.Errorf("expected %s when the cacher is as close as the holder, got %s", maxCacheTTL, ttl)
+	}
+
+	prev := maxCacheTTL
+	for prefix := bits.NumBits - 2; prefix >= bits.NumBits-8; prefix-- {
+		cacher := target.Set(prefix, !target.Get(prefix))
+		ttl := cacheTTL(target, holder, cacher)
+		if ttl != prev/2 {
+			t.Errorf("prefix %d: expected %s, got %s", prefix, prev/2, ttl)
+		}
+		prev = ttl
+	}
+
+	if ttl := cacheTTL(target, holder, target.Not()); ttl != 0 {
+		t.Errorf("expected no caching for a node on the other side of the network, got %s", ttl)
+	}
+}
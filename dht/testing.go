@@ -46,7 +46,10 @@ func TestingCreateNetwork(t *testing.T, numNodes int, bootstrap, concurrent bool
 		c.NodeID = bits.Rand().Hex()
 		c.Address = testingDHTIP + ":" + strconv.Itoa(firstPort+i)
 		c.SeedNodes = seeds
-		dht := New(c)
+		dht, err := New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
 
 		go func() {
 			err := dht.Start()
@@ -112,7 +115,7 @@ func newTestUDPConn(addr string) *testUDPConn {
 	}
 }
 
-func (t testUDPConn) ReadFromUDP(b []byte) (int, *net.UDPAddr, error) {
+func (t *testUDPConn) ReadFromUDP(b []byte) (int, *net.UDPAddr, error) {
 	var timeoutCh <-chan time.Time
 	if !t.readDeadline.IsZero() {
 		timeoutCh = time.After(time.Until(t.readDeadline))
@@ -130,7 +133,7 @@ func (t testUDPConn) ReadFromUDP(b []byte) (int, *net.UDPAddr, error) {
 	}
 }
 
-func (t testUDPConn) WriteToUDP(b []byte, addr *net.UDPAddr) (int, error) {
+func (t *testUDPConn) WriteToUDP(b []byte, addr *net.UDPAddr) (int, error) {
 	t.writes <- testUDPPacket{data: b, addr: addr}
 	return len(b), nil
 }
@@ -146,7 +149,6 @@ func (t *testUDPConn) SetWriteDeadline(tm time.Time) error {
 
 func (t *testUDPConn) Close() error {
 	close(t.toRead)
-	t.writes = nil
 	return nil
 }
 
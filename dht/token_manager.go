@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/lbryio/lbry.go/v2/dht/bits"
+	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"github.com/lbryio/lbry.go/v2/extras/stop"
 )
 
@@ -20,27 +21,33 @@ type tokenManager struct {
 	stop       *stop.Group
 }
 
-func (tm *tokenManager) Start(interval time.Duration) {
+func (tm *tokenManager) Start(interval time.Duration) error {
 	tm.secret = make([]byte, 64)
 	tm.prevSecret = make([]byte, 64)
 	tm.lock = &sync.RWMutex{}
 	tm.stop = stop.New()
 
-	tm.rotateSecret()
+	err := tm.rotateSecret()
+	if err != nil {
+		return err
+	}
 
-	tm.stop.Add(1)
-	go func() {
-		defer tm.stop.Done()
+	tm.stop.Go("token secret rotation", func() {
 		tick := time.NewTicker(interval)
 		for {
 			select {
 			case <-tick.C:
-				tm.rotateSecret()
+				err := tm.rotateSecret()
+				if err != nil {
+					log.Error(errors.Prefix("rotating token secret", err))
+				}
 			case <-tm.stop.Ch():
 				return
 			}
 		}
-	}()
+	})
+
+	return nil
 }
 
 func (tm *tokenManager) Stop() {
@@ -48,10 +55,14 @@ func (tm *tokenManager) Stop() {
 }
 
 func (tm *tokenManager) Get(nodeID bits.Bitmap, addr *net.UDPAddr) string {
+	tm.lock.RLock()
+	defer tm.lock.RUnlock()
 	return genToken(tm.secret, nodeID, addr)
 }
 
 func (tm *tokenManager) Verify(token string, nodeID bits.Bitmap, addr *net.UDPAddr) bool {
+	tm.lock.RLock()
+	defer tm.lock.RUnlock()
 	return token == genToken(tm.secret, nodeID, addr) || token == genToken(tm.prevSecret, nodeID, addr)
 }
 
@@ -65,14 +76,12 @@ func genToken(secret []byte, nodeID bits.Bitmap, addr *net.UDPAddr) string {
 	return string(t[:])
 }
 
-func (tm *tokenManager) rotateSecret() {
+func (tm *tokenManager) rotateSecret() error {
 	tm.lock.Lock()
 	defer tm.lock.Unlock()
 
 	copy(tm.prevSecret, tm.secret)
 
 	_, err := rand.Read(tm.secret)
-	if err != nil {
-		panic(err)
-	}
+	return errors.Err(err)
 }
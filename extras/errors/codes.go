@@ -0,0 +1,137 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net"
+)
+
+// Category is the broad kind of problem an error is, for deciding what to do about it
+type Category int
+
+const (
+	CategoryUnknown Category = iota
+	// connecting to or talking to another service failed
+	CategoryNetwork
+	// the chain or wallet refused something, e.g. a transaction
+	CategoryBlockchain
+	// the input is wrong, and will be wrong next time too
+	CategoryValidation
+	// a limit was hit, e.g. a rate limit or a daily quota
+	CategoryQuota
+)
+
+func (c Category) String() string {
+	switch c {
+	case CategoryNetwork:
+		return "network"
+	case CategoryBlockchain:
+		return "blockchain"
+	case CategoryValidation:
+		return "validation"
+	case CategoryQuota:
+		return "quota"
+	}
+	return "unknown"
+}
+
+// Code identifies a kind of error across packages, so callers can retry, skip, or give up without matching error
+// messages. Codes are compared by identity, so declare them once with NewCode. A Code is also an error, so
+// errors.Is(err, CodeTimeout) works, with this package or the standard library.
+type Code struct {
+	Name      string
+	Category  Category
+	Retryable bool
+}
+
+// NewCode declares a code
+func NewCode(name string, category Category, retryable bool) *Code {
+	return &Code{Name: name, Category: category, Retryable: retryable}
+}
+
+func (c *Code) Error() string { return c.Name }
+
+var (
+	CodeTimeout           = NewCode("timeout", CategoryNetwork, true)
+	CodeUnavailable       = NewCode("unavailable", CategoryNetwork, true)
+	CodeInsufficientFunds = NewCode("insufficient_funds", CategoryBlockchain, false)
+	CodeTxRejected        = NewCode("tx_rejected", CategoryBlockchain, false)
+	CodeInvalid           = NewCode("invalid", CategoryValidation, false)
+	CodeRateLimited       = NewCode("rate_limited", CategoryQuota, true)
+	CodeQuotaExceeded     = NewCode("quota_exceeded", CategoryQuota, false)
+)
+
+// Coder is implemented by errors that know their code, like the ones made by WithCode. Packages can implement it on
+// their own error types to map them to codes.
+type Coder interface {
+	ErrorCode() *Code
+}
+
+type codedError struct {
+	code *Code
+	err  error
+}
+
+func (e *codedError) Error() string        { return e.err.Error() }
+func (e *codedError) Unwrap() error        { return e.err }
+func (e *codedError) ErrorCode() *Code     { return e.code }
+func (e *codedError) Is(target error) bool { return target == error(e.code) }
+
+// WithCode is Err, but the error also carries code
+func WithCode(code *Code, err interface{}, fmtParams ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+
+	var inner error
+	switch e := err.(type) {
+	case causer:
+		inner = fmt.Errorf("%+v", e)
+	case error:
+		inner = e
+	case string:
+		if len(fmtParams) > 0 {
+			inner = fmt.Errorf(e, fmtParams...)
+		} else {
+			inner = stderrors.New(e)
+		}
+	default:
+		inner = fmt.Errorf("%v", e)
+	}
+
+	return Wrap(&codedError{code: code, err: inner}, 1)
+}
+
+// CodeOf returns the code of the first error in err's chain that has one, or nil
+func CodeOf(err error) *Code {
+	var c Coder
+	if As(err, &c) {
+		return c.ErrorCode()
+	}
+	return nil
+}
+
+// CategoryOf returns the category of err's code
+func CategoryOf(err error) Category {
+	if code := CodeOf(err); code != nil {
+		return code.Category
+	}
+	var netErr net.Error
+	if As(err, &netErr) {
+		return CategoryNetwork
+	}
+	return CategoryUnknown
+}
+
+// IsRetryable returns whether the same call might work if it's made again. Errors without a code are only retryable
+// if they're network timeouts.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if code := CodeOf(err); code != nil {
+		return code.Retryable
+	}
+	var netErr net.Error
+	return As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,62 @@
+package errors
+
+import (
+	stderrors "errors"
+	"net"
+	"testing"
+)
+
+func TestWithCode(t *testing.T) {
+	base := Base("connection reset")
+	err := Prefix("fetching page", WithCode(CodeUnavailable, base))
+
+	if CodeOf(err) != CodeUnavailable {
+		t.Errorf("expected code %s, got %v", CodeUnavailable.Name, CodeOf(err))
+	}
+	if CategoryOf(err) != CategoryNetwork {
+		t.Errorf("expected network category, got %s", CategoryOf(err))
+	}
+	if !IsRetryable(err) {
+		t.Error("expected error to be retryable")
+	}
+	if !Is(err, CodeUnavailable) || !stderrors.Is(err, CodeUnavailable) {
+		t.Error("expected error to be CodeUnavailable")
+	}
+	if Is(err, CodeTimeout) {
+		t.Error("expected error not to be CodeTimeout")
+	}
+	if !Is(err, base) || Unwrap(err) != base {
+		t.Error("expected the original error to still be in the chain")
+	}
+	if err.Error() != "fetching page: connection reset" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+
+	err = WithCode(CodeInvalid, "bad value %d", 5)
+	if err.Error() != "bad value 5" || IsRetryable(err) || CategoryOf(err) != CategoryValidation {
+		t.Errorf("unexpected error %v", err)
+	}
+	if WithCode(CodeInvalid, nil) != nil {
+		t.Error("expected nil for a nil error")
+	}
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "i/o timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+var _ net.Error = timeoutErr{}
+
+func TestIsRetryable_Uncoded(t *testing.T) {
+	if IsRetryable(nil) || IsRetryable(Err("nope")) {
+		t.Error("expected plain errors not to be retryable")
+	}
+	if !IsRetryable(Err(timeoutErr{})) {
+		t.Error("expected network timeouts to be retryable")
+	}
+	if CategoryOf(Err(timeoutErr{})) != CategoryNetwork {
+		t.Error("expected network errors to be in the network category")
+	}
+}
@@ -53,6 +53,10 @@ func Unwrap(err error) error {
 			err = e.Err
 			deeper = true
 		}
+		if e, ok := err.(*codedError); ok {
+			err = e.err
+			deeper = true
+		}
 		if c, ok := err.(causer); ok {
 			err = c.Cause()
 			deeper = true
@@ -74,6 +78,14 @@ func Is(e error, original error) bool {
 	return errors.Is(e, original)
 }
 
+// As finds the first error in err's chain that can be assigned to target, and sets target to it
+func As(err error, target interface{}) bool {
+	if c, ok := err.(causer); ok {
+		err = c.Cause()
+	}
+	return errors.As(err, target)
+}
+
 // Prefix prefixes the message of the error with the given string
 func Prefix(prefix string, err interface{}) error {
 	if err == nil {
@@ -1,6 +1,8 @@
 package jsonrpc
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -20,16 +22,35 @@ import (
 
 const DefaultPort = 5279
 
+// names of errors returned by the daemon, in Error.Name
 const (
 	ErrorWalletNotLoaded     = "WalletNotLoadedError"
 	ErrorWalletAlreadyLoaded = "WalletAlreadyLoadedError"
 	ErrorWalletNotFound      = "WalletNotFoundError"
 	ErrorWalletAlreadyExists = "WalletAlreadyExistsError"
+	ErrorInsufficientFunds   = "InsufficientFundsError"
+	ErrorResolve             = "ResolveError"
+	ErrorResolveTimeout      = "ResolveTimeoutError"
+	ErrorDownloadSDTimeout   = "DownloadSDTimeoutError"
+	ErrorKeyFeeAboveMax      = "KeyFeeAboveMaxAllowedError"
+)
+
+// json-rpc error codes, in Error.Code
+const (
+	ErrorCodeParse          = -32700
+	ErrorCodeInvalidRequest = -32600
+	ErrorCodeMethodNotFound = -32601
+	ErrorCodeInvalidParams  = -32602
+	ErrorCodeInternal       = -32603
+	// the daemon uses this for errors raised by the command itself
+	ErrorCodeApplication = -32500
 )
 
 type Client struct {
-	conn    jsonrpc.RPCClient
-	address string
+	conn       jsonrpc.RPCClient
+	address    string
+	httpClient *http.Client
+	ctx        context.Context
 }
 
 type Error struct {
@@ -47,6 +68,7 @@ func NewClient(address string) *Client {
 
 	d.conn = jsonrpc.NewClient(address)
 	d.address = address
+	d.httpClient = &http.Client{}
 
 	return &d
 }
@@ -132,9 +154,62 @@ func (e Error) Error() string {
 	return fmt.Sprintf("Error in daemon: %s", e.Message)
 }
 
+// ErrorCode maps the daemon's error to a code, so errors.IsRetryable and friends work on it
+func (e Error) ErrorCode() *errors.Code {
+	switch {
+	case e.Name == ErrorResolveTimeout || e.Name == ErrorDownloadSDTimeout:
+		return errors.CodeTimeout
+	// the daemon doesn't name these. hub timeouts only say so in the message
+	case e.Code == ErrorCodeApplication && strings.Contains(strings.ToLower(e.Message), "timeout"):
+		return errors.CodeTimeout
+	// a claim that was just made isn't on the hub yet
+	case e.Code == ErrorCodeApplication && strings.Contains(e.Message, "Couldn't find claim"):
+		return errors.CodeUnavailable
+	case e.Name == ErrorInsufficientFunds:
+		return errors.CodeInsufficientFunds
+	case e.Code == ErrorCodeParse || e.Code == ErrorCodeInvalidRequest || e.Code == ErrorCodeInvalidParams:
+		return errors.CodeInvalid
+	// lbrycrd only sets the code
+	case e.Code == LbrycrdErrorCodeInWarmup:
+		return errors.CodeUnavailable
+	case e.Code == LbrycrdErrorCodeInsufficientFunds:
+		return errors.CodeInsufficientFunds
+	case e.Code == LbrycrdErrorCodeVerify || e.Code == LbrycrdErrorCodeVerifyRejected:
+		return errors.CodeTxRejected
+	case e.Code == LbrycrdErrorCodeType || e.Code == LbrycrdErrorCodeInvalidParameter || e.Code == LbrycrdErrorCodeDeserialization:
+		return errors.CodeInvalid
+	}
+	return nil
+}
+
+// Is makes errors.Is(err, errors.CodeTimeout) and the like work on daemon errors
+func (e Error) Is(target error) bool {
+	code := e.ErrorCode()
+	return code != nil && target == error(code)
+}
+
+// IsError returns whether err is an error from the daemon with the given name, like ErrorWalletNotFound
+func IsError(err error, name string) bool {
+	var e Error
+	return errors.As(err, &e) && e.Name == name
+}
+
+// WithContext returns a copy of the client whose calls are cancelled when ctx is done
+func (d *Client) WithContext(ctx context.Context) *Client {
+	c := *d
+	c.ctx = ctx
+	return &c
+}
+
 func (d *Client) callNoDecode(command string, params map[string]interface{}) (interface{}, error) {
 	log.Debugln("jsonrpc: " + command + " " + debugParams(params))
-	r, err := d.conn.Call(command, params)
+	var r *jsonrpc.RPCResponse
+	var err error
+	if d.ctx != nil {
+		r, err = d.callContext(command, params)
+	} else {
+		r, err = d.conn.Call(command, params)
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, 0)
 	}
@@ -154,9 +229,42 @@ func (d *Client) call(response interface{}, command string, params map[string]in
 	return Decode(result, response)
 }
 
+// callContext makes the call with d.ctx, which the jsonrpc client doesn't support
+func (d *Client) callContext(command string, params map[string]interface{}) (*jsonrpc.RPCResponse, error) {
+	body, err := json.Marshal(jsonrpc.NewRequest(command, params))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, d.address, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		if d.ctx.Err() != nil {
+			return nil, d.ctx.Err()
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var r *jsonrpc.RPCResponse
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	err = decoder.Decode(&r)
+	if err != nil || r == nil {
+		return nil, errors.Err("rpc call %s() status code: %d. could not decode body to rpc response: %v", command, resp.StatusCode, err)
+	}
+	return r, nil
+}
+
 func (d *Client) SetRPCTimeout(timeout time.Duration) {
+	d.httpClient = &http.Client{Timeout: timeout}
 	d.conn = jsonrpc.NewClientWithOpts(d.address, &jsonrpc.RPCClientOpts{
-		HTTPClient: &http.Client{Timeout: timeout},
+		HTTPClient: d.httpClient,
 	})
 }
 
@@ -409,6 +517,28 @@ func (d *Client) StreamCreate(name, filePath string, bid float64, options Stream
 	return response, d.call(response, "stream_create", structs.Map(args))
 }
 
+// Publish creates a stream claim for name, or updates it if the account already has a claim for name
+func (d *Client) Publish(name, filePath string, bid float64, options StreamCreateOptions) (*TransactionSummary, error) {
+	response := new(TransactionSummary)
+	args := struct {
+		Name                 string `json:"name"`
+		Bid                  string `json:"bid"`
+		FilePath             string `json:"file_path,omitempty"`
+		IncludeProtoBuf      bool   `json:"include_protobuf"`
+		Blocking             bool   `json:"blocking"`
+		*StreamCreateOptions `json:",flatten"`
+	}{
+		Name:                name,
+		FilePath:            filePath,
+		Bid:                 fmt.Sprintf("%.6f", bid),
+		IncludeProtoBuf:     true,
+		Blocking:            true,
+		StreamCreateOptions: &options,
+	}
+	structs.DefaultTagName = "json"
+	return response, d.call(response, "publish", structs.Map(args))
+}
+
 func (d *Client) StreamAbandon(txID string, nOut uint64, accountID *string, blocking bool) (*ClaimAbandonResponse, error) {
 	response := new(ClaimAbandonResponse)
 	err := d.call(response, "stream_abandon", map[string]interface{}{
@@ -755,6 +885,34 @@ func (d *Client) WalletCreate(id string, opts *WalletCreateOpts) (*Wallet, error
 	return response, d.call(response, "wallet_create", structs.Map(opts))
 }
 
+type WalletSendOpts struct {
+	WalletID          *string  `json:"wallet_id,omitempty"`
+	ChangeAccountID   *string  `json:"change_account_id,omitempty"`
+	FundingAccountIDs []string `json:"funding_account_ids,omitempty"`
+	Preview           bool     `json:"preview"`
+}
+
+// WalletSend sends amount to each of the addresses
+func (d *Client) WalletSend(amount string, addresses []string, opts *WalletSendOpts) (*TransactionSummary, error) {
+	response := new(TransactionSummary)
+	if opts == nil {
+		opts = &WalletSendOpts{}
+	}
+	args := struct {
+		Amount          string   `json:"amount"`
+		Addresses       []string `json:"addresses"`
+		Blocking        bool     `json:"blocking"`
+		*WalletSendOpts `json:",flatten"`
+	}{
+		Amount:         amount,
+		Addresses:      addresses,
+		Blocking:       true,
+		WalletSendOpts: opts,
+	}
+	structs.DefaultTagName = "json"
+	return response, d.call(response, "wallet_send", structs.Map(args))
+}
+
 func (d *Client) WalletAdd(id string) (*Wallet, error) {
 	response := new(Wallet)
 	return response, d.call(response, "wallet_add", map[string]interface{}{"wallet_id": id})
@@ -1,9 +1,12 @@
 package jsonrpc
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strconv"
 	"strings"
@@ -12,6 +15,7 @@ import (
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/ybbus/jsonrpc/v2"
 
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 
@@ -843,3 +847,105 @@ func TestClient_TransactionSummary(t *testing.T) {
 		t.Error("found wrong lbc amount for transaction.")
 	}
 }
+
+// newTestDaemon returns a client for a fake daemon that answers every call with handler's result or error
+func newTestDaemon(t *testing.T, handler func(method string, params map[string]interface{}) (interface{}, *jsonrpc.RPCError)) *Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string                 `json:"method"`
+			Params map[string]interface{} `json:"params"`
+			ID     int                    `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, rpcErr := handler(req.Method, req.Params)
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result}
+		if rpcErr != nil {
+			resp = map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "error": rpcErr}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return NewClient(server.URL)
+}
+
+func TestClient_WithContext(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	d := newTestDaemon(t, func(method string, params map[string]interface{}) (interface{}, *jsonrpc.RPCError) {
+		if method == "status" {
+			<-block
+		}
+		return map[string]interface{}{"version": "0.113.0"}, nil
+	})
+
+	v, err := d.WithContext(context.Background()).Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Version != "0.113.0" {
+		t.Errorf("expected version 0.113.0, got %s", v.Version)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = d.WithContext(ctx).Status()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to be exceeded, got %v", err)
+	}
+}
+
+func TestClient_ErrorName(t *testing.T) {
+	d := newTestDaemon(t, func(method string, params map[string]interface{}) (interface{}, *jsonrpc.RPCError) {
+		return nil, &jsonrpc.RPCError{
+			Code:    ErrorCodeApplication,
+			Message: "Not enough funds to cover this transaction.",
+			Data:    map[string]interface{}{"name": ErrorInsufficientFunds},
+		}
+	})
+
+	for _, d := range []*Client{d, d.WithContext(context.Background())} {
+		_, err := d.WalletSend("1.0", []string{"bHW58d37s1hBjj3wPBkn5zpCX3F8ZW3F1U"}, nil)
+		if !IsError(err, ErrorInsufficientFunds) {
+			t.Errorf("expected %s, got %v", ErrorInsufficientFunds, err)
+		}
+		if IsError(err, ErrorWalletNotFound) {
+			t.Errorf("did not expect %s", ErrorWalletNotFound)
+		}
+		var e Error
+		if !errors.As(err, &e) || e.Code != ErrorCodeApplication {
+			t.Errorf("expected error code %d, got %+v", ErrorCodeApplication, e)
+		}
+		if !errors.Is(err, errors.CodeInsufficientFunds) || errors.IsRetryable(err) {
+			t.Errorf("expected a non-retryable %s error, got %v", errors.CodeInsufficientFunds.Name, errors.CodeOf(err))
+		}
+	}
+}
+
+func TestClient_PublishParams(t *testing.T) {
+	var got map[string]interface{}
+	d := newTestDaemon(t, func(method string, params map[string]interface{}) (interface{}, *jsonrpc.RPCError) {
+		if method != "publish" {
+			t.Errorf("expected publish, got %s", method)
+		}
+		got = params
+		return map[string]interface{}{"txid": "abc"}, nil
+	})
+
+	channel := "@channel"
+	tx, err := d.Publish("name", "/tmp/file.mp4", 0.01, StreamCreateOptions{
+		ClaimCreateOptions: ClaimCreateOptions{Title: util.PtrToString("title")},
+		ChannelName:        &channel,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Txid != "abc" {
+		t.Errorf("expected txid abc, got %s", tx.Txid)
+	}
+	if got["name"] != "name" || got["bid"] != "0.010000" || got["file_path"] != "/tmp/file.mp4" || got["channel_name"] != channel || got["title"] != "title" {
+		t.Errorf("unexpected params %v", got)
+	}
+}
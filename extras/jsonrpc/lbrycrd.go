@@ -0,0 +1,586 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/atomic"
+)
+
+const LbrycrdDefaultPort = 9245
+
+const (
+	// lbrycrd handles a batch on one rpc thread, so huge batches block other callers. bigger batches are split up
+	defaultLbrycrdBatchSize = 100
+	defaultLbrycrdMaxConns  = 8
+	defaultLbrycrdTimeout   = 2 * time.Minute
+	lbrycrdCookieUser       = "__cookie__"
+)
+
+// lbrycrd error codes, in Error.Code. these are in addition to the json-rpc ones
+const (
+	LbrycrdErrorCodeMisc              = -1
+	LbrycrdErrorCodeType              = -3
+	LbrycrdErrorCodeNotFound          = -5 // also used for invalid addresses and keys
+	LbrycrdErrorCodeInsufficientFunds = -6
+	LbrycrdErrorCodeInvalidParameter  = -8
+	LbrycrdErrorCodeDeserialization   = -22
+	LbrycrdErrorCodeVerify            = -25
+	LbrycrdErrorCodeVerifyRejected    = -26
+	LbrycrdErrorCodeInWarmup          = -28
+)
+
+// getblock verbosity levels
+const (
+	BlockVerbosityHex   = 0
+	BlockVerbosityTxIDs = 1
+	BlockVerbosityTxs   = 2
+)
+
+// LbrycrdOptions configures a LbrycrdClient. the zero value connects to a local lbrycrd using its cookie file
+type LbrycrdOptions struct {
+	User     string
+	Password string
+	// CookieFile is used if User is empty. it defaults to ~/.lbrycrd/.cookie. lbrycrd writes a new cookie every time
+	// it starts, so the file is read again when the credentials are rejected
+	CookieFile string
+	// MaxConns is how many connections to keep open to lbrycrd. batches that are split up are sent over this many
+	// connections at once
+	MaxConns int
+	// BatchSize is the most calls to send in one http request
+	BatchSize int
+	Timeout   time.Duration
+}
+
+// LbrycrdClient calls lbrycrd's json-rpc directly. unlike lbrycrd.Client, it can send many calls in one request,
+// which makes scanning the chain a lot faster
+type LbrycrdClient struct {
+	address    string
+	httpClient *http.Client
+	ctx        context.Context
+
+	authLock   *sync.RWMutex
+	user       string
+	password   string
+	cookieFile string
+
+	maxConns  int
+	batchSize int
+	nextID    *atomic.Uint64
+}
+
+func NewLbrycrdClient(address string, opts *LbrycrdOptions) (*LbrycrdClient, error) {
+	if opts == nil {
+		opts = &LbrycrdOptions{}
+	}
+	if address == "" {
+		address = "http://localhost:" + strconv.Itoa(LbrycrdDefaultPort)
+	} else if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+
+	c := &LbrycrdClient{
+		address:   address,
+		authLock:  &sync.RWMutex{},
+		user:      opts.User,
+		password:  opts.Password,
+		maxConns:  opts.MaxConns,
+		batchSize: opts.BatchSize,
+		nextID:    atomic.NewUint64(0),
+	}
+	if c.maxConns <= 0 {
+		c.maxConns = defaultLbrycrdMaxConns
+	}
+	if c.batchSize <= 0 {
+		c.batchSize = defaultLbrycrdBatchSize
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultLbrycrdTimeout
+	}
+
+	c.httpClient = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			MaxIdleConns:        c.maxConns,
+			MaxIdleConnsPerHost: c.maxConns,
+			MaxConnsPerHost:     c.maxConns,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+
+	if c.user == "" {
+		c.cookieFile = opts.CookieFile
+		if c.cookieFile == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, errors.Err(err)
+			}
+			c.cookieFile = filepath.Join(home, ".lbrycrd", ".cookie")
+		}
+		if err := c.readCookie(); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// WithContext returns a copy of the client whose calls are cancelled when ctx is done
+func (c *LbrycrdClient) WithContext(ctx context.Context) *LbrycrdClient {
+	cp := *c
+	cp.ctx = ctx
+	return &cp
+}
+
+// readCookie loads the credentials lbrycrd wrote to its cookie file, which look like "__cookie__:<password>"
+func (c *LbrycrdClient) readCookie() error {
+	contents, err := os.ReadFile(c.cookieFile)
+	if err != nil {
+		return errors.Prefix("reading lbrycrd cookie", err)
+	}
+	parts := strings.SplitN(strings.TrimSpace(string(contents)), ":", 2)
+	if len(parts) != 2 || parts[0] != lbrycrdCookieUser {
+		return errors.Err("malformed lbrycrd cookie file %s", c.cookieFile)
+	}
+
+	c.authLock.Lock()
+	defer c.authLock.Unlock()
+	c.user, c.password = parts[0], parts[1]
+	return nil
+}
+
+func (c *LbrycrdClient) credentials() (string, string) {
+	c.authLock.RLock()
+	defer c.authLock.RUnlock()
+	return c.user, c.password
+}
+
+type lbrycrdRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type lbrycrdResponse struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (r *lbrycrdResponse) decode(result interface{}) error {
+	if r.Error != nil {
+		return Error{Code: r.Error.Code, Message: r.Error.Message}
+	}
+	if result == nil {
+		return nil
+	}
+	err := json.Unmarshal(r.Result, result)
+	if err != nil {
+		return errors.Prefix("decoding lbrycrd response", err)
+	}
+	return nil
+}
+
+func (c *LbrycrdClient) newRequest(method string, params []interface{}) lbrycrdRequest {
+	if params == nil {
+		params = []interface{}{}
+	}
+	return lbrycrdRequest{JSONRPC: "1.0", ID: c.nextID.Inc(), Method: method, Params: params}
+}
+
+// Call calls method and decodes its result into result, which may be nil if the result isn't needed
+func (c *LbrycrdClient) Call(result interface{}, method string, params ...interface{}) error {
+	log.Debugf("lbrycrd: %s %v", method, params)
+	req := c.newRequest(method, params)
+	var resp lbrycrdResponse
+	err := c.post(req, &resp)
+	if err != nil {
+		return errors.Prefix(method, err)
+	}
+	return resp.decode(result)
+}
+
+// post sends body and decodes the response into response. lbrycrd answers errors with non-200 statuses but still
+// sends a json-rpc response, so the status only matters when the body isn't one
+func (c *LbrycrdClient) post(body interface{}, response interface{}) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return errors.Err(err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		ctx := c.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.address, bytes.NewReader(encoded))
+		if err != nil {
+			return errors.Err(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		user, password := c.credentials()
+		req.SetBasicAuth(user, password)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return errors.Err(err)
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized {
+			resp.Body.Close()
+			// lbrycrd may have restarted and written a new cookie
+			if c.cookieFile != "" && attempt == 0 {
+				if err := c.readCookie(); err != nil {
+					return err
+				}
+				continue
+			}
+			return errors.Err("lbrycrd rejected the rpc credentials")
+		}
+
+		decoder := json.NewDecoder(resp.Body)
+		err = decoder.Decode(response)
+		resp.Body.Close()
+		if err != nil {
+			return errors.Err("status code: %d. could not decode body to rpc response: %v", resp.StatusCode, err)
+		}
+		return nil
+	}
+}
+
+// LbrycrdBatch collects calls to send to lbrycrd together
+type LbrycrdBatch struct {
+	c     *LbrycrdClient
+	calls []batchCall
+}
+
+type batchCall struct {
+	req    lbrycrdRequest
+	result interface{}
+}
+
+// NewBatch starts an empty batch
+func (c *LbrycrdClient) NewBatch() *LbrycrdBatch {
+	return &LbrycrdBatch{c: c}
+}
+
+// Queue adds a call to the batch. result is filled in by Send, and may be nil if the result isn't needed
+func (b *LbrycrdBatch) Queue(result interface{}, method string, params ...interface{}) {
+	b.calls = append(b.calls, batchCall{req: b.c.newRequest(method, params), result: result})
+}
+
+// Len returns how many calls are queued
+func (b *LbrycrdBatch) Len() int {
+	return len(b.calls)
+}
+
+// Send sends the queued calls and empties the batch. The returned error is set if any request failed as a whole.
+// Otherwise, errs has the error of each call, in the order they were queued, and is nil if all of them worked.
+func (b *LbrycrdBatch) Send() (errs []error, err error) {
+	calls := b.calls
+	b.calls = nil
+	if len(calls) == 0 {
+		return nil, nil
+	}
+
+	errs = make([]error, len(calls))
+	failed := false
+
+	var chunkErr error
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, b.c.maxConns)
+	for start := 0; start < len(calls); start += b.c.batchSize {
+		end := start + b.c.batchSize
+		if end > len(calls) {
+			end = len(calls)
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			callErrs, err := b.c.sendChunk(calls[start:end])
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				if chunkErr == nil {
+					chunkErr = err
+				}
+				return
+			}
+			for i, e := range callErrs {
+				if e != nil {
+					errs[start+i] = e
+					failed = true
+				}
+			}
+		}(start, end)
+	}
+	wg.Wait()
+
+	if chunkErr != nil {
+		return nil, chunkErr
+	}
+	if !failed {
+		return nil, nil
+	}
+	return errs, nil
+}
+
+// sendChunk sends calls as one batch request. lbrycrd doesn't have to answer in order, so responses are matched up
+// by id
+func (c *LbrycrdClient) sendChunk(calls []batchCall) ([]error, error) {
+	reqs := make([]lbrycrdRequest, len(calls))
+	byID := make(map[uint64]int, len(calls))
+	for i, call := range calls {
+		reqs[i] = call.req
+		byID[call.req.ID] = i
+	}
+
+	var resps []lbrycrdResponse
+	err := c.post(reqs, &resps)
+	if err != nil {
+		return nil, errors.Prefix("batch", err)
+	}
+
+	errs := make([]error, len(calls))
+	answered := make([]bool, len(calls))
+	for i := range resps {
+		idx, ok := byID[resps[i].ID]
+		if !ok || answered[idx] {
+			return nil, errors.Err("batch: unexpected response id %d", resps[i].ID)
+		}
+		answered[idx] = true
+		if err := resps[i].decode(calls[idx].result); err != nil {
+			errs[idx] = errors.Prefix(calls[idx].req.Method, err)
+		}
+	}
+	for i, ok := range answered {
+		if !ok {
+			errs[i] = errors.Err("%s: no response in batch", calls[i].req.Method)
+		}
+	}
+	return errs, nil
+}
+
+// firstError returns the first call error from a batch, so helpers that need every call to work can fail as a whole
+func firstError(errs []error, err error) error {
+	if err != nil {
+		return err
+	}
+	for _, e := range errs {
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// LbrycrdBlockHeader is the part of getblock's result that's the same for every verbosity
+type LbrycrdBlockHeader struct {
+	Hash              string  `json:"hash"`
+	Confirmations     int64   `json:"confirmations"`
+	Size              int64   `json:"size"`
+	StrippedSize      int64   `json:"strippedsize"`
+	Weight            int64   `json:"weight"`
+	Height            int64   `json:"height"`
+	Version           int32   `json:"version"`
+	VersionHex        string  `json:"versionHex"`
+	MerkleRoot        string  `json:"merkleroot"`
+	ClaimTrieRoot     string  `json:"nameclaimroot"`
+	Time              int64   `json:"time"`
+	MedianTime        int64   `json:"mediantime"`
+	Nonce             uint32  `json:"nonce"`
+	Bits              string  `json:"bits"`
+	Difficulty        float64 `json:"difficulty"`
+	ChainWork         string  `json:"chainwork"`
+	NTx               int     `json:"nTx"`
+	PreviousBlockHash string  `json:"previousblockhash"`
+	NextBlockHash     string  `json:"nextblockhash"`
+}
+
+// LbrycrdBlock is getblock's result at BlockVerbosityTxIDs
+type LbrycrdBlock struct {
+	LbrycrdBlockHeader
+	Tx []string `json:"tx"`
+}
+
+// LbrycrdBlockTxs is getblock's result at BlockVerbosityTxs
+type LbrycrdBlockTxs struct {
+	LbrycrdBlockHeader
+	Tx []LbrycrdTx `json:"tx"`
+}
+
+type LbrycrdTx struct {
+	TxID     string        `json:"txid"`
+	Hash     string        `json:"hash"`
+	Version  int32         `json:"version"`
+	Size     int64         `json:"size"`
+	VSize    int64         `json:"vsize"`
+	Weight   int64         `json:"weight"`
+	LockTime uint32        `json:"locktime"`
+	Vin      []LbrycrdVin  `json:"vin"`
+	Vout     []LbrycrdVout `json:"vout"`
+	Hex      string        `json:"hex"`
+	// only set by getrawtransaction
+	BlockHash     string `json:"blockhash"`
+	Confirmations int64  `json:"confirmations"`
+	Time          int64  `json:"time"`
+	BlockTime     int64  `json:"blocktime"`
+}
+
+type LbrycrdVin struct {
+	// only set for the coinbase input, which has no TxID
+	Coinbase    string         `json:"coinbase"`
+	TxID        string         `json:"txid"`
+	Vout        uint32         `json:"vout"`
+	ScriptSig   *LbrycrdScript `json:"scriptSig"`
+	TxInWitness []string       `json:"txinwitness"`
+	Sequence    uint32         `json:"sequence"`
+}
+
+type LbrycrdVout struct {
+	Value        decimal.Decimal     `json:"value"`
+	N            uint32              `json:"n"`
+	ScriptPubKey LbrycrdScriptPubKey `json:"scriptPubKey"`
+}
+
+type LbrycrdScript struct {
+	Asm string `json:"asm"`
+	Hex string `json:"hex"`
+}
+
+type LbrycrdScriptPubKey struct {
+	LbrycrdScript
+	ReqSigs   int      `json:"reqSigs"`
+	Type      string   `json:"type"`
+	Addresses []string `json:"addresses"`
+}
+
+func (c *LbrycrdClient) GetBlockCount() (int64, error) {
+	var count int64
+	err := c.Call(&count, "getblockcount")
+	return count, err
+}
+
+func (c *LbrycrdClient) GetBlockHash(height int64) (string, error) {
+	var hash string
+	err := c.Call(&hash, "getblockhash", height)
+	return hash, err
+}
+
+// GetBlockHashes gets the hashes of many blocks in batches
+func (c *LbrycrdClient) GetBlockHashes(heights []int64) ([]string, error) {
+	hashes := make([]string, len(heights))
+	b := c.NewBatch()
+	for i, h := range heights {
+		b.Queue(&hashes[i], "getblockhash", h)
+	}
+	return hashes, firstError(b.Send())
+}
+
+// GetBlockRaw returns the serialized block. lbrycrd headers have the claimtrie root in them, so this can't be
+// deserialized into a wire.MsgBlock
+func (c *LbrycrdClient) GetBlockRaw(hash string) ([]byte, error) {
+	var raw string
+	err := c.Call(&raw, "getblock", hash, BlockVerbosityHex)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := hex.DecodeString(raw)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return decoded, nil
+}
+
+// GetBlock returns the block with the ids of its transactions
+func (c *LbrycrdClient) GetBlock(hash string) (*LbrycrdBlock, error) {
+	var block LbrycrdBlock
+	err := c.Call(&block, "getblock", hash, BlockVerbosityTxIDs)
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// GetBlockTxs returns the block with its transactions decoded
+func (c *LbrycrdClient) GetBlockTxs(hash string) (*LbrycrdBlockTxs, error) {
+	var block LbrycrdBlockTxs
+	err := c.Call(&block, "getblock", hash, BlockVerbosityTxs)
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// GetBlocksTxs gets many blocks with their transactions decoded, in batches
+func (c *LbrycrdClient) GetBlocksTxs(hashes []string) ([]*LbrycrdBlockTxs, error) {
+	blocks := make([]*LbrycrdBlockTxs, len(hashes))
+	b := c.NewBatch()
+	for i, h := range hashes {
+		blocks[i] = &LbrycrdBlockTxs{}
+		b.Queue(blocks[i], "getblock", h, BlockVerbosityTxs)
+	}
+	err := firstError(b.Send())
+	if err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// GetRawTransaction returns the decoded transaction. lbrycrd only has transactions that aren't in the mempool or the
+// wallet if it was started with -txindex
+func (c *LbrycrdClient) GetRawTransaction(txid string) (*LbrycrdTx, error) {
+	var tx LbrycrdTx
+	err := c.Call(&tx, "getrawtransaction", txid, true)
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// GetRawTransactions gets many transactions in batches. Transactions that couldn't be found are nil, with their
+// error at the same index of errs.
+func (c *LbrycrdClient) GetRawTransactions(txids []string) (txs []*LbrycrdTx, errs []error, err error) {
+	txs = make([]*LbrycrdTx, len(txids))
+	b := c.NewBatch()
+	for i, txid := range txids {
+		txs[i] = &LbrycrdTx{}
+		b.Queue(txs[i], "getrawtransaction", txid, true)
+	}
+	errs, err = b.Send()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, e := range errs {
+		if e != nil {
+			txs[i] = nil
+		}
+	}
+	return txs, errs, nil
+}
@@ -0,0 +1,246 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+type testLbrycrd struct {
+	lock     sync.Mutex
+	password string
+	batches  []int
+}
+
+// newTestLbrycrd returns a fake lbrycrd that answers batches in reverse order, so clients have to match up ids
+func newTestLbrycrd(t *testing.T, password string, handler func(method string, params []interface{}) (interface{}, int)) (*testLbrycrd, *httptest.Server) {
+	l := &testLbrycrd{password: password}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.lock.Lock()
+		want := l.password
+		l.lock.Unlock()
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != lbrycrdCookieUser || pass != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var raw json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		answer := func(req lbrycrdRequest) map[string]interface{} {
+			result, code := handler(req.Method, req.Params)
+			if code != 0 {
+				return map[string]interface{}{"id": req.ID, "result": nil, "error": map[string]interface{}{"code": code, "message": "failed"}}
+			}
+			return map[string]interface{}{"id": req.ID, "result": result, "error": nil}
+		}
+
+		if raw[0] != '[' {
+			var req lbrycrdRequest
+			_ = json.Unmarshal(raw, &req)
+			_ = json.NewEncoder(w).Encode(answer(req))
+			return
+		}
+
+		var reqs []lbrycrdRequest
+		_ = json.Unmarshal(raw, &reqs)
+		l.lock.Lock()
+		l.batches = append(l.batches, len(reqs))
+		l.lock.Unlock()
+		resps := make([]map[string]interface{}, len(reqs))
+		for i, req := range reqs {
+			resps[len(reqs)-1-i] = answer(req)
+		}
+		_ = json.NewEncoder(w).Encode(resps)
+	}))
+	t.Cleanup(server.Close)
+	return l, server
+}
+
+func writeCookie(t *testing.T, path, password string) {
+	err := os.WriteFile(path, []byte(lbrycrdCookieUser+":"+password+"\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLbrycrdClient_Batch(t *testing.T) {
+	l, server := newTestLbrycrd(t, "pw", func(method string, params []interface{}) (interface{}, int) {
+		height := params[0].(float64)
+		if height == 3 {
+			return nil, LbrycrdErrorCodeInvalidParameter
+		}
+		return "hash" + string(rune('0'+int(height))), 0
+	})
+	c, err := NewLbrycrdClient(server.URL, &LbrycrdOptions{User: lbrycrdCookieUser, Password: "pw", BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := make([]string, 5)
+	b := c.NewBatch()
+	for i := range hashes {
+		b.Queue(&hashes[i], "getblockhash", i)
+	}
+	errs, err := b.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 0 {
+		t.Error("batch should be empty after sending")
+	}
+
+	for i, h := range hashes {
+		if i == 3 {
+			if errs[i] == nil {
+				t.Error("expected an error for the fourth call")
+			} else if !errors.Is(errs[i], errors.CodeInvalid) {
+				t.Errorf("expected an invalid error, got %v", errs[i])
+			}
+			continue
+		}
+		if errs[i] != nil {
+			t.Errorf("call %d: %v", i, errs[i])
+		}
+		if want := "hash" + string(rune('0'+i)); h != want {
+			t.Errorf("call %d: expected %s, got %s", i, want, h)
+		}
+	}
+
+	if len(l.batches) != 3 {
+		t.Errorf("expected 5 calls to be split into 3 batches, got %v", l.batches)
+	}
+
+	_, err = c.GetBlockHashes([]int64{1, 3})
+	if err == nil {
+		t.Error("expected GetBlockHashes to fail if one of the calls did")
+	}
+}
+
+func TestLbrycrdClient_Cookie(t *testing.T) {
+	cookie := filepath.Join(t.TempDir(), ".cookie")
+	writeCookie(t, cookie, "first")
+
+	l, server := newTestLbrycrd(t, "first", func(method string, params []interface{}) (interface{}, int) {
+		return 10, 0
+	})
+	c, err := NewLbrycrdClient(server.URL, &LbrycrdOptions{CookieFile: cookie})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := c.GetBlockCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 10 {
+		t.Errorf("expected 10, got %d", count)
+	}
+
+	// lbrycrd restarted with a new cookie
+	l.lock.Lock()
+	l.password = "second"
+	l.lock.Unlock()
+	writeCookie(t, cookie, "second")
+
+	_, err = c.GetBlockCount()
+	if err != nil {
+		t.Fatalf("expected the client to reread the cookie, got %v", err)
+	}
+
+	l.lock.Lock()
+	l.password = "third"
+	l.lock.Unlock()
+	_, err = c.GetBlockCount()
+	if err == nil {
+		t.Error("expected an error when the cookie is wrong")
+	}
+
+	_, err = NewLbrycrdClient(server.URL, &LbrycrdOptions{CookieFile: filepath.Join(t.TempDir(), "missing")})
+	if err == nil {
+		t.Error("expected an error for a missing cookie file")
+	}
+}
+
+func TestLbrycrdClient_GetBlockVerbosity(t *testing.T) {
+	header := map[string]interface{}{
+		"hash":              "aa",
+		"height":            5,
+		"nameclaimroot":     "bb",
+		"previousblockhash": "cc",
+		"nTx":               1,
+	}
+	tx := map[string]interface{}{
+		"txid": "dd",
+		"vin":  []interface{}{map[string]interface{}{"coinbase": "0401", "sequence": 4294967295}},
+		"vout": []interface{}{map[string]interface{}{
+			"value":        1.5,
+			"n":            0,
+			"scriptPubKey": map[string]interface{}{"hex": "76a9", "type": "pubkeyhash", "addresses": []string{"bHW"}},
+		}},
+	}
+
+	_, server := newTestLbrycrd(t, "pw", func(method string, params []interface{}) (interface{}, int) {
+		if method != "getblock" {
+			return nil, ErrorCodeMethodNotFound
+		}
+		block := map[string]interface{}{}
+		for k, v := range header {
+			block[k] = v
+		}
+		switch int(params[1].(float64)) {
+		case BlockVerbosityHex:
+			return "0102", 0
+		case BlockVerbosityTxIDs:
+			block["tx"] = []string{"dd"}
+		case BlockVerbosityTxs:
+			block["tx"] = []interface{}{tx}
+		}
+		return block, 0
+	})
+	c, err := NewLbrycrdClient(server.URL, &LbrycrdOptions{User: lbrycrdCookieUser, Password: "pw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := c.GetBlockRaw("aa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) != 2 || raw[0] != 1 || raw[1] != 2 {
+		t.Errorf("unexpected raw block %x", raw)
+	}
+
+	block, err := c.GetBlock("aa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Height != 5 || block.ClaimTrieRoot != "bb" || len(block.Tx) != 1 || block.Tx[0] != "dd" {
+		t.Errorf("unexpected block %+v", block)
+	}
+
+	blocks, err := c.GetBlocksTxs([]string{"aa", "aa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 blocks, got %d", len(blocks))
+	}
+	txs := blocks[1].Tx
+	if len(txs) != 1 || txs[0].TxID != "dd" || txs[0].Vin[0].Coinbase != "0401" {
+		t.Fatalf("unexpected transactions %+v", txs)
+	}
+	if v := txs[0].Vout[0]; v.Value.String() != "1.5" || v.ScriptPubKey.Type != "pubkeyhash" || v.ScriptPubKey.Hex != "76a9" {
+		t.Errorf("unexpected output %+v", v)
+	}
+}
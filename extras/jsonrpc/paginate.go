@@ -0,0 +1,131 @@
+package jsonrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+const defaultPageSize = 50
+
+// how many times a page is tried before giving up, and how long to wait before the first retry. the wait doubles after
+// each retry
+var (
+	pageAttempts = 5
+	pageBackoff  = 1 * time.Second
+)
+
+// ClaimSearchAll runs a claim search and sends every claim on every page of results to the claims channel, which is
+// closed once they've all been sent. Then the error channel gets the error that stopped the search, or nil. Stop early
+// by cancelling ctx. args.Page is ignored, and args.PageSize defaults to 50.
+func (d *Client) ClaimSearchAll(ctx context.Context, args ClaimSearchArgs) (<-chan Claim, <-chan error) {
+	if args.PageSize == 0 {
+		args.PageSize = defaultPageSize
+	}
+	claims := make(chan Claim)
+	errc := make(chan error, 1)
+	c := d.WithContext(ctx)
+
+	go func() {
+		defer close(errc)
+		defer close(claims)
+		errc <- walkPages(ctx, args.PageSize, func(page uint64) (int, uint64, error) {
+			args.Page = page
+			var resp *ClaimSearchResponse
+			err := retryPage(ctx, func() (err error) {
+				resp, err = c.ClaimSearch(args)
+				return err
+			})
+			if err != nil {
+				return 0, 0, err
+			}
+			for _, claim := range resp.Claims {
+				select {
+				case claims <- claim:
+				case <-ctx.Done():
+					return 0, 0, errors.Err(ctx.Err())
+				}
+			}
+			return len(resp.Claims), resp.TotalPages, nil
+		})
+	}()
+
+	return claims, errc
+}
+
+// FileListAll works like ClaimSearchAll, for file_list. pageSize defaults to 50.
+func (d *Client) FileListAll(ctx context.Context, pageSize uint64) (<-chan File, <-chan error) {
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	files := make(chan File)
+	errc := make(chan error, 1)
+	c := d.WithContext(ctx)
+
+	go func() {
+		defer close(errc)
+		defer close(files)
+		errc <- walkPages(ctx, pageSize, func(page uint64) (int, uint64, error) {
+			var resp *FileListResponse
+			err := retryPage(ctx, func() (err error) {
+				resp, err = c.FileList(page, pageSize)
+				return err
+			})
+			if err != nil {
+				return 0, 0, err
+			}
+			for _, file := range resp.Items {
+				select {
+				case files <- file:
+				case <-ctx.Done():
+					return 0, 0, errors.Err(ctx.Err())
+				}
+			}
+			return len(resp.Items), resp.TotalPages, nil
+		})
+	}()
+
+	return files, errc
+}
+
+// walkPages gets pages until the last page is reached if the daemon gave a total, or until one is short or empty if it
+// didn't. getPage returns how many items were on the page, and the total number of pages or 0 if the daemon didn't say.
+func walkPages(ctx context.Context, pageSize uint64, getPage func(page uint64) (int, uint64, error)) error {
+	for page := uint64(1); ; page++ {
+		if ctx.Err() != nil {
+			return errors.Err(ctx.Err())
+		}
+		count, totalPages, err := getPage(page)
+		if err != nil {
+			return err
+		}
+		if totalPages > 0 {
+			// claims can be filtered out of a page after it's counted, so a short page isn't the end
+			if page >= totalPages {
+				return nil
+			}
+		} else if count == 0 || uint64(count) < pageSize {
+			return nil
+		}
+	}
+}
+
+// retryPage calls f until it works, it fails with an error that won't go away by retrying, or it's been tried
+// pageAttempts times
+func retryPage(ctx context.Context, f func() error) error {
+	backoff := pageBackoff
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= pageAttempts || ctx.Err() != nil || !errors.IsRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return errors.Err(ctx.Err())
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,169 @@
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	"github.com/ybbus/jsonrpc/v2"
+)
+
+var resolveTimeout = &jsonrpc.RPCError{Code: ErrorCodeApplication, Message: "hub timed out",
+	Data: map[string]interface{}{"name": ErrorResolveTimeout}}
+
+// claimPages fakes claim_search with total claims, without totals like the daemon does by default. Pages in failures
+// fail with the given error that many times before they work
+func claimPages(total int, failures map[float64]int, failure *jsonrpc.RPCError) func(string, map[string]interface{}) (interface{}, *jsonrpc.RPCError) {
+	return func(method string, params map[string]interface{}) (interface{}, *jsonrpc.RPCError) {
+		page, pageSize := params["page"].(float64), params["page_size"].(float64)
+		if failures[page] > 0 {
+			failures[page]--
+			return nil, failure
+		}
+		var items []map[string]interface{}
+		for i := int((page - 1) * pageSize); i < total && i < int(page*pageSize); i++ {
+			items = append(items, map[string]interface{}{"name": "claim", "nout": i})
+		}
+		return map[string]interface{}{"items": items, "page": page, "page_size": pageSize}, nil
+	}
+}
+
+func TestClaimSearchAll(t *testing.T) {
+	pageBackoff = time.Millisecond
+	d := newTestDaemon(t, claimPages(25, map[float64]int{2: 2}, resolveTimeout))
+
+	claims, errc := d.ClaimSearchAll(context.Background(), ClaimSearchArgs{PageSize: 10})
+	count := 0
+	for claim := range claims {
+		if int(claim.Nout) != count {
+			t.Errorf("expected claim %d, got %d", count, claim.Nout)
+		}
+		count++
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if count != 25 {
+		t.Errorf("expected 25 claims, got %d", count)
+	}
+}
+
+func TestClaimSearchAll_RetriesUnnamedErrors(t *testing.T) {
+	pageBackoff = time.Millisecond
+	for _, msg := range []string{"Couldn't find claim lbry://@channel#abc", "hub request timeout"} {
+		failure := &jsonrpc.RPCError{Code: ErrorCodeApplication, Message: msg}
+		d := newTestDaemon(t, claimPages(25, map[float64]int{1: 1, 3: 2}, failure))
+		claims, errc := d.ClaimSearchAll(context.Background(), ClaimSearchArgs{PageSize: 10})
+		count := 0
+		for range claims {
+			count++
+		}
+		if err := <-errc; err != nil {
+			t.Fatalf("%s: %v", msg, err)
+		}
+		if count != 25 {
+			t.Errorf("%s: expected 25 claims, got %d", msg, count)
+		}
+	}
+}
+
+func TestClaimSearchAll_ExactPages(t *testing.T) {
+	d := newTestDaemon(t, claimPages(20, nil, nil))
+	claims, errc := d.ClaimSearchAll(context.Background(), ClaimSearchArgs{PageSize: 10})
+	count := 0
+	for range claims {
+		count++
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if count != 20 {
+		t.Errorf("expected 20 claims, got %d", count)
+	}
+}
+
+func TestClaimSearchAll_GivesUp(t *testing.T) {
+	pageBackoff = time.Millisecond
+	d := newTestDaemon(t, claimPages(25, map[float64]int{2: pageAttempts}, resolveTimeout))
+	claims, errc := d.ClaimSearchAll(context.Background(), ClaimSearchArgs{PageSize: 10})
+	for range claims {
+	}
+	if err := <-errc; err == nil {
+		t.Error("expected an error after running out of attempts")
+	}
+}
+
+func TestClaimSearchAll_Cancel(t *testing.T) {
+	d := newTestDaemon(t, claimPages(1000, nil, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	claims, errc := d.ClaimSearchAll(ctx, ClaimSearchArgs{PageSize: 10})
+	<-claims
+	cancel()
+	for range claims {
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestFileListAll(t *testing.T) {
+	d := newTestDaemon(t, func(method string, params map[string]interface{}) (interface{}, *jsonrpc.RPCError) {
+		page := params["page"].(float64)
+		items := []map[string]interface{}{{"claim_name": "a"}, {"claim_name": "b"}}
+		// the daemon returns totals for file_list
+		return map[string]interface{}{"items": items, "page": page, "page_size": 2, "total_pages": 3}, nil
+	})
+	files, errc := d.FileListAll(context.Background(), 2)
+	count := 0
+	for range files {
+		count++
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if count != 6 {
+		t.Errorf("expected 6 files, got %d", count)
+	}
+}
+
+func TestFileListAll_ShortPageWithTotal(t *testing.T) {
+	d := newTestDaemon(t, func(method string, params map[string]interface{}) (interface{}, *jsonrpc.RPCError) {
+		page := params["page"].(float64)
+		items := []map[string]interface{}{{"claim_name": "a"}, {"claim_name": "b"}}
+		if page == 2 {
+			items = items[:1]
+		}
+		return map[string]interface{}{"items": items, "page": page, "page_size": 2, "total_pages": 3}, nil
+	})
+	files, errc := d.FileListAll(context.Background(), 2)
+	count := 0
+	for range files {
+		count++
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if count != 5 {
+		t.Errorf("expected 5 files, got %d", count)
+	}
+}
+
+func TestClaimSearchAll_NotRetryable(t *testing.T) {
+	pageBackoff = time.Millisecond
+	calls := 0
+	d := newTestDaemon(t, func(method string, params map[string]interface{}) (interface{}, *jsonrpc.RPCError) {
+		calls++
+		return nil, &jsonrpc.RPCError{Code: ErrorCodeInvalidParams, Message: "bad page size"}
+	})
+	claims, errc := d.ClaimSearchAll(context.Background(), ClaimSearchArgs{PageSize: 10})
+	for range claims {
+	}
+	if err := <-errc; err == nil {
+		t.Error("expected an error")
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
@@ -16,8 +16,12 @@ package stop
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
 // Chan is a receive-only channel
@@ -29,36 +33,66 @@ type Group struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
+	name   string
+	parent *Group
+	debug  bool
+
 	mu        *sync.Mutex
+	running   int
 	waitingOn map[string]int
+	// children that have goroutines running, so they can be waited on and reported
+	children map[*Group]struct{}
 }
 type Stopper = Group
 
 // New allocates and returns a new instance. Use New(parent) to create an instance that is stopped when parent is stopped.
 func New(parent ...*Group) *Group {
-	s := &Group{}
-	ctx := context.Background()
 	if len(parent) > 0 && parent[0] != nil {
-		ctx = parent[0].ctx
+		return newGroup(parent[0].ctx, parent[0])
+	}
+	return newGroup(context.Background(), nil)
+}
+
+func newGroup(ctx context.Context, parent *Group) *Group {
+	s := &Group{
+		parent:    parent,
+		mu:        &sync.Mutex{},
+		waitingOn: make(map[string]int),
+		children:  make(map[*Group]struct{}),
 	}
 	s.ctx, s.cancel = context.WithCancel(ctx)
 	return s
 }
 
+// NewNamed is New, with a name that shows up in WaitTimeout errors
+func NewNamed(name string, parent ...*Group) *Group {
+	s := New(parent...)
+	s.name = name
+	return s
+}
+
 // NewDebug allows you to debug the go routines the group waits on. In order to leverage this, AddNamed and DoneNamed should be used.
 func NewDebug(parent ...*Group) *Group {
 	s := New(parent...)
-	s.waitingOn = make(map[string]int)
-	s.mu = &sync.Mutex{}
-
+	s.debug = true
 	return s
 }
 
+// FromContext returns an instance that is stopped when ctx is done
+func FromContext(ctx context.Context) *Group {
+	return newGroup(ctx, nil)
+}
+
 // Ch returns a channel that will be closed when Stop is called.
 func (s *Group) Ch() Chan {
 	return s.ctx.Done()
 }
 
+// Context returns a context that is cancelled when Stop is called, for passing to functions that take one
+func (s *Group) Context() context.Context {
+	return s.ctx
+}
+
 // Stop signals any listening processes to stop. After the first call, Stop() does nothing.
 func (s *Group) Stop() {
 	s.cancel()
@@ -75,55 +109,176 @@ func (s *Group) Child() *Group {
 	return New(s)
 }
 
-//AddNamed is the same as Add but will register the functional name of the routine for later output. See `DoneNamed`.
-func (s *Group) AddNamed(delta int, name string) {
-	s.Add(delta)
+// ChildNamed is Child, with a name that shows up in WaitTimeout errors
+func (s *Group) ChildNamed(name string) *Group {
+	return NewNamed(name, s)
+}
 
-	if s.waitingOn != nil {
-		s.mu.Lock()
-		defer s.mu.Unlock()
+// Add is the same as sync.WaitGroup.Add, but keeps count so WaitTimeout can report what's still running
+func (s *Group) Add(delta int) {
+	s.WaitGroup.Add(delta)
 
-		if s.waitingOn == nil {
-			s.waitingOn = make(map[string]int)
-		}
+	s.mu.Lock()
+	before := s.running
+	s.running += delta
+	after := s.running
+	s.mu.Unlock()
 
-		if current, ok := s.waitingOn[name]; ok {
-			s.waitingOn[name] = current + 1
-		} else {
-			s.waitingOn[name] = 1
-		}
+	if s.parent == nil {
+		return
 	}
+	if before <= 0 && after > 0 {
+		s.parent.mu.Lock()
+		s.parent.children[s] = struct{}{}
+		s.parent.mu.Unlock()
+	} else if before > 0 && after <= 0 {
+		s.parent.mu.Lock()
+		delete(s.parent.children, s)
+		s.parent.mu.Unlock()
+	}
+}
+
+// Done is the same as sync.WaitGroup.Done
+func (s *Group) Done() {
+	s.Add(-1)
+}
+
+// Go runs f in a goroutine that the group waits for. The name shows up in WaitTimeout errors if f doesn't return.
+func (s *Group) Go(name string, f func()) {
+	s.AddNamed(1, name)
+	go func() {
+		defer s.DoneNamed(name)
+		f()
+	}()
+}
+
+//AddNamed is the same as Add but will register the functional name of the routine for later output. See `DoneNamed`.
+func (s *Group) AddNamed(delta int, name string) {
+	s.Add(delta)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waitingOn[name] += delta
 }
 
 //DoneNamed is the same as `Done` but will output the functional name of all remaining named routines and the waiting on count.
 func (s *Group) DoneNamed(name string) {
 	defer s.Done()
 
-	if s.waitingOn != nil {
-		s.mu.Lock()
-		if current, ok := s.waitingOn[name]; ok {
-			if current <= 1 {
-				delete(s.waitingOn, name)
-			} else {
-				s.waitingOn[name] = current - 1
-			}
+	s.mu.Lock()
+	if current, ok := s.waitingOn[name]; ok {
+		if current <= 1 {
+			delete(s.waitingOn, name)
 		} else {
-			log.Printf("%s is not recorded in stop group map", name)
+			s.waitingOn[name] = current - 1
 		}
-		s.mu.Unlock()
+	} else if s.debug {
+		log.Printf("%s is not recorded in stop group map", name)
+	}
+	s.mu.Unlock()
+
+	if s.debug {
 		s.listWaitingOn()
 	}
 }
 
 func (s *Group) listWaitingOn() {
-	if s.waitingOn != nil {
-		s.mu.Lock()
-		defer s.mu.Unlock()
-		log.Printf("-->> LIST ROUTINES WAITING ON")
-		for k, v := range s.waitingOn {
-			if v > 0 {
-				log.Printf("waiting on %d %s routines...", v, k)
-			}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	log.Printf("-->> LIST ROUTINES WAITING ON")
+	for k, v := range s.waitingOn {
+		if v > 0 {
+			log.Printf("waiting on %d %s routines...", v, k)
+		}
+	}
+}
+
+// TimeoutError is returned by WaitTimeout when goroutines didn't return in time
+type TimeoutError struct {
+	// what's still running, like "dht/announce x2". goroutines started without a name are counted as "unnamed"
+	Running []string
+}
+
+func (e *TimeoutError) Error() string {
+	return "timed out waiting for " + strings.Join(e.Running, ", ")
+}
+
+// WaitTimeout waits for the goroutines in the group and in its children, for at most timeout. If they don't all return
+// in time, it returns a *TimeoutError saying which ones are still running. They keep running either way.
+func (s *Group) WaitTimeout(timeout time.Duration) error {
+	done := make(chan struct{})
+	go func() {
+		s.waitAll()
+		close(done)
+	}()
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-t.C:
+		return &TimeoutError{Running: s.report("")}
+	}
+}
+
+// StopAndWaitTimeout is StopAndWait, but gives up after timeout. See WaitTimeout.
+func (s *Group) StopAndWaitTimeout(timeout time.Duration) error {
+	s.Stop()
+	return s.WaitTimeout(timeout)
+}
+
+func (s *Group) waitAll() {
+	s.Wait()
+	for _, c := range s.runningChildren() {
+		c.waitAll()
+	}
+}
+
+func (s *Group) runningChildren() []*Group {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	children := make([]*Group, 0, len(s.children))
+	for c := range s.children {
+		children = append(children, c)
+	}
+	return children
+}
+
+// report lists what's running in s and its children
+func (s *Group) report(prefix string) []string {
+	name := prefix
+	if s.name != "" {
+		if name != "" {
+			name += "/"
+		}
+		name += s.name
+	}
+
+	s.mu.Lock()
+	var running []string
+	unnamed := s.running
+	for routine, count := range s.waitingOn {
+		if count > 0 {
+			running = append(running, fmt.Sprintf("%s x%d", join(name, routine), count))
+			unnamed -= count
 		}
 	}
+	if unnamed > 0 {
+		running = append(running, fmt.Sprintf("%s x%d", join(name, "unnamed"), unnamed))
+	}
+	s.mu.Unlock()
+	sort.Strings(running)
+
+	for _, c := range s.runningChildren() {
+		running = append(running, c.report(name)...)
+	}
+	return running
+}
+
+func join(group, routine string) string {
+	if group == "" {
+		return routine
+	}
+	return group + "/" + routine
 }
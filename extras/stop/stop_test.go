@@ -0,0 +1,63 @@
+package stop
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWaitTimeout(t *testing.T) {
+	grp := NewNamed("server")
+	stuck := make(chan struct{})
+	defer close(stuck)
+
+	grp.Go("listener", func() { <-grp.Ch() })
+	grp.Go("handler", func() { <-stuck })
+	child := grp.ChildNamed("conn")
+	child.Go("reader", func() { <-stuck })
+	child.Go("reader", func() { <-stuck })
+	child.Add(1) // an unnamed goroutine that never calls Done
+
+	err := grp.StopAndWaitTimeout(50 * time.Millisecond)
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	expected := []string{"server/handler x1", "server/conn/reader x2", "server/conn/unnamed x1"}
+	if !reflect.DeepEqual(timeoutErr.Running, expected) {
+		t.Errorf("expected %v, got %v", expected, timeoutErr.Running)
+	}
+	if child.Context().Err() == nil {
+		t.Error("expected child to be stopped with its parent")
+	}
+}
+
+func TestWaitTimeout_Clean(t *testing.T) {
+	grp := New()
+	child := grp.Child()
+	for i := 0; i < 3; i++ {
+		grp.Go("worker", func() { <-grp.Ch() })
+		child.Go("worker", func() { <-child.Ch() })
+	}
+
+	if err := grp.StopAndWaitTimeout(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if len(grp.runningChildren()) != 0 {
+		t.Error("expected finished children to be forgotten")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	grp := FromContext(ctx)
+	cancel()
+
+	select {
+	case <-grp.Ch():
+	case <-time.After(time.Second):
+		t.Fatal("expected group to stop when its context is cancelled")
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"golang.org/x/crypto/ripemd160"
 )
 
@@ -17,15 +20,20 @@ func rev(b []byte) []byte {
 	return r
 }
 
+// ClaimIDFromOutpoint returns the id of the claim made in output nout of transaction txid. The id is the hash160 of the
+// transaction hash (in its internal byte order) followed by the big-endian nout, shown in reverse like txids are.
 func ClaimIDFromOutpoint(txid string, nout int) (string, error) {
 	// convert transaction id to byte array
-	txidBytes, err := hex.DecodeString(txid)
+	txidBytes, err := ReverseHex(txid)
 	if err != nil {
 		return "", err
 	}
-
-	// reverse (make big-endian)
-	txidBytes = rev(txidBytes)
+	if len(txidBytes) != chainhash.HashSize {
+		return "", errors.Err("txid must be %d bytes, got %d", chainhash.HashSize, len(txidBytes))
+	}
+	if nout < 0 {
+		return "", errors.Err("invalid nout %d", nout)
+	}
 
 	// append nout
 	noutBytes := make([]byte, 4) // num bytes in uint32
@@ -40,8 +48,32 @@ func ClaimIDFromOutpoint(txid string, nout int) (string, error) {
 	r := ripemd160.New()
 	r.Write(s.Sum(nil))
 
-	// reverse (make little-endian)
-	res := rev(r.Sum(nil))
+	return ClaimIDFromBytes(r.Sum(nil)), nil
+}
+
+// ReverseHex decodes a hex string that is shown in reverse byte order, like a txid or claim id, into its internal
+// byte order
+func ReverseHex(s string) ([]byte, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return rev(b), nil
+}
+
+// ClaimIDToBytes returns a claim id in the byte order that scripts use
+func ClaimIDToBytes(claimID string) ([]byte, error) {
+	b, err := ReverseHex(claimID)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != ripemd160.Size {
+		return nil, errors.Err("claim id must be %d bytes, got %d", ripemd160.Size, len(b))
+	}
+	return b, nil
+}
 
-	return hex.EncodeToString(res), nil
+// ClaimIDFromBytes returns the displayed claim id for one in the byte order that scripts use
+func ClaimIDFromBytes(b []byte) string {
+	return hex.EncodeToString(rev(b))
 }
@@ -52,3 +52,32 @@ func TestGetClaimIDFromOutput(t *testing.T) {
 		}
 	}
 }
+
+func TestClaimIDFromOutpoint_Invalid(t *testing.T) {
+	for _, txid := range []string{"", "zz", "6a9dbe3084b86cec8aa519970d2245dfa15193294cab65819a0d96d455c2a5"} {
+		if _, err := lbrycrd.ClaimIDFromOutpoint(txid, 0); err == nil {
+			t.Errorf("expected an error for txid '%s'", txid)
+		}
+	}
+	if _, err := lbrycrd.ClaimIDFromOutpoint(claimIdTests[0].txHash, -1); err == nil {
+		t.Error("expected an error for a negative nout")
+	}
+}
+
+func TestClaimIDBytes(t *testing.T) {
+	for _, test := range claimIdTests {
+		b, err := lbrycrd.ClaimIDToBytes(test.claimID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) != 20 {
+			t.Errorf("expected 20 bytes, got %d", len(b))
+		}
+		if lbrycrd.ClaimIDFromBytes(b) != test.claimID {
+			t.Errorf("expected %s, got %s", test.claimID, lbrycrd.ClaimIDFromBytes(b))
+		}
+	}
+	if _, err := lbrycrd.ClaimIDToBytes("589bc4"); err == nil {
+		t.Error("expected an error for a short claim id")
+	}
+}
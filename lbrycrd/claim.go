@@ -84,3 +84,71 @@ func SignClaim(rawTx *wire.MsgTx, privKey btcec.PrivateKey, claim, channel *c.St
 	return nil
 
 }
+
+// ClaimFromTx parses the script of output nout of tx and decodes the claim or support in it
+func ClaimFromTx(tx *wire.MsgTx, nout int, blockchainName string) (*ClaimScript, *c.StakeHelper, error) {
+	if nout < 0 || nout >= len(tx.TxOut) {
+		return nil, nil, errors.Err("transaction has no output %d", nout)
+	}
+	script, err := ParseClaimScript(tx.TxOut[nout].PkScript)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var helper *c.StakeHelper
+	switch script.Type {
+	case ClaimName, ClaimUpdate:
+		helper, err = c.DecodeClaimBytes(script.Value, blockchainName)
+	case ClaimSupport:
+		if len(script.Value) == 0 {
+			return script, nil, nil
+		}
+		helper, err = c.DecodeSupportBytes(script.Value, blockchainName)
+	default:
+		return nil, nil, errors.Err("output %d is not a claim or support", nout)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if script.Type == ClaimName {
+		script.ClaimID, err = ClaimIDFromOutpoint(tx.TxHash().String(), nout)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return script, helper, nil
+}
+
+// ValidateClaimSignature checks the channel signature of the claim in output nout of tx against the channel's public
+// key. Legacy claims sign the claim address, everything since signs the first input of the transaction.
+func ValidateClaimSignature(tx *wire.MsgTx, nout int, channelKey *btcec.PublicKey, blockchainName string) (bool, error) {
+	script, claim, err := ClaimFromTx(tx, nout, blockchainName)
+	if err != nil {
+		return false, err
+	}
+	if claim == nil || claim.Signature == nil {
+		return false, errors.Err("output %d is not signed by a channel", nout)
+	}
+
+	var k string
+	if claim.LegacyClaim != nil {
+		params, ok := ChainParamsMap[blockchainName]
+		if !ok {
+			return false, errors.Err("unknown blockchain %s", blockchainName)
+		}
+		address, err := script.Address(&params)
+		if err != nil {
+			return false, err
+		}
+		k = address.EncodeAddress()
+	} else {
+		if len(tx.TxIn) == 0 {
+			return false, errors.Err("transaction has no inputs")
+		}
+		k, err = outpointHash(tx.TxIn[0].PreviousOutPoint)
+		if err != nil {
+			return false, err
+		}
+	}
+	return claim.ValidateSignature(channelKey, k, blockchainName)
+}
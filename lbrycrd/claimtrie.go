@@ -0,0 +1,414 @@
+package lbrycrd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	c "github.com/lbryio/lbry.go/v2/schema/stake"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// ClaimTrieSupport is a support for a claim in the claimtrie. Amounts are in deweys.
+type ClaimTrieSupport struct {
+	TxID          string `json:"txId"`
+	Nout          int    `json:"n"`
+	Height        int    `json:"height"`
+	ValidAtHeight int    `json:"validAtHeight"`
+	Amount        int64  `json:"amount"`
+	Address       string `json:"address"`
+	// hex-encoded. only set for supports that carry a value
+	Value string `json:"value,omitempty"`
+}
+
+// ClaimTrieClaim is a claim in the claimtrie. Amounts are in deweys.
+type ClaimTrieClaim struct {
+	Name               string             `json:"name"`
+	NormalizedName     string             `json:"normalizedName"`
+	ClaimID            string             `json:"claimId"`
+	TxID               string             `json:"txId"`
+	Nout               int                `json:"n"`
+	Height             int                `json:"height"`
+	ValidAtHeight      int                `json:"validAtHeight"`
+	Amount             int64              `json:"amount"`
+	EffectiveAmount    int64              `json:"effectiveAmount"`
+	PendingAmount      int64              `json:"pendingAmount"`
+	LastTakeoverHeight int                `json:"lastTakeoverHeight"`
+	Address            string             `json:"address"`
+	Supports           []ClaimTrieSupport `json:"supports"`
+	// hex-encoded
+	Value string `json:"value"`
+}
+
+// Stake decodes the claim's value
+func (t *ClaimTrieClaim) Stake(blockchainName string) (*c.StakeHelper, error) {
+	return c.DecodeClaimHex(t.Value, blockchainName)
+}
+
+// ClaimsForName is every claim for a name, and supports for claims that no longer exist
+type ClaimsForName struct {
+	NormalizedName       string             `json:"normalizedName"`
+	Claims               []ClaimTrieClaim   `json:"claims"`
+	SupportsWithoutClaim []ClaimTrieSupport `json:"supportsWithoutClaim"`
+	LastTakeoverHeight   int                `json:"lastTakeoverHeight"`
+}
+
+// ProofChild is a child of a claimtrie node in a proof. NodeHash is empty for the child that the proof goes through.
+type ProofChild struct {
+	Character int    `json:"character"`
+	NodeHash  string `json:"nodeHash,omitempty"`
+}
+
+// ProofNode is a claimtrie node on the way from the root to a name
+type ProofNode struct {
+	Children  []ProofChild `json:"children"`
+	ValueHash string       `json:"valueHash,omitempty"`
+}
+
+// ProofPair is a sibling hash on the way from a claim to the root. Odd means the sibling goes on the left.
+type ProofPair struct {
+	Odd  bool   `json:"odd"`
+	Hash string `json:"hash"`
+}
+
+// ClaimProof proves that a claim is (or that no claim is) in the claimtrie at some block. Proofs from before the hash
+// fork have Nodes, and later ones have Pairs.
+type ClaimProof struct {
+	Nodes []ProofNode `json:"nodes"`
+	Pairs []ProofPair `json:"pairs"`
+	// the outpoint of the claim that's proven. empty if the proof shows that there's no claim for the name
+	TxID               string `json:"txId"`
+	Nout               *int   `json:"n"`
+	LastTakeoverHeight int    `json:"lastTakeoverHeight"`
+}
+
+// GetClaimsForName returns all the claims for a name at a block, or at the chain tip if blockHash is nil
+func (c *Client) GetClaimsForName(name string, blockHash *chainhash.Hash) (*ClaimsForName, error) {
+	var res ClaimsForName
+	err := c.claimTrieRequest(&res, "getclaimsforname", name, blockHash, "")
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetValueForName returns the claim that controls a name. If claimID is set, that claim is returned instead, as
+// long as it's a claim for the name.
+func (c *Client) GetValueForName(name string, blockHash *chainhash.Hash, claimID string) (*ClaimTrieClaim, error) {
+	var res ClaimTrieClaim
+	err := c.claimTrieRequest(&res, "getvalueforname", name, blockHash, claimID)
+	if err != nil {
+		return nil, err
+	}
+	if res.ClaimID == "" {
+		return nil, errors.Err("no claim for %s", name)
+	}
+	return &res, nil
+}
+
+// GetClaimByID returns a claim that's currently in the claimtrie
+func (c *Client) GetClaimByID(claimID string) (*ClaimTrieClaim, error) {
+	params, err := jsonParams(claimID)
+	if err != nil {
+		return nil, err
+	}
+	var res ClaimTrieClaim
+	err = c.rawRequest(&res, "getclaimbyid", params)
+	if err != nil {
+		return nil, err
+	}
+	if res.ClaimID == "" {
+		return nil, errors.Err("claim %s not found", claimID)
+	}
+	return &res, nil
+}
+
+// GetNamesInTrie returns every name that has a claim at a block, or at the chain tip if blockHash is nil
+func (c *Client) GetNamesInTrie(blockHash *chainhash.Hash) ([]string, error) {
+	var args []interface{}
+	if blockHash != nil {
+		args = append(args, blockHash.String())
+	}
+	params, err := jsonParams(args...)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	err = c.rawRequest(&names, "getnamesintrie", params)
+	return names, err
+}
+
+// GetClaimProof returns lbrycrd's proof (getnameproof) for the claim that controls name, or for claimID if it's set, at
+// a block. If blockHash is nil, the chain tip is used.
+func (c *Client) GetClaimProof(name string, blockHash *chainhash.Hash, claimID string) (*ClaimProof, error) {
+	var res ClaimProof
+	err := c.claimTrieRequest(&res, "getnameproof", name, blockHash, claimID)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetClaimTrieRoot returns the claimtrie root hash in the header of a block, or of the chain tip if blockHash is nil
+func (c *Client) GetClaimTrieRoot(blockHash *chainhash.Hash) (*chainhash.Hash, error) {
+	if blockHash == nil {
+		tip, err := c.GetBestBlockHash()
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		blockHash = tip
+	}
+	params, err := jsonParams(blockHash.String(), false)
+	if err != nil {
+		return nil, err
+	}
+	var header string
+	err = c.rawRequest(&header, "getblockheader", params)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := hex.DecodeString(header)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return claimTrieRootFromHeader(raw)
+}
+
+// GetVerifiedClaim gets the claim that controls name (or claimID, if it's set) at the chain tip, and checks lbrycrd's
+// proof for it against the tip's header, so the result doesn't depend on trusting lbrycrd's answer
+func (c *Client) GetVerifiedClaim(name, claimID string) (*ClaimTrieClaim, error) {
+	tip, err := c.GetBestBlockHash()
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	claim, err := c.GetValueForName(name, tip, claimID)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := c.GetClaimProof(name, tip, claim.ClaimID)
+	if err != nil {
+		return nil, err
+	}
+	root, err := c.GetClaimTrieRoot(tip)
+	if err != nil {
+		return nil, err
+	}
+
+	if proof.TxID != claim.TxID || proof.Nout == nil || *proof.Nout != claim.Nout {
+		return nil, errors.Err("proof is for %s:%v, not the claim at %s:%d", proof.TxID, proof.Nout, claim.TxID, claim.Nout)
+	}
+	err = proof.Verify(name, *root)
+	if err != nil {
+		return nil, err
+	}
+	return claim, nil
+}
+
+// claimTrieRequest makes one of the claimtrie calls that take a name, an optional block hash, and an optional claim id
+func (c *Client) claimTrieRequest(res interface{}, method, name string, blockHash *chainhash.Hash, claimID string) error {
+	args := []interface{}{name}
+	if blockHash == nil && claimID != "" {
+		// the block hash comes first, so it has to be filled in to give a claim id
+		tip, err := c.GetBestBlockHash()
+		if err != nil {
+			return errors.Err(err)
+		}
+		blockHash = tip
+	}
+	if blockHash != nil {
+		args = append(args, blockHash.String())
+	}
+	if claimID != "" {
+		args = append(args, claimID)
+	}
+
+	params, err := jsonParams(args...)
+	if err != nil {
+		return err
+	}
+	return c.rawRequest(res, method, params)
+}
+
+func (c *Client) rawRequest(res interface{}, method string, params []json.RawMessage) error {
+	raw, err := c.RawRequest(method, params)
+	if err != nil {
+		return errors.Prefix(method, err)
+	}
+	err = json.Unmarshal(raw, res)
+	if err != nil {
+		return errors.Prefix(method, err)
+	}
+	return nil
+}
+
+func jsonParams(args ...interface{}) ([]json.RawMessage, error) {
+	params := make([]json.RawMessage, len(args))
+	for i, arg := range args {
+		p, err := json.Marshal(arg)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		params[i] = p
+	}
+	return params, nil
+}
+
+// blockHeaderSize is the size of an lbrycrd block header, which is a bitcoin header with the claimtrie root hash
+// after the merkle root
+const blockHeaderSize = 112
+
+// claimTrieRootFromHeader returns the claimtrie root hash in a serialized block header
+func claimTrieRootFromHeader(header []byte) (*chainhash.Hash, error) {
+	if len(header) != blockHeaderSize {
+		return nil, errors.Err("block header must be %d bytes, got %d", blockHeaderSize, len(header))
+	}
+	return chainhash.NewHash(header[68:100])
+}
+
+// Verify checks that the proof hashes up to root, the claimtrie root hash in a block header. For proofs from before
+// the hash fork, it also checks that the proof is for name. Proofs from after the fork don't cover the name, so check
+// that the claim's transaction has the right name.
+func (p *ClaimProof) Verify(name string, root chainhash.Hash) error {
+	if len(p.Pairs) > 0 {
+		return p.verifyPairs(root)
+	}
+	return p.verifyNodes(name, root)
+}
+
+func (p *ClaimProof) valueHash() (*chainhash.Hash, error) {
+	if p.TxID == "" || p.Nout == nil {
+		return nil, nil
+	}
+	txid, err := chainhash.NewHashFromStr(p.TxID)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	h := claimValueHash(*txid, *p.Nout, p.LastTakeoverHeight)
+	return &h, nil
+}
+
+// verifyPairs checks a proof from after the hash fork, which is the merkle path from the claim's value hash to the root
+func (p *ClaimProof) verifyPairs(root chainhash.Hash) error {
+	h, err := p.valueHash()
+	if err != nil {
+		return err
+	}
+	if h == nil {
+		return errors.Err("proof has no claim")
+	}
+
+	computed := *h
+	for _, pair := range p.Pairs {
+		sibling, err := chainhash.NewHashFromStr(pair.Hash)
+		if err != nil {
+			return errors.Err(err)
+		}
+		if pair.Odd {
+			computed = chainhash.DoubleHashH(append(sibling[:], computed[:]...))
+		} else {
+			computed = chainhash.DoubleHashH(append(computed[:], sibling[:]...))
+		}
+	}
+
+	if computed != root {
+		return errors.Err("proof hashes to %s, not the claimtrie root %s", computed, root)
+	}
+	return nil
+}
+
+// verifyNodes checks a proof from before the hash fork, which has every node from the root to the name. each node's
+// hash covers its children's characters and hashes, then its value hash.
+func (p *ClaimProof) verifyNodes(name string, root chainhash.Hash) error {
+	if len(p.Nodes) == 0 {
+		return errors.Err("proof has no nodes")
+	}
+	value, err := p.valueHash()
+	if err != nil {
+		return err
+	}
+
+	var computed *chainhash.Hash
+	var reversedName []byte
+	for i := len(p.Nodes) - 1; i >= 0; i-- {
+		node := p.Nodes[i]
+		var buf bytes.Buffer
+		prevChar := -1
+		foundNext := false
+
+		for _, child := range node.Children {
+			if child.Character < 0 || child.Character > 255 {
+				return errors.Err("invalid child character %d", child.Character)
+			}
+			if child.Character <= prevChar {
+				return errors.Err("children are not in order")
+			}
+			prevChar = child.Character
+			buf.WriteByte(byte(child.Character))
+
+			if child.NodeHash != "" {
+				h, err := chainhash.NewHashFromStr(child.NodeHash)
+				if err != nil {
+					return errors.Err(err)
+				}
+				buf.Write(h[:])
+				continue
+			}
+
+			// this is the child the proof goes through
+			if computed == nil {
+				return errors.Err("the last node can't have a child without a hash")
+			}
+			if foundNext {
+				return errors.Err("node has more than one child without a hash")
+			}
+			foundNext = true
+			reversedName = append(reversedName, byte(child.Character))
+			buf.Write(computed[:])
+		}
+
+		if computed != nil && !foundNext {
+			return errors.Err("node does not lead to the next node in the proof")
+		}
+
+		last := i == len(p.Nodes)-1
+		if last && value != nil {
+			buf.Write(value[:])
+		} else if node.ValueHash != "" {
+			h, err := chainhash.NewHashFromStr(node.ValueHash)
+			if err != nil {
+				return errors.Err(err)
+			}
+			buf.Write(h[:])
+		}
+
+		h := chainhash.DoubleHashH(buf.Bytes())
+		computed = &h
+	}
+
+	if *computed != root {
+		return errors.Err("proof hashes to %s, not the claimtrie root %s", computed, root)
+	}
+
+	proven := string(rev(reversedName))
+	if proven != name {
+		return errors.Err("proof is for %q, not %q", proven, name)
+	}
+	return nil
+}
+
+// claimValueHash is the hash that a claim contributes to the claimtrie. It commits to the claim's outpoint and the
+// height that its name was last taken over at.
+func claimValueHash(txid chainhash.Hash, nout, lastTakeoverHeight int) chainhash.Hash {
+	txHash := chainhash.DoubleHashB(txid[:])
+	noutHash := chainhash.DoubleHashB([]byte(strconv.Itoa(nout)))
+
+	height := make([]byte, 8)
+	binary.BigEndian.PutUint32(height[4:], uint32(lastTakeoverHeight))
+	heightHash := chainhash.DoubleHashB(height)
+
+	return chainhash.DoubleHashH(append(append(txHash, noutHash...), heightHash...))
+}
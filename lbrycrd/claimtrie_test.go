@@ -0,0 +1,136 @@
+package lbrycrd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// trieNodeHash hashes a claimtrie node from before the hash fork
+func trieNodeHash(children map[byte]chainhash.Hash, order []byte, value *chainhash.Hash) chainhash.Hash {
+	var buf bytes.Buffer
+	for _, char := range order {
+		h := children[char]
+		buf.WriteByte(char)
+		buf.Write(h[:])
+	}
+	if value != nil {
+		buf.Write(value[:])
+	}
+	return chainhash.DoubleHashH(buf.Bytes())
+}
+
+func TestClaimProof_VerifyNodes(t *testing.T) {
+	txid := chainhash.Hash{1, 2, 3}
+	nout := 1
+	value := claimValueHash(txid, nout, 100)
+	other := chainhash.Hash{9}
+	otherValue := chainhash.Hash{8}
+
+	// a trie with the names "ab", "a", and something under "z"
+	ab := trieNodeHash(nil, nil, &value)
+	a := trieNodeHash(map[byte]chainhash.Hash{'b': ab}, []byte{'b'}, &otherValue)
+	root := trieNodeHash(map[byte]chainhash.Hash{'a': a, 'z': other}, []byte{'a', 'z'}, nil)
+
+	// the same shape as lbrycrd's json
+	raw := `{
+		"nodes": [
+			{"children": [{"character": 97}, {"character": 122, "nodeHash": "` + other.String() + `"}]},
+			{"children": [{"character": 98}], "valueHash": "` + otherValue.String() + `"},
+			{"children": []}
+		],
+		"txId": "` + txid.String() + `",
+		"n": 1,
+		"lastTakeoverHeight": 100
+	}`
+	var proof ClaimProof
+	err := json.Unmarshal([]byte(raw), &proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := proof.Verify("ab", root); err != nil {
+		t.Fatal(err)
+	}
+	if err := proof.Verify("ac", root); err == nil {
+		t.Error("proof verified for the wrong name")
+	}
+	if err := proof.Verify("ab", chainhash.Hash{7}); err == nil {
+		t.Error("proof verified against the wrong root")
+	}
+
+	proof.LastTakeoverHeight = 101
+	if err := proof.Verify("ab", root); err == nil {
+		t.Error("proof verified with the wrong takeover height")
+	}
+	proof.LastTakeoverHeight = 100
+
+	nout = 2
+	proof.Nout = &nout
+	if err := proof.Verify("ab", root); err == nil {
+		t.Error("proof verified for the wrong outpoint")
+	}
+}
+
+func TestClaimProof_VerifyNodes_Malformed(t *testing.T) {
+	h := chainhash.Hash{1}.String()
+	proofs := map[string]ClaimProof{
+		"no nodes":          {},
+		"unordered":         {Nodes: []ProofNode{{Children: []ProofChild{{Character: 98}, {Character: 97, NodeHash: h}}}, {}}},
+		"two open children": {Nodes: []ProofNode{{Children: []ProofChild{{Character: 97}, {Character: 98}}}, {}}},
+		"open last node":    {Nodes: []ProofNode{{Children: []ProofChild{{Character: 97}}}}},
+		"broken chain":      {Nodes: []ProofNode{{Children: []ProofChild{{Character: 97, NodeHash: h}}}, {}}},
+		"bad character":     {Nodes: []ProofNode{{Children: []ProofChild{{Character: 256}}}, {}}},
+	}
+	for name, proof := range proofs {
+		if err := proof.Verify("a", chainhash.Hash{}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestClaimProof_VerifyPairs(t *testing.T) {
+	txid := chainhash.Hash{4, 5, 6}
+	nout := 0
+	value := claimValueHash(txid, nout, 7)
+	left := chainhash.Hash{1}
+	right := chainhash.Hash{2}
+
+	level1 := chainhash.DoubleHashH(append(left[:], value[:]...))
+	root := chainhash.DoubleHashH(append(level1[:], right[:]...))
+
+	proof := ClaimProof{
+		Pairs:              []ProofPair{{Odd: true, Hash: left.String()}, {Odd: false, Hash: right.String()}},
+		TxID:               txid.String(),
+		Nout:               &nout,
+		LastTakeoverHeight: 7,
+	}
+	if err := proof.Verify("anything", root); err != nil {
+		t.Fatal(err)
+	}
+
+	proof.Pairs[0].Odd = false
+	if err := proof.Verify("anything", root); err == nil {
+		t.Error("proof verified with a sibling on the wrong side")
+	}
+}
+
+func TestClaimTrieRootFromHeader(t *testing.T) {
+	header := make([]byte, blockHeaderSize)
+	want := chainhash.Hash{0xab, 0xcd}
+	copy(header[68:], want[:])
+
+	root, err := claimTrieRootFromHeader(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *root != want {
+		t.Errorf("expected %s, got %s", want, root)
+	}
+
+	if _, err := claimTrieRootFromHeader(header[:80]); err == nil {
+		t.Error("expected an error for a bitcoin-sized header")
+	}
+}
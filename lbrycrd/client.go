@@ -1,7 +1,6 @@
 package lbrycrd
 
 import (
-	"encoding/hex"
 	"net/url"
 	"os"
 	"strconv"
@@ -269,6 +268,9 @@ const (
 	ClaimName ScriptType = iota
 	ClaimUpdate
 	ClaimSupport
+	PayToPubKeyHash
+	PayToScriptHash
+	NonStandard
 )
 
 func (c *Client) AddStakeToTx(rawTx *wire.MsgTx, claim *c.StakeHelper, name string, claimAmount float64, scriptType ScriptType) error {
@@ -288,7 +290,7 @@ func (c *Client) AddStakeToTx(rawTx *wire.MsgTx, claim *c.StakeHelper, name stri
 	}
 	var claimID string
 	if len(claim.ClaimID) > 0 {
-		claimID = hex.EncodeToString(rev(claim.ClaimID))
+		claimID = ClaimIDFromBytes(claim.ClaimID)
 	}
 	var script []byte
 	switch scriptType {
@@ -303,7 +305,7 @@ func (c *Client) AddStakeToTx(rawTx *wire.MsgTx, claim *c.StakeHelper, name stri
 			return errors.Err(err)
 		}
 	case ClaimSupport:
-		script, err = getUpdateClaimPayoutScript(name, claimID, value, address)
+		script, err = getClaimSupportPayoutScript(name, claimID, address)
 		if err != nil {
 			return errors.Err(err)
 		}
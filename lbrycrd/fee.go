@@ -0,0 +1,204 @@
+package lbrycrd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+const (
+	// the size of a signature script that spends a p2pkh output: a push of a 72 byte signature and a 33 byte key
+	p2pkhSigScriptSize = 1 + 72 + 1 + 33
+	// the size of a p2pkh output script, for the change output while the fee is worked out
+	p2pkhScriptSize = 25
+	// change smaller than this is added to the fee instead of making an output no one will ever spend
+	dustThreshold = 1000
+
+	// segwit counts the bytes that aren't witness data this many times
+	witnessScaleFactor = 4
+
+	// rbfSequence is the highest sequence number that signals BIP-125 replaceability
+	rbfSequence = wire.MaxTxInSequenceNum - 2
+	// incrementalRelayFeeRate is lbrycrd's default -incrementalrelayfee, in deweys per virtual byte. BIP-125 says a
+	// replacement has to pay at least this much more than the transaction it replaces, for its own size
+	incrementalRelayFeeRate = 1
+)
+
+// FeeRate picks the fee rate for a transaction, in deweys per virtual byte
+type FeeRate interface {
+	FeeRate(c *Client) (float64, error)
+}
+
+// StaticFeeRate always pays the same fee rate, in deweys per virtual byte
+type StaticFeeRate float64
+
+func (f StaticFeeRate) FeeRate(c *Client) (float64, error) { return float64(f), nil }
+
+// FeeRateFunc lets the caller pick the fee rate however they like
+type FeeRateFunc func() (float64, error)
+
+func (f FeeRateFunc) FeeRate(c *Client) (float64, error) { return f() }
+
+// SmartFeeRate asks lbrycrd (estimatesmartfee) for the fee rate that should confirm within ConfTarget blocks. lbrycrd
+// can't always estimate, usually because it hasn't seen enough transactions, and then Fallback is used if it's set.
+type SmartFeeRate struct {
+	ConfTarget int64
+	Fallback   FeeRate
+}
+
+func (f SmartFeeRate) FeeRate(c *Client) (float64, error) {
+	target, err := json.Marshal(f.ConfTarget)
+	if err != nil {
+		return 0, errors.Err(err)
+	}
+	res, err := c.RawRequest("estimatesmartfee", []json.RawMessage{target})
+	if err != nil {
+		return 0, errors.Err(err)
+	}
+
+	var estimate struct {
+		FeeRate *float64 `json:"feerate"` // in LBC per kvB
+		Errors  []string `json:"errors"`
+	}
+	err = json.Unmarshal(res, &estimate)
+	if err != nil {
+		return 0, errors.Err(err)
+	}
+	if estimate.FeeRate == nil || *estimate.FeeRate <= 0 {
+		if f.Fallback != nil {
+			return f.Fallback.FeeRate(c)
+		}
+		return 0, errors.Err("lbrycrd could not estimate a fee: %v", estimate.Errors)
+	}
+	return *estimate.FeeRate * btcutil.SatoshiPerBitcoin / 1000, nil
+}
+
+// EstimateVSize returns the virtual size a transaction will have once it's signed. Inputs without a signature script
+// are assumed to spend p2pkh outputs, which the claim, update, and support scripts built here all pay to.
+func EstimateVSize(tx *wire.MsgTx) int64 {
+	stripped := tx.SerializeSizeStripped()
+	total := tx.SerializeSize()
+	for _, in := range tx.TxIn {
+		if len(in.SignatureScript) == 0 && len(in.Witness) == 0 {
+			// the length of an empty script is already counted in a single byte, and so is this one
+			stripped += p2pkhSigScriptSize
+			total += p2pkhSigScriptSize
+		}
+	}
+	weight := stripped*(witnessScaleFactor-1) + total
+	return int64((weight + witnessScaleFactor - 1) / witnessScaleFactor)
+}
+
+// feeFor returns the fee for a transaction of the given virtual size
+func feeFor(rate float64, vsize int64) btcutil.Amount {
+	return btcutil.Amount(math.Ceil(rate * float64(vsize)))
+}
+
+// BumpFee replaces a transaction that signals replaceability (see TxOptions.ReplaceByFee) with one that pays the new
+// fee rate, taking the extra fee out of the change output at changeNout (see TxResult.ChangeNout). The claim stays the
+// same, and so does its signature, since that only covers the first input. Only the PrivateKeys in opts are used; the
+// rest of the options don't apply. The inputs must be in the mempool or lbrycrd must run with -txindex so their values
+// can be looked up.
+func (c *Client) BumpFee(txid *chainhash.Hash, changeNout int, feeRate FeeRate, opts *TxOptions) (*chainhash.Hash, error) {
+	orig, err := c.GetRawTransaction(txid)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	tx := orig.MsgTx().Copy()
+
+	var totalIn btcutil.Amount
+	replaceable := false
+	for _, in := range tx.TxIn {
+		if in.Sequence <= rbfSequence {
+			replaceable = true
+		}
+		prev, err := c.prevOut(in.PreviousOutPoint)
+		if err != nil {
+			return nil, err
+		}
+		totalIn += btcutil.Amount(prev.Value)
+		in.SignatureScript = nil
+		in.Witness = nil
+	}
+	if !replaceable {
+		return nil, errors.Err("transaction %s does not signal replaceability", txid)
+	}
+
+	rate, err := feeRate.FeeRate(c)
+	if err != nil {
+		return nil, err
+	}
+	err = bumpTxFee(tx, totalIn, changeNout, rate)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.signAndSend(tx, opts)
+}
+
+// bumpTxFee makes tx, an unsigned copy of the transaction being replaced whose inputs spend totalIn, pay the fee rate.
+// The extra fee comes out of the output at changeNout. It fails if the new fee isn't enough for nodes to accept the
+// replacement (BIP-125 rules 3 and 4), or if the change can't cover it.
+func bumpTxFee(tx *wire.MsgTx, totalIn btcutil.Amount, changeNout int, rate float64) error {
+	if changeNout < 0 || changeNout >= len(tx.TxOut) {
+		return errors.Err("transaction has no output %d to pay the higher fee with", changeNout)
+	}
+	change := tx.TxOut[changeNout]
+	if script, err := ParseClaimScript(change.PkScript); err != nil {
+		return err
+	} else if script.Type == ClaimName || script.Type == ClaimUpdate || script.Type == ClaimSupport {
+		return errors.Err("output %d is a claim or support, not change", changeNout)
+	}
+
+	var totalOut btcutil.Amount
+	for _, out := range tx.TxOut {
+		totalOut += btcutil.Amount(out.Value)
+	}
+	oldFee := totalIn - totalOut
+	vsize := EstimateVSize(tx)
+	newFee := feeFor(rate, vsize)
+	if minFee := oldFee + feeFor(incrementalRelayFeeRate, vsize); newFee < minFee {
+		return errors.Err("new fee %s is less than %s, the current fee plus the incremental relay fee", newFee, minFee)
+	}
+
+	if btcutil.Amount(change.Value)-(newFee-oldFee) < dustThreshold {
+		return errors.Err("not enough change to pay the higher fee")
+	}
+	change.Value -= int64(newFee - oldFee)
+	return nil
+}
+
+// prevOut returns the output that an input spends. It's looked up in the utxo set first, and in the transaction that
+// made it if it's already spent, which needs -txindex unless that transaction is in the mempool.
+func (c *Client) prevOut(outpoint wire.OutPoint) (*wire.TxOut, error) {
+	out, err := c.GetTxOut(&outpoint.Hash, outpoint.Index, false)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	if out != nil {
+		value, err := btcutil.NewAmount(out.Value)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		script, err := hex.DecodeString(out.ScriptPubKey.Hex)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		return wire.NewTxOut(int64(value), script), nil
+	}
+
+	prevTx, err := c.GetRawTransaction(&outpoint.Hash)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	if int(outpoint.Index) >= len(prevTx.MsgTx().TxOut) {
+		return nil, errors.Err("output %s does not exist", outpoint.String())
+	}
+	return prevTx.MsgTx().TxOut[outpoint.Index], nil
+}
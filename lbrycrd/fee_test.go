@@ -0,0 +1,191 @@
+package lbrycrd
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+func TestEstimateVSize(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkHash, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(key.PubKey().SerializeCompressed()), &MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevScript, err := txscript.PayToAddrScript(pkHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := testClaimTx(t)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{2}, 1), nil, nil))
+	estimate := EstimateVSize(tx)
+
+	for i := range tx.TxIn {
+		sigScript, err := txscript.SignatureScript(tx, i, prevScript, txscript.SigHashAll, key, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.TxIn[i].SignatureScript = sigScript
+	}
+	actual := int64(tx.SerializeSize())
+
+	// signatures are 71 or 72 bytes, so the estimate can be over by a byte per input, but never under
+	if estimate < actual || estimate > actual+int64(len(tx.TxIn)) {
+		t.Errorf("estimated %d bytes, signed transaction is %d", estimate, actual)
+	}
+	if EstimateVSize(tx) != actual {
+		t.Errorf("expected a signed transaction's size to be exact, got %d instead of %d", EstimateVSize(tx), actual)
+	}
+}
+
+func testTxOptions(t *testing.T, unspent ...float64) *TxOptions {
+	address, err := DecodeAddress(testPayoutAddress, &MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &TxOptions{PayoutAddress: address, ChangeAddress: address}
+	for i, amount := range unspent {
+		hash := chainhash.Hash{byte(i + 1)}
+		opts.Unspent = append(opts.Unspent, btcjson.ListUnspentResult{
+			TxID:      hash.String(),
+			Amount:    amount,
+			Spendable: true,
+		})
+	}
+	return opts
+}
+
+func supportScript(name, claimID string) func([]byte) ([]byte, error) {
+	return func(payout []byte) ([]byte, error) {
+		return claimSupportScript(name, claimID, payout)
+	}
+}
+
+func TestBuildClaimTx_FeeRate(t *testing.T) {
+	client := &Client{}
+	opts := testTxOptions(t, 0.5, 0.6, 2)
+	opts.FeeRate = StaticFeeRate(100)
+	opts.ReplaceByFee = true
+
+	u, err := client.buildClaimTx(1, nil, opts, supportScript("test", testClaimID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := u.Tx
+	if len(tx.TxIn) != 2 {
+		t.Fatalf("expected 2 inputs, got %d", len(tx.TxIn))
+	}
+	if len(tx.TxOut) != 2 {
+		t.Fatalf("expected a support and a change output, got %d outputs", len(tx.TxOut))
+	}
+	for _, in := range tx.TxIn {
+		if in.Sequence != rbfSequence {
+			t.Errorf("expected sequence %x, got %x", rbfSequence, in.Sequence)
+		}
+	}
+
+	fee := btcutil.Amount(110000000) - btcutil.Amount(tx.TxOut[0].Value) - btcutil.Amount(tx.TxOut[1].Value)
+	if expected := feeFor(100, EstimateVSize(tx)); fee != expected {
+		t.Errorf("expected a fee of %s, got %s", expected, fee)
+	}
+}
+
+func TestBuildClaimTx_FeeNeedsAnotherInput(t *testing.T) {
+	client := &Client{}
+	// the first input covers the amount, but not the fee
+	opts := testTxOptions(t, 1.00001, 1)
+	opts.FeeRate = StaticFeeRate(1000)
+
+	u, err := client.buildClaimTx(1, nil, opts, supportScript("test", testClaimID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := u.Tx
+	if len(tx.TxIn) != 2 {
+		t.Errorf("expected 2 inputs, got %d", len(tx.TxIn))
+	}
+	if tx.TxIn[0].Sequence != wire.MaxTxInSequenceNum {
+		t.Error("expected the transaction to not be replaceable")
+	}
+}
+
+func TestBuildClaimTx_DustChange(t *testing.T) {
+	client := &Client{}
+	opts := testTxOptions(t, 1.001)
+	opts.Fee = 0.00099999
+
+	u, err := client.buildClaimTx(1, nil, opts, supportScript("test", testClaimID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := u.Tx
+	if len(tx.TxOut) != 1 {
+		t.Errorf("expected dust change to go to the fee, got %d outputs", len(tx.TxOut))
+	}
+}
+
+func TestBuildClaimTx_InsufficientFunds(t *testing.T) {
+	client := &Client{}
+	opts := testTxOptions(t, 0.5)
+	if _, err := client.buildClaimTx(1, nil, opts, supportScript("test", testClaimID), nil); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestBumpTxFee(t *testing.T) {
+	client := &Client{}
+	opts := testTxOptions(t, 1.5)
+	opts.FeeRate = StaticFeeRate(10)
+	opts.ReplaceByFee = true
+	u, err := client.buildClaimTx(1, nil, opts, supportScript("test", testClaimID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	totalIn := btcutil.Amount(150000000)
+	fee := func(tx *wire.MsgTx) btcutil.Amount {
+		return totalIn - btcutil.Amount(tx.TxOut[0].Value) - btcutil.Amount(tx.TxOut[1].Value)
+	}
+	oldFee := fee(u.Tx)
+	vsize := EstimateVSize(u.Tx)
+	minFee := oldFee + feeFor(incrementalRelayFeeRate, vsize)
+
+	// exactly the minimum BIP-125 allows
+	tx := u.Tx.Copy()
+	if err := bumpTxFee(tx, totalIn, 1, float64(minFee)/float64(vsize)); err != nil {
+		t.Fatal(err)
+	}
+	if fee(tx) != minFee {
+		t.Errorf("expected a fee of %s, got %s", minFee, fee(tx))
+	}
+	if tx.TxOut[0].Value != u.Tx.TxOut[0].Value {
+		t.Error("expected the support amount to stay the same")
+	}
+
+	// a dewey less than the minimum
+	tx = u.Tx.Copy()
+	if err := bumpTxFee(tx, totalIn, 1, (float64(minFee)-1.5)/float64(vsize)); err == nil {
+		t.Error("expected a fee below the incremental relay fee to be refused")
+	}
+	if err := bumpTxFee(tx, totalIn, 1, 10); err == nil {
+		t.Error("expected the same fee rate to be refused")
+	}
+
+	for _, nout := range []int{claimNout, 2, -1} {
+		if err := bumpTxFee(u.Tx.Copy(), totalIn, nout, 100); err == nil {
+			t.Errorf("expected output %d to be refused as change", nout)
+		}
+	}
+
+	if err := bumpTxFee(u.Tx.Copy(), totalIn, 1, 1e6); err == nil {
+		t.Error("expected an error when the change can't cover the fee")
+	}
+}
@@ -0,0 +1,174 @@
+package lbrycrd
+
+import (
+	"bytes"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// P2SHPayout is a p2sh address for claims and supports to pay to, and the script that spends from it. Put Address in
+// TxOptions.PayoutAddress and the claim script is the claim opcodes followed by a p2sh script. Keep RedeemScript,
+// since the output can't be spent (to update or abandon the claim) without it.
+type P2SHPayout struct {
+	Address      *btcutil.AddressScriptHash
+	RedeemScript []byte
+}
+
+// Matches returns whether an output script, with or without claim opcodes, pays to p
+func (p *P2SHPayout) Matches(pkScript []byte) bool {
+	script, err := ParseClaimScript(pkScript)
+	if err != nil {
+		return false
+	}
+	expected, err := txscript.PayToAddrScript(p.Address)
+	return err == nil && bytes.Equal(script.PayoutScript, expected)
+}
+
+// MultisigPayout returns a payout that needs required of the keys to sign to spend it, so a claim can be controlled by
+// several people. Spend it with SignMultisigInput and MultisigSigScript.
+func MultisigPayout(required int, pubKeys []*btcec.PublicKey, chainParams *chaincfg.Params) (*P2SHPayout, error) {
+	if required < 1 || required > len(pubKeys) {
+		return nil, errors.Err("need between 1 and %d signatures, got %d", len(pubKeys), required)
+	}
+	if len(pubKeys) > txscript.MaxPubKeysPerMultiSig {
+		return nil, errors.Err("at most %d keys are allowed, got %d", txscript.MaxPubKeysPerMultiSig, len(pubKeys))
+	}
+
+	addresses := make([]*btcutil.AddressPubKey, len(pubKeys))
+	for i, key := range pubKeys {
+		address, err := btcutil.NewAddressPubKey(key.SerializeCompressed(), chainParams)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		addresses[i] = address
+	}
+	redeemScript, err := txscript.MultiSigScript(addresses, required)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return newP2SHPayout(redeemScript, chainParams)
+}
+
+// P2WPKHPayout returns a payout to a segwit key hash, nested in p2sh so it can follow the claim opcodes (a witness
+// program only counts as one when it's the whole output script). Spend it with SignP2WPKHInput.
+func P2WPKHPayout(pubKey *btcec.PublicKey, chainParams *chaincfg.Params) (*P2SHPayout, error) {
+	redeemScript, err := p2wpkhRedeemScript(pubKey)
+	if err != nil {
+		return nil, err
+	}
+	return newP2SHPayout(redeemScript, chainParams)
+}
+
+func newP2SHPayout(redeemScript []byte, chainParams *chaincfg.Params) (*P2SHPayout, error) {
+	address, err := btcutil.NewAddressScriptHash(redeemScript, chainParams)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return &P2SHPayout{Address: address, RedeemScript: redeemScript}, nil
+}
+
+// OP_0 <hash160(pubkey)>
+func p2wpkhRedeemScript(pubKey *btcec.PublicKey) ([]byte, error) {
+	script, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_0).
+		AddData(btcutil.Hash160(pubKey.SerializeCompressed())).
+		Script()
+	return script, errors.Err(err)
+}
+
+// SignMultisigInput returns key's signature for input idx of tx, which spends a MultisigPayout output, plain or with
+// claim opcodes in front. Each key holder can sign separately, and MultisigSigScript puts the signatures together.
+func SignMultisigInput(tx *wire.MsgTx, idx int, redeemScript []byte, key *btcec.PrivateKey) ([]byte, error) {
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, errors.Err("transaction has no input %d", idx)
+	}
+	sig, err := txscript.RawTxInSignature(tx, idx, redeemScript, txscript.SigHashAll, key)
+	return sig, errors.Err(err)
+}
+
+// MultisigSigScript makes the signature script for input idx of tx from signatures made with SignMultisigInput. The
+// signatures can be in any order, and are checked against the keys in the redeem script. Extra signatures are dropped.
+func MultisigSigScript(tx *wire.MsgTx, idx int, redeemScript []byte, sigs [][]byte) ([]byte, error) {
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, errors.Err("transaction has no input %d", idx)
+	}
+	class, addresses, required, err := txscript.ExtractPkScriptAddrs(redeemScript, &MainNetParams)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	if class != txscript.MultiSigTy {
+		return nil, errors.Err("redeem script is not a multisig script")
+	}
+	hash, err := txscript.CalcSignatureHash(redeemScript, txscript.SigHashAll, tx, idx)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+
+	// CHECKMULTISIG needs the signatures in the same order as the keys
+	ordered := make([][]byte, len(addresses))
+	for _, sig := range sigs {
+		if len(sig) == 0 || txscript.SigHashType(sig[len(sig)-1]) != txscript.SigHashAll {
+			return nil, errors.Err("signature is not a SIGHASH_ALL signature")
+		}
+		parsed, err := btcec.ParseDERSignature(sig[:len(sig)-1], btcec.S256())
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		found := false
+		for i, address := range addresses {
+			if parsed.Verify(hash, address.(*btcutil.AddressPubKey).PubKey()) {
+				ordered[i] = sig
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.Err("signature does not match any key in the redeem script")
+		}
+	}
+
+	//OP_0 <sig>... <redeem script>. the OP_0 is for the extra item that CHECKMULTISIG pops
+	builder := txscript.NewScriptBuilder().AddOp(txscript.OP_0)
+	count := 0
+	for _, sig := range ordered {
+		if sig != nil && count < required {
+			builder.AddData(sig)
+			count++
+		}
+	}
+	if count < required {
+		return nil, errors.Err("need %d signatures, got %d", required, count)
+	}
+	sigScript, err := builder.AddData(redeemScript).Script()
+	return sigScript, errors.Err(err)
+}
+
+// SignP2WPKHInput signs input idx of tx, which spends a P2WPKHPayout output of amount deweys, plain or with claim
+// opcodes in front
+func SignP2WPKHInput(tx *wire.MsgTx, idx int, amount int64, key *btcec.PrivateKey) error {
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return errors.Err("transaction has no input %d", idx)
+	}
+	redeemScript, err := p2wpkhRedeemScript(key.PubKey())
+	if err != nil {
+		return err
+	}
+	witness, err := txscript.WitnessSignature(tx, txscript.NewTxSigHashes(tx), idx, amount, redeemScript,
+		txscript.SigHashAll, key, true)
+	if err != nil {
+		return errors.Err(err)
+	}
+	sigScript, err := txscript.NewScriptBuilder().AddData(redeemScript).Script()
+	if err != nil {
+		return errors.Err(err)
+	}
+	tx.TxIn[idx].SignatureScript = sigScript
+	tx.TxIn[idx].Witness = witness
+	return nil
+}
@@ -0,0 +1,140 @@
+package lbrycrd
+
+import (
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func newTestKeys(t *testing.T, n int) []*btcec.PrivateKey {
+	keys := make([]*btcec.PrivateKey, n)
+	for i := range keys {
+		key, err := btcec.NewPrivateKey(btcec.S256())
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+// spendClaim makes a claim that pays to payout, and a transaction that spends it
+func spendClaim(t *testing.T, payout *P2SHPayout, amount int64) ([]byte, *wire.MsgTx) {
+	payoutScript, err := txscript.PayToAddrScript(payout.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkScript, err := claimNameScript("test", []byte("value"), payoutScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !payout.Matches(pkScript) {
+		t.Fatal("claim script does not pay to the payout")
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(amount-1000, payoutScript))
+	return pkScript, tx
+}
+
+// verifyInput runs the input's scripts the way lbrycrd does, with the claim opcodes stripped
+func verifyInput(t *testing.T, pkScript []byte, tx *wire.MsgTx, amount int64) error {
+	script, err := ParseClaimScript(pkScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flags := txscript.ScriptBip16 | txscript.ScriptVerifyWitness
+	vm, err := txscript.NewEngine(script.PayoutScript, tx, 0, flags, nil, nil, amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vm.Execute()
+}
+
+func TestMultisigPayout(t *testing.T) {
+	keys := newTestKeys(t, 3)
+	payout, err := MultisigPayout(2, []*btcec.PublicKey{keys[0].PubKey(), keys[1].PubKey(), keys[2].PubKey()}, &MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := payout.Address.EncodeAddress()
+	if address[0] != 'r' {
+		t.Errorf("expected an lbry p2sh address, got %s", address)
+	}
+
+	pkScript, tx := spendClaim(t, payout, 100000)
+	parsed, err := ParseClaimScript(pkScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Type != ClaimName || payoutScriptType(parsed.PayoutScript) != PayToScriptHash {
+		t.Errorf("expected a claim paying to p2sh, got %+v", parsed)
+	}
+
+	// the key holders sign separately, in any order
+	sig2, err := SignMultisigInput(tx, 0, payout.RedeemScript, keys[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig0, err := SignMultisigInput(tx, 0, payout.RedeemScript, keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := MultisigSigScript(tx, 0, payout.RedeemScript, [][]byte{sig2}); err == nil {
+		t.Error("expected an error with too few signatures")
+	}
+	other := newTestKeys(t, 1)[0]
+	sigOther, err := SignMultisigInput(tx, 0, payout.RedeemScript, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MultisigSigScript(tx, 0, payout.RedeemScript, [][]byte{sig2, sigOther}); err == nil {
+		t.Error("expected an error with a signature from another key")
+	}
+
+	tx.TxIn[0].SignatureScript, err = MultisigSigScript(tx, 0, payout.RedeemScript, [][]byte{sig2, sig0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyInput(t, pkScript, tx, 100000); err != nil {
+		t.Errorf("multisig spend is not valid: %v", err)
+	}
+}
+
+func TestMultisigPayout_Invalid(t *testing.T) {
+	keys := newTestKeys(t, 2)
+	pubKeys := []*btcec.PublicKey{keys[0].PubKey(), keys[1].PubKey()}
+	for _, required := range []int{0, 3} {
+		if _, err := MultisigPayout(required, pubKeys, &MainNetParams); err == nil {
+			t.Errorf("expected an error for %d of %d", required, len(pubKeys))
+		}
+	}
+}
+
+func TestP2WPKHPayout(t *testing.T) {
+	key := newTestKeys(t, 1)[0]
+	payout, err := P2WPKHPayout(key.PubKey(), &MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	amount := int64(100000)
+	pkScript, tx := spendClaim(t, payout, amount)
+	err = SignP2WPKHInput(tx, 0, amount, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyInput(t, pkScript, tx, amount); err != nil {
+		t.Errorf("p2wpkh spend is not valid: %v", err)
+	}
+
+	// the witness signature commits to the amount
+	if err := verifyInput(t, pkScript, tx, amount+1); err == nil {
+		t.Error("expected the spend to fail with the wrong amount")
+	}
+}
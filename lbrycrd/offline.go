@@ -0,0 +1,213 @@
+package lbrycrd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	c "github.com/lbryio/lbry.go/v2/schema/stake"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+const unsignedTxVersion = 1
+
+// UnsignedTx is a claim, update, or support transaction that hasn't been signed yet. It has everything needed to sign
+// it without a connection to lbrycrd, so it can be serialized with json, signed with SignClaimTx on a machine that
+// holds the keys and is never online, and then sent with BroadcastClaimTx.
+type UnsignedTx struct {
+	Tx *wire.MsgTx
+	// the outputs that the inputs spend, in the same order as the inputs
+	PrevOuts []*wire.TxOut
+	// if set, the claim has a placeholder signature and has to be signed by this channel
+	ChannelClaimID string
+}
+
+type unsignedTxJSON struct {
+	Version        int           `json:"version"`
+	Tx             string        `json:"tx"`
+	PrevOuts       []prevOutJSON `json:"prev_outs"`
+	ChannelClaimID string        `json:"channel_claim_id,omitempty"`
+}
+
+type prevOutJSON struct {
+	Value  int64  `json:"value"`
+	Script string `json:"script"`
+}
+
+func (u *UnsignedTx) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	err := u.Tx.Serialize(&buf)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+
+	j := unsignedTxJSON{
+		Version:        unsignedTxVersion,
+		Tx:             hex.EncodeToString(buf.Bytes()),
+		ChannelClaimID: u.ChannelClaimID,
+	}
+	for _, prev := range u.PrevOuts {
+		j.PrevOuts = append(j.PrevOuts, prevOutJSON{Value: prev.Value, Script: hex.EncodeToString(prev.PkScript)})
+	}
+	return json.Marshal(j)
+}
+
+func (u *UnsignedTx) UnmarshalJSON(b []byte) error {
+	var j unsignedTxJSON
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return errors.Err(err)
+	}
+	if j.Version != unsignedTxVersion {
+		return errors.Err("unsigned transaction version %d is not supported", j.Version)
+	}
+
+	txBytes, err := hex.DecodeString(j.Tx)
+	if err != nil {
+		return errors.Err(err)
+	}
+	tx := &wire.MsgTx{}
+	err = tx.Deserialize(bytes.NewReader(txBytes))
+	if err != nil {
+		return errors.Err(err)
+	}
+	if len(j.PrevOuts) != len(tx.TxIn) {
+		return errors.Err("transaction has %d inputs but %d previous outputs", len(tx.TxIn), len(j.PrevOuts))
+	}
+
+	u.Tx = tx
+	u.ChannelClaimID = j.ChannelClaimID
+	u.PrevOuts = nil
+	for _, prev := range j.PrevOuts {
+		script, err := hex.DecodeString(prev.Script)
+		if err != nil {
+			return errors.Err(err)
+		}
+		u.PrevOuts = append(u.PrevOuts, wire.NewTxOut(prev.Value, script))
+	}
+	return nil
+}
+
+// SignClaimTx signs the claim with channelKey if it needs a channel signature, and signs each input with whichever of
+// privKeys it pays to. Inputs can spend p2pkh outputs, and claims or supports that pay to p2pkh. The UnsignedTx isn't
+// changed.
+func (u *UnsignedTx) SignClaimTx(privKeys []*btcec.PrivateKey, channelKey *btcec.PrivateKey) (*wire.MsgTx, error) {
+	if len(u.PrevOuts) != len(u.Tx.TxIn) {
+		return nil, errors.Err("transaction has %d inputs but %d previous outputs", len(u.Tx.TxIn), len(u.PrevOuts))
+	}
+	tx := u.Tx.Copy()
+
+	if u.ChannelClaimID != "" {
+		if channelKey == nil {
+			return nil, errors.Err("the claim has to be signed by channel %s", u.ChannelClaimID)
+		}
+		err := signClaimOutput(tx, u.ChannelClaimID, channelKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, prev := range u.PrevOuts {
+		payout, key, compressed, err := keyForScript(prev.PkScript, privKeys)
+		if err != nil {
+			return nil, errors.Prefix("input "+u.Tx.TxIn[i].PreviousOutPoint.String(), err)
+		}
+		// lbrycrd strips the claim opcodes before running the script, so the signature only covers the payout
+		sigScript, err := txscript.SignatureScript(tx, i, payout, txscript.SigHashAll, key, compressed)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		tx.TxIn[i].SignatureScript = sigScript
+	}
+
+	return tx, nil
+}
+
+// BroadcastClaimTx sends a transaction signed with SignClaimTx
+func (c *Client) BroadcastClaimTx(tx *wire.MsgTx) (*TxResult, error) {
+	txid, err := c.SendRawTransaction(tx, false)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return claimTxResult(txid, tx)
+}
+
+// placeholderSignature gives a claim a signature of the right size, so the transaction's size and fee don't change
+// when it's signed for real
+func placeholderSignature(claim *c.StakeHelper, channelClaimID string) error {
+	claimIDBytes, err := ClaimIDToBytes(channelClaimID)
+	if err != nil {
+		return err
+	}
+	claim.Version = c.WithSig
+	claim.ClaimID = claimIDBytes
+	claim.Signature = make([]byte, 64)
+	return nil
+}
+
+// signClaimOutput replaces the placeholder signature of the claim in the claim output with a real one
+func signClaimOutput(tx *wire.MsgTx, channelClaimID string, channelKey *btcec.PrivateKey) error {
+	out := tx.TxOut[claimNout]
+	script, err := ParseClaimScript(out.PkScript)
+	if err != nil {
+		return err
+	}
+	if script.Type != ClaimName && script.Type != ClaimUpdate {
+		return errors.Err("output %d is not a claim", claimNout)
+	}
+
+	claim, err := c.DecodeClaimBytes(script.Value, LbrycrdMain)
+	if err != nil {
+		return err
+	}
+	hash, err := outpointHash(tx.TxIn[0].PreviousOutPoint)
+	if err != nil {
+		return err
+	}
+	err = claim.Sign(*channelKey, channelClaimID, hash, LbrycrdMain)
+	if err != nil {
+		return err
+	}
+	value, err := claim.CompileValue()
+	if err != nil {
+		return err
+	}
+
+	if script.Type == ClaimName {
+		out.PkScript, err = claimNameScript(script.Name, value, script.PayoutScript)
+	} else {
+		out.PkScript, err = updateClaimScript(script.Name, script.ClaimID, value, script.PayoutScript)
+	}
+	return err
+}
+
+// keyForScript finds the key that can spend an output, and returns it with the output's payout script
+func keyForScript(pkScript []byte, privKeys []*btcec.PrivateKey) ([]byte, *btcec.PrivateKey, bool, error) {
+	script, err := ParseClaimScript(pkScript)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if payoutScriptType(script.PayoutScript) != PayToPubKeyHash {
+		return nil, nil, false, errors.Err("only p2pkh outputs can be signed")
+	}
+
+	_, addresses, _, err := txscript.ExtractPkScriptAddrs(script.PayoutScript, &MainNetParams)
+	if err != nil {
+		return nil, nil, false, errors.Err(err)
+	}
+	pkHash := addresses[0].ScriptAddress()
+	for _, key := range privKeys {
+		if bytes.Equal(btcutil.Hash160(key.PubKey().SerializeCompressed()), pkHash) {
+			return script.PayoutScript, key, true, nil
+		}
+		if bytes.Equal(btcutil.Hash160(key.PubKey().SerializeUncompressed()), pkHash) {
+			return script.PayoutScript, key, false, nil
+		}
+	}
+	return nil, nil, false, errors.Err("no key for %x", pkHash)
+}
@@ -0,0 +1,142 @@
+package lbrycrd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	c "github.com/lbryio/lbry.go/v2/schema/stake"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+)
+
+func TestOfflineSigning(t *testing.T) {
+	key, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	channelKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(key.PubKey().SerializeCompressed()), &MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkScript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &TxOptions{
+		FeeRate:       StaticFeeRate(10),
+		PayoutAddress: address,
+		ChangeAddress: address,
+		Channel:       &ChannelSigner{ClaimID: testClaimID},
+	}
+	for i, amount := range []float64{0.7, 0.7} {
+		opts.Unspent = append(opts.Unspent, btcjson.ListUnspentResult{
+			TxID:         chainhash.Hash{byte(i + 1)}.String(),
+			ScriptPubKey: hex.EncodeToString(pkScript),
+			Amount:       amount,
+			Spendable:    true,
+		})
+	}
+
+	claim, err := NewStreamClaim("title", "description")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := (&Client{}).BuildClaimName("test", claim, 1, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ChannelClaimID != testClaimID || len(u.PrevOuts) != 2 {
+		t.Fatalf("unexpected unsigned tx %+v", u)
+	}
+	estimate := EstimateVSize(u.Tx)
+
+	// the air gap
+	envelope, err := json.Marshal(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var offline UnsignedTx
+	err = json.Unmarshal(envelope, &offline)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := offline.SignClaimTx([]*btcec.PrivateKey{key}, nil); err == nil {
+		t.Error("expected an error signing without the channel key")
+	}
+	if _, err := offline.SignClaimTx([]*btcec.PrivateKey{channelKey}, channelKey); err == nil {
+		t.Error("expected an error signing without the input key")
+	}
+
+	signed, err := offline.SignClaimTx([]*btcec.PrivateKey{key}, channelKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := int64(signed.SerializeSize()); size > estimate || size < estimate-int64(len(signed.TxIn)) {
+		t.Errorf("signed transaction is %d bytes, but the fee was paid for %d", size, estimate)
+	}
+
+	for i, prev := range offline.PrevOuts {
+		vm, err := txscript.NewEngine(prev.PkScript, signed, i, 0, nil, nil, prev.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := vm.Execute(); err != nil {
+			t.Errorf("input %d: %v", i, err)
+		}
+	}
+
+	script, err := ParseClaimScript(signed.TxOut[claimNout].PkScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedClaim, err := c.DecodeClaimBytes(script.Value, LbrycrdMain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := outpointHash(signed.TxIn[0].PreviousOutPoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := signedClaim.ValidateSignature(channelKey.PubKey(), hash, LbrycrdMain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("claim signature is not valid")
+	}
+	valid, err = ValidateClaimSignature(signed, claimNout, channelKey.PubKey(), LbrycrdMain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !valid {
+		t.Error("ValidateClaimSignature says the claim signature is not valid")
+	}
+	valid, err = ValidateClaimSignature(signed, claimNout, key.PubKey(), LbrycrdMain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid {
+		t.Error("claim signature is valid for the wrong key")
+	}
+
+	txid := signed.TxHash()
+	result, err := claimTxResult(&txid, signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedID, _ := ClaimIDFromOutpoint(txid.String(), claimNout)
+	if result.ClaimID != expectedID {
+		t.Errorf("expected claim id %s, got %s", expectedID, result.ClaimID)
+	}
+}
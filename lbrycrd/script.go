@@ -1,45 +1,59 @@
 package lbrycrd
 
 import (
-	"encoding/hex"
+	"encoding/binary"
 
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcutil"
 )
 
 func getClaimSupportPayoutScript(name, claimid string, address btcutil.Address) ([]byte, error) {
-	//OP_SUPPORT_CLAIM <name> <claimid> OP_2DROP OP_DROP OP_DUP OP_HASH160 <address> OP_EQUALVERIFY OP_CHECKSIG
+	pkscript, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return claimSupportScript(name, claimid, pkscript)
+}
 
+func getClaimNamePayoutScript(name string, value []byte, address btcutil.Address) ([]byte, error) {
 	pkscript, err := txscript.PayToAddrScript(address)
 	if err != nil {
 		return nil, errors.Err(err)
 	}
+	return claimNameScript(name, value, pkscript)
+}
 
-	bytes, err := hex.DecodeString(claimid)
+func getUpdateClaimPayoutScript(name, claimid string, value []byte, address btcutil.Address) ([]byte, error) {
+	pkscript, err := txscript.PayToAddrScript(address)
 	if err != nil {
 		return nil, errors.Err(err)
 	}
+	return updateClaimScript(name, claimid, value, pkscript)
+}
+
+func claimSupportScript(name, claimid string, payout []byte) ([]byte, error) {
+	//OP_SUPPORT_CLAIM <name> <claimid> OP_2DROP OP_DROP <payout>
+
+	claimIDBytes, err := ClaimIDToBytes(claimid)
+	if err != nil {
+		return nil, err
+	}
 
 	return txscript.NewScriptBuilder().
 		AddOp(txscript.OP_NOP7).  //OP_SUPPORT_CLAIM
 		AddData([]byte(name)).    //<name>
-		AddData(rev(bytes)).      //<claimid>
+		AddData(claimIDBytes).    //<claimid>
 		AddOp(txscript.OP_2DROP). //OP_2DROP
 		AddOp(txscript.OP_DROP).  //OP_DROP
-		AddOps(pkscript).         //OP_DUP OP_HASH160 <address> OP_EQUALVERIFY OP_CHECKSIG
+		AddOps(payout).           //e.g. OP_DUP OP_HASH160 <address> OP_EQUALVERIFY OP_CHECKSIG
 		Script()
-
 }
 
-func getClaimNamePayoutScript(name string, value []byte, address btcutil.Address) ([]byte, error) {
-	//OP_CLAIM_NAME <name> <value> OP_2DROP OP_DROP OP_DUP OP_HASH160 <address> OP_EQUALVERIFY OP_CHECKSIG
-
-	pkscript, err := txscript.PayToAddrScript(address)
-	if err != nil {
-		return nil, errors.Err(err)
-	}
+func claimNameScript(name string, value []byte, payout []byte) ([]byte, error) {
+	//OP_CLAIM_NAME <name> <value> OP_2DROP OP_DROP <payout>
 
 	return txscript.NewScriptBuilder().
 		AddOp(txscript.OP_NOP6).  //OP_CLAIMNAME
@@ -47,30 +61,160 @@ func getClaimNamePayoutScript(name string, value []byte, address btcutil.Address
 		AddData(value).           //<value>
 		AddOp(txscript.OP_2DROP). //OP_2DROP
 		AddOp(txscript.OP_DROP).  //OP_DROP
-		AddOps(pkscript).         //OP_DUP OP_HASH160 <address> OP_EQUALVERIFY OP_CHECKSIG
+		AddOps(payout).           //e.g. OP_DUP OP_HASH160 <address> OP_EQUALVERIFY OP_CHECKSIG
 		Script()
 }
 
-func getUpdateClaimPayoutScript(name, claimid string, value []byte, address btcutil.Address) ([]byte, error) {
-	//OP_UPDATE_CLAIM <name> <claimid> <value> OP_2DROP OP_DROP OP_DUP OP_HASH160 <address> OP_EQUALVERIFY OP_CHECKSIG
+func updateClaimScript(name, claimid string, value []byte, payout []byte) ([]byte, error) {
+	//OP_UPDATE_CLAIM <name> <claimid> <value> OP_2DROP OP_DROP <payout>
 
-	pkscript, err := txscript.PayToAddrScript(address)
+	claimIDBytes, err := ClaimIDToBytes(claimid)
 	if err != nil {
-		return nil, errors.Err(err)
-	}
-
-	bytes, err := hex.DecodeString(claimid)
-	if err != nil {
-		return nil, errors.Err(err)
+		return nil, err
 	}
 
 	return txscript.NewScriptBuilder().
 		AddOp(txscript.OP_NOP8).  //OP_UPDATE_CLAIM
 		AddData([]byte(name)).    //<name>
-		AddData(rev(bytes)).      //<claimid>
+		AddData(claimIDBytes).    //<claimid>
 		AddData(value).           //<value>
 		AddOp(txscript.OP_2DROP). //OP_2DROP
 		AddOp(txscript.OP_DROP).  //OP_DROP
-		AddOps(pkscript).         //OP_DUP OP_HASH160 <address> OP_EQUALVERIFY OP_CHECKSIG
+		AddOps(payout).           //e.g. OP_DUP OP_HASH160 <address> OP_EQUALVERIFY OP_CHECKSIG
 		Script()
 }
+
+// ClaimScript is a parsed output script
+type ClaimScript struct {
+	Type ScriptType
+	Name string
+	// the claim id in display order. ParseClaimScript leaves it empty for claimname scripts, since it comes from the
+	// outpoint. NewClaimTx fills it in
+	ClaimID string
+	// the claim or support value. supports usually don't have one
+	Value []byte
+	// the script that the output pays to once the claim is spent, or the whole script for plain outputs
+	PayoutScript []byte
+}
+
+// Address returns the address that the output pays to
+func (s *ClaimScript) Address(chainParams *chaincfg.Params) (btcutil.Address, error) {
+	_, addresses, _, err := txscript.ExtractPkScriptAddrs(s.PayoutScript, chainParams)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	if len(addresses) != 1 {
+		return nil, errors.Err("script pays to %d addresses", len(addresses))
+	}
+	return addresses[0], nil
+}
+
+// ParseClaimScript parses a script made by one of the payout script builders, or a plain p2pkh or p2sh script. Any
+// other script is NonStandard.
+func ParseClaimScript(script []byte) (*ClaimScript, error) {
+	if len(script) == 0 {
+		return nil, errors.Err("empty script")
+	}
+
+	var scriptType ScriptType
+	var pushes int
+	switch script[0] {
+	case txscript.OP_NOP6: //OP_CLAIM_NAME <name> <value>
+		scriptType, pushes = ClaimName, 2
+	case txscript.OP_NOP7: //OP_SUPPORT_CLAIM <name> <claimid> [<value>]
+		scriptType, pushes = ClaimSupport, 2
+	case txscript.OP_NOP8: //OP_UPDATE_CLAIM <name> <claimid> <value>
+		scriptType, pushes = ClaimUpdate, 3
+	default:
+		return &ClaimScript{Type: payoutScriptType(script), PayoutScript: script}, nil
+	}
+
+	var data [][]byte
+	i := 1
+	for len(data) < pushes || (scriptType == ClaimSupport && len(data) == 2 && isPush(script, i)) {
+		d, next, err := readPush(script, i)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, d)
+		i = next
+	}
+
+	//OP_2DROP OP_DROP, or OP_2DROP OP_2DROP when there are an even number of pushes
+	if i+1 >= len(script) || script[i] != txscript.OP_2DROP ||
+		(script[i+1] != txscript.OP_DROP && script[i+1] != txscript.OP_2DROP) {
+		return nil, errors.Err("claim script is missing the drops after the claim data")
+	}
+	i += 2
+
+	parsed := &ClaimScript{Type: scriptType, Name: string(data[0]), PayoutScript: script[i:]}
+	switch scriptType {
+	case ClaimName:
+		parsed.Value = data[1]
+	case ClaimUpdate:
+		parsed.ClaimID = ClaimIDFromBytes(data[1])
+		parsed.Value = data[2]
+	case ClaimSupport:
+		parsed.ClaimID = ClaimIDFromBytes(data[1])
+		if len(data) > 2 {
+			parsed.Value = data[2]
+		}
+	}
+	if parsed.ClaimID != "" && len(data[1]) != 20 {
+		return nil, errors.Err("claim id must be 20 bytes, got %d", len(data[1]))
+	}
+
+	return parsed, nil
+}
+
+func payoutScriptType(script []byte) ScriptType {
+	switch txscript.GetScriptClass(script) {
+	case txscript.PubKeyHashTy:
+		return PayToPubKeyHash
+	case txscript.ScriptHashTy:
+		return PayToScriptHash
+	}
+	return NonStandard
+}
+
+func isPush(script []byte, i int) bool {
+	return i < len(script) && script[i] <= txscript.OP_PUSHDATA4
+}
+
+// readPush reads the data pushed by the opcode at i, and returns it and the index of the next opcode
+func readPush(script []byte, i int) ([]byte, int, error) {
+	if !isPush(script, i) {
+		return nil, 0, errors.Err("expected a data push at %d", i)
+	}
+
+	op := script[i]
+	i++
+	var length int
+	switch {
+	case op < txscript.OP_PUSHDATA1:
+		length = int(op)
+	case op == txscript.OP_PUSHDATA1:
+		if i+1 > len(script) {
+			return nil, 0, errors.Err("script truncated")
+		}
+		length = int(script[i])
+		i++
+	case op == txscript.OP_PUSHDATA2:
+		if i+2 > len(script) {
+			return nil, 0, errors.Err("script truncated")
+		}
+		length = int(binary.LittleEndian.Uint16(script[i:]))
+		i += 2
+	default: // OP_PUSHDATA4
+		if i+4 > len(script) {
+			return nil, 0, errors.Err("script truncated")
+		}
+		length = int(binary.LittleEndian.Uint32(script[i:]))
+		i += 4
+	}
+
+	if length < 0 || i+length > len(script) {
+		return nil, 0, errors.Err("script truncated")
+	}
+	return script[i : i+length], i + length, nil
+}
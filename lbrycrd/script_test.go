@@ -0,0 +1,119 @@
+package lbrycrd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/btcsuite/btcd/txscript"
+)
+
+const testPayoutAddress = "bMUxfQVUeDi7ActVeZJZHzHKBceai7kHha"
+const testClaimID = "589bc4845caca70977332025990b2a1807732b44"
+
+func TestParseClaimScript(t *testing.T) {
+	address, err := DecodeAddress(testPayoutAddress, &MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := bytes.Repeat([]byte{0xaa}, 300) // long enough to need OP_PUSHDATA2
+
+	claimName, err := getClaimNamePayoutScript("test", value, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	update, err := getUpdateClaimPayoutScript("test", testClaimID, value, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	support, err := getClaimSupportPayoutScript("test", testClaimID, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		script  []byte
+		typ     ScriptType
+		claimID string
+		value   []byte
+	}{
+		{claimName, ClaimName, "", value},
+		{update, ClaimUpdate, testClaimID, value},
+		{support, ClaimSupport, testClaimID, nil},
+	}
+
+	for _, test := range tests {
+		parsed, err := ParseClaimScript(test.script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.Type != test.typ {
+			t.Errorf("expected type %d, got %d", test.typ, parsed.Type)
+		}
+		if parsed.Name != "test" {
+			t.Errorf("expected name 'test', got '%s'", parsed.Name)
+		}
+		if parsed.ClaimID != test.claimID {
+			t.Errorf("expected claim id %s, got %s", test.claimID, parsed.ClaimID)
+		}
+		if !bytes.Equal(parsed.Value, test.value) {
+			t.Error("value mismatch")
+		}
+		payout, err := parsed.Address(&MainNetParams)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payout.EncodeAddress() != testPayoutAddress {
+			t.Errorf("expected payout address %s, got %s", testPayoutAddress, payout.EncodeAddress())
+		}
+	}
+}
+
+func TestParseClaimScript_Plain(t *testing.T) {
+	address, err := DecodeAddress(testPayoutAddress, &MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2pkh, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseClaimScript(p2pkh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Type != PayToPubKeyHash {
+		t.Errorf("expected p2pkh, got %d", parsed.Type)
+	}
+
+	p2sh := append([]byte{txscript.OP_HASH160, txscript.OP_DATA_20}, make([]byte, 20)...)
+	p2sh = append(p2sh, txscript.OP_EQUAL)
+	parsed, err = ParseClaimScript(p2sh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Type != PayToScriptHash {
+		t.Errorf("expected p2sh, got %d", parsed.Type)
+	}
+
+	parsed, err = ParseClaimScript([]byte{txscript.OP_RETURN})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Type != NonStandard {
+		t.Errorf("expected nonstandard, got %d", parsed.Type)
+	}
+}
+
+func TestParseClaimScript_Malformed(t *testing.T) {
+	for _, script := range [][]byte{
+		{},
+		{txscript.OP_NOP6, txscript.OP_DATA_4, 't', 'e'},
+		{txscript.OP_NOP6, txscript.OP_DATA_1, 't', txscript.OP_DATA_1, 'v'},
+		{txscript.OP_NOP6, txscript.OP_DATA_1, 't', txscript.OP_DATA_1, 'v', txscript.OP_DROP, txscript.OP_DROP},
+		{txscript.OP_NOP7, txscript.OP_DATA_1, 't', txscript.OP_DATA_1, 'c', txscript.OP_2DROP, txscript.OP_DROP},
+	} {
+		if _, err := ParseClaimScript(script); err == nil {
+			t.Errorf("expected an error parsing %x", script)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package lbrycrd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/stop"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	zmqTopicRawBlock  = "rawblock"
+	zmqTopicRawTx     = "rawtx"
+	zmqTopicHashBlock = "hashblock"
+
+	subscriberDialTimeout = 10 * time.Second
+	subscriberMinBackoff  = 1 * time.Second
+	subscriberMaxBackoff  = 1 * time.Minute
+	subscriberQueueLength = 100
+
+	// version, prev block, merkle root, claimtrie root, time, bits, nonce
+	blockHeaderLength = 4 + 3*chainhash.HashSize + 4 + 4 + 4
+)
+
+// SubscriberConfig has the zmq endpoints that lbrycrd publishes to, as set with -zmqpubrawblock, -zmqpubrawtx, and
+// -zmqpubhashblock. Endpoints look like tcp://127.0.0.1:28332. Leave an endpoint empty to not subscribe to it.
+type SubscriberConfig struct {
+	RawBlock  string
+	RawTx     string
+	HashBlock string
+}
+
+// ClaimOutput is a claim, update, or support in a transaction
+type ClaimOutput struct {
+	*ClaimScript
+	Nout int
+	// in deweys
+	Amount int64
+}
+
+// ClaimTx is a transaction with at least one claim, update, or support in it
+type ClaimTx struct {
+	Tx     *wire.MsgTx
+	Claims []ClaimOutput
+}
+
+// Block is a block from lbrycrd. lbrycrd block headers have the claimtrie root in them, which wire.BlockHeader
+// doesn't, so blocks are decoded here instead of with wire.MsgBlock.
+type Block struct {
+	Hash          chainhash.Hash
+	Header        wire.BlockHeader
+	ClaimTrieRoot chainhash.Hash
+	Transactions  []*wire.MsgTx
+	ClaimTxs      []*ClaimTx
+}
+
+// Subscriber receives new blocks and transactions from lbrycrd as they happen. It reconnects with backoff when a
+// connection drops. The channels must be read, or the subscriber stops reading from lbrycrd once they fill up.
+type Subscriber struct {
+	// new blocks, from the rawblock endpoint
+	Blocks chan *Block
+	// new transactions with claims in them, from the rawtx endpoint. transactions are sent again when they're mined
+	ClaimTxs chan *ClaimTx
+	// the hashes of new blocks, from the hashblock endpoint
+	BlockHashes chan *chainhash.Hash
+
+	conf SubscriberConfig
+	grp  *stop.Group
+}
+
+// NewSubscriber returns a subscriber for the endpoints in conf. Call Start to connect.
+func NewSubscriber(conf SubscriberConfig) *Subscriber {
+	return &Subscriber{
+		Blocks:      make(chan *Block, subscriberQueueLength),
+		ClaimTxs:    make(chan *ClaimTx, subscriberQueueLength),
+		BlockHashes: make(chan *chainhash.Hash, subscriberQueueLength),
+		conf:        conf,
+		grp:         stop.New(),
+	}
+}
+
+// Start connects to each endpoint in the background
+func (s *Subscriber) Start() error {
+	// lbrycrd can publish several topics on the same endpoint
+	topics := make(map[string][]string)
+	for topic, endpoint := range map[string]string{
+		zmqTopicRawBlock:  s.conf.RawBlock,
+		zmqTopicRawTx:     s.conf.RawTx,
+		zmqTopicHashBlock: s.conf.HashBlock,
+	} {
+		if endpoint == "" {
+			continue
+		}
+		if !strings.HasPrefix(endpoint, "tcp://") {
+			return errors.Err("only tcp endpoints are supported, got %s", endpoint)
+		}
+		addr := strings.TrimPrefix(endpoint, "tcp://")
+		topics[addr] = append(topics[addr], topic)
+	}
+	if len(topics) == 0 {
+		return errors.Err("no endpoints to subscribe to")
+	}
+
+	for addr, t := range topics {
+		s.grp.Add(1)
+		go func(addr string, topics []string) {
+			defer s.grp.Done()
+			s.subscribe(addr, topics)
+		}(addr, t)
+	}
+	return nil
+}
+
+// Shutdown disconnects and waits for everything to stop
+func (s *Subscriber) Shutdown() {
+	s.grp.StopAndWait()
+}
+
+// subscribe stays connected to the endpoint until the subscriber is shut down
+func (s *Subscriber) subscribe(addr string, topics []string) {
+	backoff := subscriberMinBackoff
+	for {
+		err := s.listen(addr, topics)
+		select {
+		case <-s.grp.Ch():
+			return
+		default:
+		}
+		log.Errorf("lbrycrd zmq %s: %s, reconnecting in %s", addr, errors.FullTrace(err), backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-s.grp.Ch():
+			return
+		}
+		backoff *= 2
+		if backoff > subscriberMaxBackoff {
+			backoff = subscriberMaxBackoff
+		}
+	}
+}
+
+// listen connects once and handles messages until the connection fails or the subscriber is shut down
+func (s *Subscriber) listen(addr string, topics []string) error {
+	conn, err := dialZMTP(addr, subscriberDialTimeout, topics...)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-s.grp.Ch():
+			conn.Close()
+		case <-done:
+			conn.Close()
+		}
+	}()
+
+	sequences := make(map[string]uint32)
+	for {
+		msg, err := conn.readMessage()
+		if err != nil {
+			return err
+		}
+		if len(msg) != 3 { // topic, body, sequence number
+			log.Warnf("lbrycrd zmq %s: ignoring message with %d parts", addr, len(msg))
+			continue
+		}
+
+		topic := string(msg[0])
+		if len(msg[2]) == 4 {
+			seq := binary.LittleEndian.Uint32(msg[2])
+			if last, ok := sequences[topic]; ok && seq != last+1 {
+				log.Warnf("lbrycrd zmq %s: missed %d %s messages", addr, seq-last-1, topic)
+			}
+			sequences[topic] = seq
+		}
+
+		err = s.handle(topic, msg[1])
+		if err != nil {
+			log.Errorf("lbrycrd zmq %s: %s", addr, errors.FullTrace(err))
+		}
+	}
+}
+
+func (s *Subscriber) handle(topic string, body []byte) error {
+	switch topic {
+	case zmqTopicRawBlock:
+		block, err := DecodeBlock(body)
+		if err != nil {
+			return err
+		}
+		select {
+		case s.Blocks <- block:
+		case <-s.grp.Ch():
+		}
+
+	case zmqTopicRawTx:
+		tx := &wire.MsgTx{}
+		err := tx.Deserialize(bytes.NewReader(body))
+		if err != nil {
+			return errors.Err(err)
+		}
+		claimTx := NewClaimTx(tx)
+		if claimTx == nil {
+			return nil
+		}
+		select {
+		case s.ClaimTxs <- claimTx:
+		case <-s.grp.Ch():
+		}
+
+	case zmqTopicHashBlock:
+		hash, err := chainhash.NewHash(body)
+		if err != nil {
+			return errors.Err(err)
+		}
+		select {
+		case s.BlockHashes <- hash:
+		case <-s.grp.Ch():
+		}
+	}
+	return nil
+}
+
+// NewClaimTx finds the claims, updates, and supports in a transaction. It returns nil if there aren't any.
+func NewClaimTx(tx *wire.MsgTx) *ClaimTx {
+	var claims []ClaimOutput
+	for nout, out := range tx.TxOut {
+		script, err := ParseClaimScript(out.PkScript)
+		if err != nil {
+			continue // lbrycrd ignores claim scripts it can't parse, so they're not claims
+		}
+		if script.Type != ClaimName && script.Type != ClaimUpdate && script.Type != ClaimSupport {
+			continue
+		}
+		if script.Type == ClaimName {
+			script.ClaimID, _ = ClaimIDFromOutpoint(tx.TxHash().String(), nout)
+		}
+		claims = append(claims, ClaimOutput{ClaimScript: script, Nout: nout, Amount: out.Value})
+	}
+
+	if len(claims) == 0 {
+		return nil
+	}
+	return &ClaimTx{Tx: tx, Claims: claims}
+}
+
+// DecodeBlock decodes a serialized lbrycrd block
+func DecodeBlock(b []byte) (*Block, error) {
+	if len(b) < blockHeaderLength {
+		return nil, errors.Err("block is too short")
+	}
+
+	block := &Block{Hash: chainhash.DoubleHashH(b[:blockHeaderLength])}
+	h := &block.Header
+	h.Version = int32(binary.LittleEndian.Uint32(b[0:]))
+	copy(h.PrevBlock[:], b[4:])
+	copy(h.MerkleRoot[:], b[36:])
+	copy(block.ClaimTrieRoot[:], b[68:])
+	h.Timestamp = time.Unix(int64(binary.LittleEndian.Uint32(b[100:])), 0)
+	h.Bits = binary.LittleEndian.Uint32(b[104:])
+	h.Nonce = binary.LittleEndian.Uint32(b[108:])
+
+	r := bytes.NewReader(b[blockHeaderLength:])
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	if count > uint64(r.Len()) { // every transaction is at least a byte
+		return nil, errors.Err("block has more transactions than bytes")
+	}
+	for i := uint64(0); i < count; i++ {
+		tx := &wire.MsgTx{}
+		err = tx.Deserialize(r)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		block.Transactions = append(block.Transactions, tx)
+		if claimTx := NewClaimTx(tx); claimTx != nil {
+			block.ClaimTxs = append(block.ClaimTxs, claimTx)
+		}
+	}
+
+	return block, nil
+}
@@ -0,0 +1,204 @@
+package lbrycrd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// fakePublisher accepts one zmq subscriber and sends it messages
+type fakePublisher struct {
+	listener net.Listener
+	topics   chan string
+	conn     chan *zmtpConn
+}
+
+func newFakePublisher(t *testing.T) *fakePublisher {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakePublisher{listener: l, topics: make(chan string, 10), conn: make(chan *zmtpConn, 1)}
+	go p.accept(t)
+	return p
+}
+
+func (p *fakePublisher) accept(t *testing.T) {
+	conn, err := p.listener.Accept()
+	if err != nil {
+		return
+	}
+	z := &zmtpConn{conn: conn}
+	greeting := make([]byte, zmtpGreetingLength)
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		t.Error(err)
+		return
+	}
+	greeting[10], greeting[11] = 3, 1
+	if _, err := conn.Write(greeting); err != nil {
+		t.Error(err)
+		return
+	}
+	z.r = bufio.NewReader(conn)
+	if _, _, err := z.readFrame(); err != nil { // their READY
+		t.Error(err)
+		return
+	}
+	ready := append([]byte{5}, "READY"...)
+	ready = append(ready, byte(len("Socket-Type")))
+	ready = append(ready, "Socket-Type"...)
+	ready = append(ready, 0, 0, 0, 3)
+	ready = append(ready, "PUB"...)
+	if err := z.writeFrame(zmtpFlagCommand, ready); err != nil {
+		t.Error(err)
+		return
+	}
+	p.conn <- z
+	for {
+		_, body, err := z.readFrame()
+		if err != nil {
+			return
+		}
+		if len(body) > 0 && body[0] == 1 {
+			p.topics <- string(body[1:])
+		}
+	}
+}
+
+func (p *fakePublisher) publish(z *zmtpConn, topic string, body []byte, seq uint32) error {
+	seqBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(seqBytes, seq)
+	if err := z.writeFrame(zmtpFlagMore, []byte(topic)); err != nil {
+		return err
+	}
+	if err := z.writeFrame(zmtpFlagMore, body); err != nil {
+		return err
+	}
+	return z.writeFrame(0, seqBytes)
+}
+
+func testClaimTx(t *testing.T) *wire.MsgTx {
+	address, err := DecodeAddress(testPayoutAddress, &MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, err := getClaimNamePayoutScript("test", []byte("value"), address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{1}, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(100000000, script))
+	tx.AddTxOut(wire.NewTxOut(5000, []byte{0x6a}))
+	return tx
+}
+
+func TestSubscriber(t *testing.T) {
+	pub := newFakePublisher(t)
+	defer pub.listener.Close()
+
+	s := NewSubscriber(SubscriberConfig{
+		RawTx:     "tcp://" + pub.listener.Addr().String(),
+		HashBlock: "tcp://" + pub.listener.Addr().String(),
+	})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown()
+
+	var z *zmtpConn
+	select {
+	case z = <-pub.conn:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscriber did not connect")
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-pub.topics:
+		case <-time.After(5 * time.Second):
+			t.Fatal("subscriber did not subscribe")
+		}
+	}
+
+	tx := testClaimTx(t)
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := pub.publish(z, zmqTopicRawTx, buf.Bytes(), 0); err != nil {
+		t.Fatal(err)
+	}
+	hash := chainhash.Hash{7}
+	if err := pub.publish(z, zmqTopicHashBlock, hash[:], 0); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case claimTx := <-s.ClaimTxs:
+		if len(claimTx.Claims) != 1 {
+			t.Fatalf("expected 1 claim, got %d", len(claimTx.Claims))
+		}
+		claim := claimTx.Claims[0]
+		expectedID, _ := ClaimIDFromOutpoint(tx.TxHash().String(), 0)
+		if claim.Name != "test" || claim.Nout != 0 || claim.Amount != 100000000 || claim.ClaimID != expectedID {
+			t.Errorf("unexpected claim %+v", claim)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no claim tx received")
+	}
+
+	select {
+	case h := <-s.BlockHashes:
+		if *h != hash {
+			t.Errorf("expected %s, got %s", hash, h)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no block hash received")
+	}
+}
+
+func TestDecodeBlock(t *testing.T) {
+	header := make([]byte, blockHeaderLength)
+	binary.LittleEndian.PutUint32(header[0:], 536870912)
+	header[4] = 0xaa  // prev block
+	header[68] = 0xbb // claimtrie root
+	binary.LittleEndian.PutUint32(header[100:], 1600000000)
+	binary.LittleEndian.PutUint32(header[108:], 42)
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	if err := wire.WriteVarInt(&buf, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := testClaimTx(t).Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := DecodeBlock(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Hash != chainhash.DoubleHashH(header) {
+		t.Error("wrong block hash")
+	}
+	if block.Header.PrevBlock[0] != 0xaa || block.ClaimTrieRoot[0] != 0xbb || block.Header.Nonce != 42 {
+		t.Errorf("header decoded wrong: %+v", block.Header)
+	}
+	if block.Header.Timestamp.Unix() != 1600000000 {
+		t.Errorf("wrong timestamp %s", block.Header.Timestamp)
+	}
+	if len(block.Transactions) != 1 || len(block.ClaimTxs) != 1 {
+		t.Errorf("expected 1 transaction with claims, got %d and %d", len(block.Transactions), len(block.ClaimTxs))
+	}
+
+	if _, err := DecodeBlock(buf.Bytes()[:blockHeaderLength+10]); err == nil {
+		t.Error("expected an error for a truncated block")
+	}
+}
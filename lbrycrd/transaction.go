@@ -0,0 +1,380 @@
+package lbrycrd
+
+import (
+	"encoding/hex"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	c "github.com/lbryio/lbry.go/v2/schema/stake"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// DefaultTxFee is the fee paid by claim transactions when the options don't set one
+const DefaultTxFee = 0.001
+
+// claimNout is the output that the claim or support goes in. change always comes after it.
+const claimNout = 0
+
+// TxOptions controls how the transaction for a claim, update, or support is built. The zero value uses the lbrycrd
+// wallet for everything.
+type TxOptions struct {
+	// the fee to pay. if this isn't set, the fee is worked out from FeeRate, and if that isn't set either, it's
+	// DefaultTxFee
+	Fee float64
+	// how much to pay per virtual byte
+	FeeRate FeeRate
+	// signal that the transaction can be replaced (BIP-125), so it can be sped up with BumpFee
+	ReplaceByFee bool
+	// the outputs that may be spent. defaults to the wallet's unspent outputs with at least one confirmation
+	Unspent []btcjson.ListUnspentResult
+	// sign the inputs with these keys instead of the wallet's keys
+	PrivateKeys []*btcutil.WIF
+	// where the claim or support is paid to, which can be a P2SHPayout address. defaults to a new wallet address
+	PayoutAddress btcutil.Address
+	// where the change goes. defaults to a new wallet change address
+	ChangeAddress btcutil.Address
+	// if set, the claim is signed by this channel
+	Channel *ChannelSigner
+}
+
+// ChannelSigner is a channel that a claim is published in. If PrivateKey is nil, the claim gets a placeholder signature
+// of the right size, so the claim can be signed offline later with UnsignedTx.SignClaimTx.
+type ChannelSigner struct {
+	ClaimID    string
+	PrivateKey *btcec.PrivateKey
+}
+
+// TxResult is what a claim transaction was broadcast as
+type TxResult struct {
+	TxID *chainhash.Hash
+	// the claim that the transaction created, updated, or supported
+	ClaimID string
+	Nout    int
+	// the change output, or -1 if the transaction has no change. BumpFee takes the higher fee out of it
+	ChangeNout int
+}
+
+// ClaimName puts a new claim for name on the blockchain
+func (c *Client) ClaimName(name string, claim *c.StakeHelper, amount float64, opts *TxOptions) (*TxResult, error) {
+	u, err := c.BuildClaimName(name, claim, amount, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.signAndBroadcast(u, opts)
+}
+
+// UpdateClaim replaces the value of a claim. claimOutpoint is the claim's current output, which the update spends.
+func (c *Client) UpdateClaim(name, claimID string, claimOutpoint wire.OutPoint, claim *c.StakeHelper, amount float64, opts *TxOptions) (*TxResult, error) {
+	u, err := c.BuildUpdateClaim(name, claimID, claimOutpoint, claim, amount, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.signAndBroadcast(u, opts)
+}
+
+// AddSupport stakes amount on an existing claim
+func (c *Client) AddSupport(name, claimID string, amount float64, opts *TxOptions) (*TxResult, error) {
+	u, err := c.BuildSupport(name, claimID, amount, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.signAndBroadcast(u, opts)
+}
+
+// BuildClaimName is like ClaimName, but returns the transaction without signing its inputs or sending it
+func (c *Client) BuildClaimName(name string, claim *c.StakeHelper, amount float64, opts *TxOptions) (*UnsignedTx, error) {
+	return c.buildClaimTx(amount, nil, opts, func(payout []byte) ([]byte, error) {
+		value, err := claim.CompileValue()
+		if err != nil {
+			return nil, err
+		}
+		return claimNameScript(name, value, payout)
+	}, claim)
+}
+
+// BuildUpdateClaim is like UpdateClaim, but returns the transaction without signing its inputs or sending it
+func (c *Client) BuildUpdateClaim(name, claimID string, claimOutpoint wire.OutPoint, claim *c.StakeHelper, amount float64, opts *TxOptions) (*UnsignedTx, error) {
+	return c.buildClaimTx(amount, &claimOutpoint, opts, func(payout []byte) ([]byte, error) {
+		value, err := claim.CompileValue()
+		if err != nil {
+			return nil, err
+		}
+		return updateClaimScript(name, claimID, value, payout)
+	}, claim)
+}
+
+// BuildSupport is like AddSupport, but returns the transaction without signing its inputs or sending it
+func (c *Client) BuildSupport(name, claimID string, amount float64, opts *TxOptions) (*UnsignedTx, error) {
+	return c.buildClaimTx(amount, nil, opts, func(payout []byte) ([]byte, error) {
+		return claimSupportScript(name, claimID, payout)
+	}, nil)
+}
+
+func (c *Client) signAndBroadcast(u *UnsignedTx, opts *TxOptions) (*TxResult, error) {
+	txid, err := c.signAndSend(u.Tx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return claimTxResult(txid, u.Tx)
+}
+
+// claimTxResult works out which claim a transaction is for from its claim output
+func claimTxResult(txid *chainhash.Hash, tx *wire.MsgTx) (*TxResult, error) {
+	if len(tx.TxOut) <= claimNout {
+		return nil, errors.Err("transaction has no claim output")
+	}
+	script, err := ParseClaimScript(tx.TxOut[claimNout].PkScript)
+	if err != nil {
+		return nil, err
+	}
+
+	claimID := script.ClaimID
+	switch script.Type {
+	case ClaimName:
+		claimID, err = ClaimIDFromOutpoint(txid.String(), claimNout)
+		if err != nil {
+			return nil, err
+		}
+	case ClaimUpdate, ClaimSupport:
+	default:
+		return nil, errors.Err("output %d is not a claim, update, or support", claimNout)
+	}
+	changeNout := -1
+	if len(tx.TxOut) > claimNout+1 {
+		changeNout = claimNout + 1
+	}
+	return &TxResult{TxID: txid, ClaimID: claimID, Nout: claimNout, ChangeNout: changeNout}, nil
+}
+
+// buildClaimTx selects the inputs to spend, signs the claim with the channel if there is one (the signature covers the
+// first input, so it can only be made once the inputs are known), and adds the claim output and the change. spend is
+// an output that must be spent as well, like the claim being updated. script makes the claim script from the payout
+// script.
+func (c *Client) buildClaimTx(amount float64, spend *wire.OutPoint, opts *TxOptions, script func(payout []byte) ([]byte, error), claim *c.StakeHelper) (*UnsignedTx, error) {
+	if opts == nil {
+		opts = &TxOptions{}
+	}
+	claimAmount, err := btcutil.NewAmount(amount)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	feeFunc, err := c.feeFunc(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	u := &UnsignedTx{Tx: tx}
+	var totalInputSpend btcutil.Amount
+	addInput := func(outpoint *wire.OutPoint, prev *wire.TxOut) {
+		in := wire.NewTxIn(outpoint, nil, nil)
+		if opts.ReplaceByFee {
+			in.Sequence = rbfSequence
+		}
+		tx.AddTxIn(in)
+		u.PrevOuts = append(u.PrevOuts, prev)
+		totalInputSpend += btcutil.Amount(prev.Value)
+	}
+
+	if spend != nil {
+		out, err := c.GetTxOut(&spend.Hash, spend.Index, true)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		if out == nil {
+			return nil, errors.Err("output %s is already spent", spend.String())
+		}
+		value, err := btcutil.NewAmount(out.Value)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		pkScript, err := hex.DecodeString(out.ScriptPubKey.Hex)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		addInput(spend, wire.NewTxOut(int64(value), pkScript))
+	}
+
+	var finder *outputFinder
+	addInputs := func(needed btcutil.Amount) error {
+		if finder == nil {
+			unspent := opts.Unspent
+			if unspent == nil {
+				var err error
+				unspent, err = c.ListUnspentMin(1)
+				if err != nil {
+					return errors.Err(err)
+				}
+			}
+			finder = newOutputFinder(unspent)
+		}
+		outputs, err := finder.nextBatch(needed.ToBTC())
+		if err != nil {
+			return err
+		}
+		if len(outputs) == 0 {
+			return errors.Err(errInsufficientFunds)
+		}
+		for _, output := range outputs {
+			hash, err := chainhash.NewHashFromStr(output.TxID)
+			if err != nil {
+				return errors.Err(err)
+			}
+			value, err := btcutil.NewAmount(output.Amount)
+			if err != nil {
+				return errors.Err(err)
+			}
+			pkScript, err := hex.DecodeString(output.ScriptPubKey)
+			if err != nil {
+				return errors.Err(err)
+			}
+			addInput(wire.NewOutPoint(hash, output.Vout), wire.NewTxOut(int64(value), pkScript))
+		}
+		return nil
+	}
+
+	// the claim needs the first input before it can be signed, so start with enough inputs for the amount
+	if needed := claimAmount + feeFunc(tx) - totalInputSpend; needed > 0 {
+		err = addInputs(needed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if claim != nil && opts.Channel != nil {
+		if claim.LegacyClaim != nil {
+			return nil, errors.Err("legacy claims cannot be signed")
+		}
+		if opts.Channel.PrivateKey == nil {
+			err = placeholderSignature(claim, opts.Channel.ClaimID)
+			if err != nil {
+				return nil, err
+			}
+			u.ChannelClaimID = opts.Channel.ClaimID
+		} else {
+			hash, err := outpointHash(tx.TxIn[0].PreviousOutPoint)
+			if err != nil {
+				return nil, err
+			}
+			err = claim.Sign(*opts.Channel.PrivateKey, opts.Channel.ClaimID, hash, LbrycrdMain)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	payout := opts.PayoutAddress
+	if payout == nil {
+		payout, err = c.GetNewAddress("")
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+	}
+	payoutScript, err := txscript.PayToAddrScript(payout)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	claimScript, err := script(payoutScript)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	tx.AddTxOut(wire.NewTxOut(int64(claimAmount), claimScript))
+
+	// now that the size is known, make sure the inputs cover the fee, counting the change output that will be added
+	feeWithChange := func() btcutil.Amount {
+		withChange := tx.Copy()
+		withChange.AddTxOut(wire.NewTxOut(0, make([]byte, p2pkhScriptSize)))
+		return feeFunc(withChange)
+	}
+	fee := feeWithChange()
+	for totalInputSpend < claimAmount+fee {
+		err = addInputs(claimAmount + fee - totalInputSpend)
+		if err != nil {
+			return nil, err
+		}
+		fee = feeWithChange()
+	}
+
+	change := totalInputSpend - claimAmount - fee
+	if change >= dustThreshold {
+		changeAddress := opts.ChangeAddress
+		if changeAddress == nil {
+			changeAddress, err = c.GetRawChangeAddress("")
+			if err != nil {
+				return nil, errors.Err(err)
+			}
+		}
+		changeScript, err := txscript.PayToAddrScript(changeAddress)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		tx.AddTxOut(wire.NewTxOut(int64(change), changeScript))
+	}
+
+	return u, nil
+}
+
+// feeFunc returns a function that gives the fee for a transaction, either the fixed fee or one from the fee rate
+func (c *Client) feeFunc(opts *TxOptions) (func(*wire.MsgTx) btcutil.Amount, error) {
+	if opts.Fee > 0 || opts.FeeRate == nil {
+		fixed := opts.Fee
+		if fixed == 0 {
+			fixed = DefaultTxFee
+		}
+		fee, err := btcutil.NewAmount(fixed)
+		if err != nil {
+			return nil, errors.Err(err)
+		}
+		return func(*wire.MsgTx) btcutil.Amount { return fee }, nil
+	}
+
+	rate, err := opts.FeeRate.FeeRate(c)
+	if err != nil {
+		return nil, err
+	}
+	return func(tx *wire.MsgTx) btcutil.Amount { return feeFor(rate, EstimateVSize(tx)) }, nil
+}
+
+// signAndSend signs the transaction with the wallet, or with the keys in the options if there are any, and broadcasts
+// it
+func (c *Client) signAndSend(tx *wire.MsgTx, opts *TxOptions) (*chainhash.Hash, error) {
+	if opts == nil || len(opts.PrivateKeys) == 0 {
+		return c.SignTxAndSend(tx)
+	}
+
+	inputs := make([]btcjson.RawTxInput, len(tx.TxIn))
+	for i, in := range tx.TxIn {
+		prev, err := c.prevOut(in.PreviousOutPoint)
+		if err != nil {
+			return nil, err
+		}
+		inputs[i] = btcjson.RawTxInput{
+			Txid:         in.PreviousOutPoint.Hash.String(),
+			Vout:         in.PreviousOutPoint.Index,
+			ScriptPubKey: hex.EncodeToString(prev.PkScript),
+		}
+	}
+	wifs := make([]string, len(opts.PrivateKeys))
+	for i, key := range opts.PrivateKeys {
+		wifs[i] = key.String()
+	}
+
+	signedTx, allInputsSigned, err := c.SignRawTransaction3(tx, inputs, wifs)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	if !allInputsSigned {
+		return nil, errors.Err("Not all inputs for the tx could be signed!")
+	}
+	return c.SendRawTransaction(signedTx, false)
+}
+
+// outpointHash is the hash of an outpoint that channel signatures cover
+func outpointHash(outpoint wire.OutPoint) (string, error) {
+	return c.GetOutpointHash(outpoint.Hash.String(), outpoint.Index)
+}
@@ -0,0 +1,234 @@
+package lbrycrd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// newTxOutClient returns a client for a fake lbrycrd that only answers gettxout, with an unspent output worth value
+func newTxOutClient(t *testing.T, value float64, pkScript []byte) *Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     interface{} `json:"id"`
+			Method string      `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "gettxout" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":    req.ID,
+			"error": nil,
+			"result": map[string]interface{}{
+				"confirmations": 1,
+				"value":         value,
+				"scriptPubKey":  map[string]interface{}{"hex": hex.EncodeToString(pkScript)},
+			},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	rpc, err := rpcclient.New(&rpcclient.ConnConfig{
+		Host:         strings.TrimPrefix(server.URL, "http://"),
+		User:         "user",
+		Pass:         "pass",
+		HTTPPostMode: true,
+		DisableTLS:   true,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(rpc.Shutdown)
+	return &Client{Client: rpc}
+}
+
+func TestBuildClaimTx_Scripts(t *testing.T) {
+	claim, err := NewStreamClaim("title", "description")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := claim.CompileValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimOutpoint := wire.OutPoint{Hash: chainhash.Hash{9}, Index: 3}
+	claimScript := testClaimTx(t).TxOut[0].PkScript
+
+	tests := []struct {
+		name       string
+		build      func(*Client, *TxOptions) (*UnsignedTx, error)
+		scriptType ScriptType
+		claimID    string
+		value      []byte
+		spends     *wire.OutPoint
+	}{
+		{
+			name: "claim",
+			build: func(c *Client, opts *TxOptions) (*UnsignedTx, error) {
+				return c.BuildClaimName("test", claim, 1, opts)
+			},
+			scriptType: ClaimName,
+			value:      value,
+		},
+		{
+			name: "update",
+			build: func(c *Client, opts *TxOptions) (*UnsignedTx, error) {
+				return c.BuildUpdateClaim("test", testClaimID, claimOutpoint, claim, 1, opts)
+			},
+			scriptType: ClaimUpdate,
+			claimID:    testClaimID,
+			value:      value,
+			spends:     &claimOutpoint,
+		},
+		{
+			name: "support",
+			build: func(c *Client, opts *TxOptions) (*UnsignedTx, error) {
+				return c.BuildSupport("test", testClaimID, 1, opts)
+			},
+			scriptType: ClaimSupport,
+			claimID:    testClaimID,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newTxOutClient(t, 1, claimScript)
+			opts := testTxOptions(t, 2)
+			u, err := test.build(client, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tx := u.Tx
+
+			if test.spends != nil {
+				if len(tx.TxIn) != 2 || tx.TxIn[0].PreviousOutPoint != *test.spends {
+					t.Fatalf("expected the first input to spend %s", test.spends)
+				}
+				if u.PrevOuts[0].Value != 100000000 || !bytes.Equal(u.PrevOuts[0].PkScript, claimScript) {
+					t.Errorf("expected the spent claim output as the first previous output, got %+v", u.PrevOuts[0])
+				}
+			} else if len(tx.TxIn) != 1 {
+				t.Fatalf("expected 1 input, got %d", len(tx.TxIn))
+			}
+			if len(tx.TxOut) != 2 {
+				t.Fatalf("expected a claim and a change output, got %d outputs", len(tx.TxOut))
+			}
+
+			out := tx.TxOut[claimNout]
+			if out.Value != 100000000 {
+				t.Errorf("expected the claim output to be worth 1 LBC, got %d", out.Value)
+			}
+			script, err := ParseClaimScript(out.PkScript)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if script.Type != test.scriptType || script.Name != "test" || script.ClaimID != test.claimID {
+				t.Errorf("unexpected claim script %+v", script)
+			}
+			if !bytes.Equal(script.Value, test.value) {
+				t.Errorf("expected value %x, got %x", test.value, script.Value)
+			}
+			address, err := script.Address(&MainNetParams)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if address.EncodeAddress() != testPayoutAddress {
+				t.Errorf("expected the claim to pay to %s, got %s", testPayoutAddress, address.EncodeAddress())
+			}
+
+			change, err := ParseClaimScript(tx.TxOut[claimNout+1].PkScript)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if change.Type != PayToPubKeyHash {
+				t.Errorf("expected plain p2pkh change, got %d", change.Type)
+			}
+		})
+	}
+}
+
+func TestClaimTxResult(t *testing.T) {
+	address, err := DecodeAddress(testPayoutAddress, &MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payout, err := txscript.PayToAddrScript(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txid := &chainhash.Hash{7}
+	claimID, err := ClaimIDFromOutpoint(txid.String(), claimNout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimScript, err := claimNameScript("test", []byte("value"), payout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	update, err := updateClaimScript("test", testClaimID, []byte("value"), payout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	support, err := claimSupportScript("test", testClaimID, payout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		outputs    [][]byte
+		claimID    string
+		changeNout int
+	}{
+		{
+			name:       "claim",
+			outputs:    [][]byte{claimScript, payout},
+			claimID:    claimID,
+			changeNout: 1,
+		},
+		{
+			name:       "update",
+			outputs:    [][]byte{update, payout},
+			claimID:    testClaimID,
+			changeNout: 1,
+		},
+		{
+			name:       "support without change",
+			outputs:    [][]byte{support},
+			claimID:    testClaimID,
+			changeNout: -1,
+		},
+	}
+	for _, test := range tests {
+		tx := wire.NewMsgTx(wire.TxVersion)
+		for _, pkScript := range test.outputs {
+			tx.AddTxOut(wire.NewTxOut(100000000, pkScript))
+		}
+		res, err := claimTxResult(txid, tx)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if res.TxID != txid || res.Nout != claimNout || res.ClaimID != test.claimID || res.ChangeNout != test.changeNout {
+			t.Errorf("%s: unexpected result %+v", test.name, res)
+		}
+	}
+
+	if _, err := claimTxResult(txid, wire.NewMsgTx(wire.TxVersion)); err == nil {
+		t.Error("expected an error for a transaction without outputs")
+	}
+	plain := wire.NewMsgTx(wire.TxVersion)
+	plain.AddTxOut(wire.NewTxOut(100000000, payout))
+	if _, err := claimTxResult(txid, plain); err == nil {
+		t.Error("expected an error when the first output isn't a claim")
+	}
+}
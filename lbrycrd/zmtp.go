@@ -0,0 +1,172 @@
+package lbrycrd
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// this is just enough of ZMTP 3.0 (https://rfc.zeromq.org/spec/23/) to subscribe to lbrycrd's zmq notifications,
+// without pulling in libzmq or a full go implementation of it
+
+const (
+	zmtpGreetingLength = 64
+	zmtpMaxFrameLength = 64 << 20 // no block comes close to this
+
+	zmtpFlagMore    = 0x01
+	zmtpFlagLong    = 0x02
+	zmtpFlagCommand = 0x04
+)
+
+// zmtpConn is a zmq SUB socket connected to a single publisher
+type zmtpConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// dialZMTP connects to a zmq PUB socket at addr (host:port) and subscribes to the topics
+func dialZMTP(addr string, timeout time.Duration, topics ...string) (*zmtpConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	z := &zmtpConn{conn: conn, r: bufio.NewReader(conn)}
+
+	err = conn.SetDeadline(time.Now().Add(timeout))
+	if err == nil {
+		err = z.handshake()
+	}
+	if err == nil {
+		for _, topic := range topics {
+			// in ZMTP 3.0, a subscription is a message that starts with 1
+			err = z.writeFrame(0, append([]byte{1}, topic...))
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = conn.SetDeadline(time.Time{})
+	}
+	if err != nil {
+		conn.Close()
+		return nil, errors.Err(err)
+	}
+
+	return z, nil
+}
+
+func (z *zmtpConn) handshake() error {
+	greeting := make([]byte, zmtpGreetingLength)
+	greeting[0] = 0xff
+	greeting[9] = 0x7f
+	greeting[10] = 3 // version 3.0
+	copy(greeting[12:], "NULL")
+	_, err := z.conn.Write(greeting)
+	if err != nil {
+		return errors.Err(err)
+	}
+
+	theirs := make([]byte, zmtpGreetingLength)
+	_, err = io.ReadFull(z.r, theirs)
+	if err != nil {
+		return errors.Err(err)
+	}
+	if theirs[0] != 0xff || theirs[9] != 0x7f {
+		return errors.Err("not a zmq endpoint")
+	}
+	if theirs[10] < 3 {
+		return errors.Err("zmtp version %d is not supported", theirs[10])
+	}
+
+	var ready []byte
+	ready = append(ready, 5)
+	ready = append(ready, "READY"...)
+	ready = append(ready, byte(len("Socket-Type")))
+	ready = append(ready, "Socket-Type"...)
+	ready = append(ready, 0, 0, 0, byte(len("SUB")))
+	ready = append(ready, "SUB"...)
+	err = z.writeFrame(zmtpFlagCommand, ready)
+	if err != nil {
+		return err
+	}
+
+	flags, body, err := z.readFrame()
+	if err != nil {
+		return err
+	}
+	if flags&zmtpFlagCommand == 0 || len(body) < 6 || string(body[1:6]) != "READY" {
+		return errors.Err("expected a READY command")
+	}
+	return nil
+}
+
+func (z *zmtpConn) writeFrame(flags byte, body []byte) error {
+	var header []byte
+	if len(body) > 255 {
+		header = make([]byte, 9)
+		header[0] = flags | zmtpFlagLong
+		binary.BigEndian.PutUint64(header[1:], uint64(len(body)))
+	} else {
+		header = []byte{flags, byte(len(body))}
+	}
+	_, err := z.conn.Write(append(header, body...))
+	return errors.Err(err)
+}
+
+func (z *zmtpConn) readFrame() (byte, []byte, error) {
+	flags, err := z.r.ReadByte()
+	if err != nil {
+		return 0, nil, errors.Err(err)
+	}
+
+	var length uint64
+	if flags&zmtpFlagLong != 0 {
+		var l [8]byte
+		_, err = io.ReadFull(z.r, l[:])
+		length = binary.BigEndian.Uint64(l[:])
+	} else {
+		var l byte
+		l, err = z.r.ReadByte()
+		length = uint64(l)
+	}
+	if err != nil {
+		return 0, nil, errors.Err(err)
+	}
+	if length > zmtpMaxFrameLength {
+		return 0, nil, errors.Err("frame of %d bytes is too long", length)
+	}
+
+	body := make([]byte, length)
+	_, err = io.ReadFull(z.r, body)
+	if err != nil {
+		return 0, nil, errors.Err(err)
+	}
+	return flags, body, nil
+}
+
+// readMessage reads the next message, skipping any commands
+func (z *zmtpConn) readMessage() ([][]byte, error) {
+	var parts [][]byte
+	for {
+		flags, body, err := z.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		if flags&zmtpFlagCommand != 0 {
+			continue
+		}
+		parts = append(parts, body)
+		if flags&zmtpFlagMore == 0 {
+			return parts, nil
+		}
+	}
+}
+
+func (z *zmtpConn) Close() error {
+	return z.conn.Close()
+}
@@ -0,0 +1,246 @@
+// Package reflector uploads streams to reflector servers, which mirror blobs so a stream stays available when the
+// publisher goes offline. It speaks version 2 of the reflector protocol: json messages over tcp, with raw blob bytes
+// sent after a message that the server agrees to.
+package reflector
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/stream"
+)
+
+const (
+	DefaultPort    = 5566
+	DefaultTimeout = 30 * time.Second
+
+	protocolVersion2 = 1
+)
+
+var (
+	ErrBlobExists   = errors.Base("blob exists on server")
+	ErrNotConnected = errors.Base("not connected")
+)
+
+type handshakeRequestResponse struct {
+	Version *int `json:"version"`
+}
+
+type sendBlobRequest struct {
+	BlobHash   string `json:"blob_hash,omitempty"`
+	BlobSize   int    `json:"blob_size,omitempty"`
+	SdBlobHash string `json:"sd_blob_hash,omitempty"`
+	SdBlobSize int    `json:"sd_blob_size,omitempty"`
+}
+
+type sendBlobResponse struct {
+	SendBlob bool `json:"send_blob"`
+}
+
+type sendSdBlobResponse struct {
+	SendSdBlob  bool     `json:"send_sd_blob"`
+	NeededBlobs []string `json:"needed_blobs,omitempty"`
+}
+
+type blobTransferResponse struct {
+	ReceivedBlob bool `json:"received_blob"`
+}
+
+type sdBlobTransferResponse struct {
+	ReceivedSdBlob bool `json:"received_sd_blob"`
+}
+
+// Client uploads blobs to a reflector server. It isn't safe for concurrent use.
+type Client struct {
+	// how long each request to the server can take. defaults to DefaultTimeout
+	Timeout time.Duration
+
+	conn    net.Conn
+	decoder *json.Decoder
+}
+
+// Connect connects to a reflector server and does the handshake. address is host:port, or just host for the default
+// port.
+func (c *Client) Connect(address string) error {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, strconv.Itoa(DefaultPort))
+	}
+
+	conn, err := net.DialTimeout("tcp", address, c.timeout())
+	if err != nil {
+		return errors.Err(err)
+	}
+	c.conn = conn
+	c.decoder = json.NewDecoder(conn)
+
+	err = c.doHandshake()
+	if err != nil {
+		c.Close()
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return errors.Err(err)
+}
+
+// SendBlob uploads a content blob. It returns ErrBlobExists if the server already has it.
+func (c *Client) SendBlob(blob stream.Blob) error {
+	if c.conn == nil {
+		return errors.Err(ErrNotConnected)
+	}
+	if err := blob.ValidForSend(); err != nil {
+		return errors.Err(err)
+	}
+
+	hash := blob.HashHex()
+	var resp sendBlobResponse
+	err := c.request(sendBlobRequest{BlobHash: hash, BlobSize: blob.Size()}, &resp)
+	if err != nil {
+		return err
+	}
+	if !resp.SendBlob {
+		return errors.Err(ErrBlobExists)
+	}
+
+	var transfer blobTransferResponse
+	err = c.send(blob, &transfer)
+	if err != nil {
+		return err
+	}
+	if !transfer.ReceivedBlob {
+		return errors.Err("server did not accept blob %s", hash)
+	}
+	return nil
+}
+
+// SendSDBlob uploads an sd blob and returns the hashes of the stream's content blobs that the server still needs. If
+// the server already has the sd blob, it returns ErrBlobExists along with the blobs that are needed, if any.
+func (c *Client) SendSDBlob(blob stream.Blob) ([]string, error) {
+	if c.conn == nil {
+		return nil, errors.Err(ErrNotConnected)
+	}
+	if err := blob.ValidForSend(); err != nil {
+		return nil, errors.Err(err)
+	}
+	sd := &stream.SDBlob{}
+	err := sd.FromBlob(blob)
+	if err != nil {
+		return nil, errors.Prefix("not an sd blob", err)
+	}
+
+	hash := blob.HashHex()
+	var resp sendSdBlobResponse
+	err = c.request(sendBlobRequest{SdBlobHash: hash, SdBlobSize: blob.Size()}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.SendSdBlob {
+		return resp.NeededBlobs, errors.Err(ErrBlobExists)
+	}
+
+	var transfer sdBlobTransferResponse
+	err = c.send(blob, &transfer)
+	if err != nil {
+		return nil, err
+	}
+	if !transfer.ReceivedSdBlob {
+		return nil, errors.Err("server did not accept sd blob %s", hash)
+	}
+
+	// the server didn't have the stream, so it needs every blob
+	var needed []string
+	for _, info := range sd.BlobInfos {
+		if info.Length > 0 {
+			needed = append(needed, hex.EncodeToString(info.BlobHash))
+		}
+	}
+	return needed, nil
+}
+
+// SendStream uploads a stream, the sd blob first and then whichever content blobs the server needs. getter is where
+// the content blobs come from. It returns how many blobs were uploaded.
+func (c *Client) SendStream(sdBlob stream.Blob, getter stream.BlobGetter) (int, error) {
+	sent := 0
+	needed, err := c.SendSDBlob(sdBlob)
+	if err == nil {
+		sent++
+	} else if !errors.Is(err, ErrBlobExists) {
+		return sent, err
+	}
+
+	for _, hash := range needed {
+		blob, err := getter.Get(hash)
+		if err != nil {
+			return sent, err
+		}
+		if blob.HashHex() != hash {
+			return sent, errors.Err("blob %s doesn't match its hash", hash)
+		}
+		err = c.SendBlob(blob)
+		if errors.Is(err, ErrBlobExists) {
+			continue
+		} else if err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func (c *Client) doHandshake() error {
+	version := protocolVersion2
+	var resp handshakeRequestResponse
+	err := c.request(handshakeRequestResponse{Version: &version}, &resp)
+	if err != nil {
+		return err
+	}
+	if resp.Version == nil || *resp.Version != protocolVersion2 {
+		return errors.Err("handshake failed: server does not speak version 2 of the protocol")
+	}
+	return nil
+}
+
+// request sends a json message and reads the json response
+func (c *Client) request(req interface{}, resp interface{}) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return errors.Err(err)
+	}
+	return c.send(b, resp)
+}
+
+// send writes raw bytes and reads the json response
+func (c *Client) send(b []byte, resp interface{}) error {
+	err := c.conn.SetDeadline(time.Now().Add(c.timeout()))
+	if err != nil {
+		return errors.Err(err)
+	}
+	_, err = c.conn.Write(b)
+	if err != nil {
+		return errors.Err(err)
+	}
+	err = c.decoder.Decode(resp)
+	if err != nil {
+		return errors.Err(err)
+	}
+	return nil
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout == 0 {
+		return DefaultTimeout
+	}
+	return c.Timeout
+}
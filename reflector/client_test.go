@@ -0,0 +1,251 @@
+package reflector
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/stream"
+)
+
+// testServer is just enough of a reflector server to test the client
+type testServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	blobs    map[string][]byte
+	wg       sync.WaitGroup
+}
+
+func newTestServer(t *testing.T) *testServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{listener: l, blobs: make(map[string][]byte)}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				defer conn.Close()
+				s.handle(conn)
+			}()
+		}
+	}()
+	t.Cleanup(func() {
+		l.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+func (s *testServer) has(hash string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.blobs[hash]
+	return ok
+}
+
+func (s *testServer) handle(conn net.Conn) {
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+
+	var handshake handshakeRequestResponse
+	if dec.Decode(&handshake) != nil {
+		return
+	}
+	enc.Encode(handshake)
+
+	for {
+		var req sendBlobRequest
+		if dec.Decode(&req) != nil {
+			return
+		}
+		isSD := req.SdBlobHash != ""
+		hash, size := req.BlobHash, req.BlobSize
+		if isSD {
+			hash, size = req.SdBlobHash, req.SdBlobSize
+		}
+
+		if s.has(hash) {
+			if isSD {
+				enc.Encode(sendSdBlobResponse{SendSdBlob: false, NeededBlobs: s.needed(hash)})
+			} else {
+				enc.Encode(sendBlobResponse{SendBlob: false})
+			}
+			continue
+		}
+		if isSD {
+			enc.Encode(sendSdBlobResponse{SendSdBlob: true})
+		} else {
+			enc.Encode(sendBlobResponse{SendBlob: true})
+		}
+
+		// the decoder may have buffered some of the blob already
+		blob := make([]byte, size)
+		_, err := io.ReadFull(io.MultiReader(dec.Buffered(), conn), blob)
+		if err != nil {
+			return
+		}
+		dec = json.NewDecoder(conn)
+		ok := stream.Blob(blob).HashHex() == hash
+		if ok {
+			s.mu.Lock()
+			s.blobs[hash] = blob
+			s.mu.Unlock()
+		}
+		if isSD {
+			enc.Encode(sdBlobTransferResponse{ReceivedSdBlob: ok})
+		} else {
+			enc.Encode(blobTransferResponse{ReceivedBlob: ok})
+		}
+	}
+}
+
+func (s *testServer) needed(sdHash string) []string {
+	sd := &stream.SDBlob{}
+	s.mu.Lock()
+	err := sd.FromBlob(s.blobs[sdHash])
+	s.mu.Unlock()
+	if err != nil {
+		return nil
+	}
+	var needed []string
+	for _, info := range sd.BlobInfos {
+		if info.Length > 0 {
+			hash := hex.EncodeToString(info.BlobHash)
+			if !s.has(hash) {
+				needed = append(needed, hash)
+			}
+		}
+	}
+	return needed
+}
+
+func testStream(t *testing.T) stream.Stream {
+	data := make([]byte, 3*stream.MaxBlobSize)
+	_, err := rand.Read(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := stream.New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func streamGetter(s stream.Stream) stream.BlobGetter {
+	return stream.BlobGetterFunc(func(hash string) (stream.Blob, error) {
+		for _, b := range s {
+			if b.HashHex() == hash {
+				return b, nil
+			}
+		}
+		return nil, errors.Err("blob not found")
+	})
+}
+
+func TestSendStream(t *testing.T) {
+	server := newTestServer(t)
+	s := testStream(t)
+
+	c := &Client{}
+	err := c.Connect(server.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// the server already has one of the content blobs
+	err = c.SendBlob(s[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SendBlob(s[2]); !errors.Is(err, ErrBlobExists) {
+		t.Errorf("expected ErrBlobExists, got %v", err)
+	}
+
+	sent, err := c.SendStream(s[0], streamGetter(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != len(s)-1 {
+		t.Errorf("expected %d blobs to be sent, got %d", len(s)-1, sent)
+	}
+	for i, b := range s {
+		if !server.has(b.HashHex()) {
+			t.Errorf("server is missing blob %d", i)
+		}
+	}
+
+	sent, err = c.SendStream(s[0], streamGetter(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 0 {
+		t.Errorf("expected nothing to be sent for a stream the server has, sent %d", sent)
+	}
+}
+
+func TestSendStream_Partial(t *testing.T) {
+	server := newTestServer(t)
+	s := testStream(t)
+
+	c := &Client{}
+	err := c.Connect(server.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// an earlier upload that was interrupted after the sd blob
+	_, err = c.SendSDBlob(s[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	needed, err := c.SendSDBlob(s[0])
+	if !errors.Is(err, ErrBlobExists) {
+		t.Fatalf("expected ErrBlobExists, got %v", err)
+	}
+	if len(needed) != len(s)-1 {
+		t.Errorf("expected %d needed blobs, got %d", len(s)-1, len(needed))
+	}
+
+	sent, err := c.SendStream(s[0], streamGetter(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != len(s)-1 {
+		t.Errorf("expected %d blobs to be sent, got %d", len(s)-1, sent)
+	}
+}
+
+func TestSendSDBlob_NotSD(t *testing.T) {
+	c := &Client{}
+	if _, err := c.SendSDBlob(stream.Blob("hi")); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("expected ErrNotConnected, got %v", err)
+	}
+
+	server := newTestServer(t)
+	err := c.Connect(server.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.SendSDBlob(stream.Blob("not json")); err == nil {
+		t.Error("expected an error sending a blob that isn't an sd blob")
+	}
+}
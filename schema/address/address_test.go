@@ -1,6 +1,10 @@
 package address
 
-import "testing"
+import (
+	"testing"
+
+	pb "github.com/lbryio/types/v2/go"
+)
 
 func TestDecodeAddressLBRYCrdMain(t *testing.T) {
 	addr := "bUc9gyCJPKu2CBYpTvJ98MdmsLb68utjP6"
@@ -27,3 +31,102 @@ func TestEncodeAddressLBRYCrdMain(t *testing.T) {
 		t.Error("Mismatch")
 	}
 }
+
+func TestDetectNetwork(t *testing.T) {
+	networks, addrType, err := DetectNetwork("bUc9gyCJPKu2CBYpTvJ98MdmsLb68utjP6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networks) != 1 || networks[0] != "lbrycrd_main" {
+		t.Errorf("expected lbrycrd_main, got %v", networks)
+	}
+	if addrType != PubKeyHash {
+		t.Errorf("expected a pubkeyhash address, got %s", addrType)
+	}
+
+	hash := [20]byte{1, 2, 3}
+	testnetAddr, err := EncodeScriptHash(hash, "lbrycrd_testnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	networks, addrType, err = DetectNetwork(testnetAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networks) != 2 || networks[0] != "lbrycrd_testnet" || networks[1] != "lbrycrd_regtest" {
+		t.Errorf("expected testnet and regtest, got %v", networks)
+	}
+	if addrType != ScriptHash {
+		t.Errorf("expected a scripthash address, got %s", addrType)
+	}
+
+	if _, _, err := DetectNetwork("bUc9gyCJPKu2CBYpTvJ98MdmsLb68utjP7"); err == nil {
+		t.Error("expected a checksum error")
+	}
+}
+
+func TestEncodePubKeyHash(t *testing.T) {
+	hash := [20]byte{174, 41, 64, 245, 110, 91, 239, 43, 208, 32, 73, 115, 20, 70, 204, 83, 199, 3, 206, 210}
+	result, err := EncodePubKeyHash(hash, "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "bUc9gyCJPKu2CBYpTvJ98MdmsLb68utjP6" {
+		t.Errorf("Mismatch: %s", result)
+	}
+
+	decoded, addrType, err := DecodeHash(result, "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != hash || addrType != PubKeyHash {
+		t.Error("Mismatch")
+	}
+	if _, err := DecodeScriptHash(result, "lbrycrd_main"); err == nil {
+		t.Error("expected an error decoding a pubkeyhash address as a script hash")
+	}
+}
+
+func TestScriptHashRoundTrip(t *testing.T) {
+	hash := [20]byte{0xde, 0xad, 0xbe, 0xef}
+	for _, name := range []string{"lbrycrd_main", "lbrycrd_testnet", "lbrycrd_regtest"} {
+		addr, err := EncodeScriptHash(hash, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeScriptHash(addr, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded != hash {
+			t.Errorf("%s: mismatch", name)
+		}
+	}
+	if _, err := EncodeScriptHash(hash, "bitcoin"); err == nil {
+		t.Error("expected an error for an unknown blockchain")
+	}
+}
+
+func TestValidateClaimFeeAddress(t *testing.T) {
+	addr, err := DecodeAddress("bUc9gyCJPKu2CBYpTvJ98MdmsLb68utjP6", "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claim := &pb.Claim{Type: &pb.Claim_Stream{Stream: &pb.Stream{Fee: &pb.Fee{Address: addr[:]}}}}
+	if err := ValidateClaimFeeAddress(claim, "lbrycrd_main"); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateClaimFeeAddress(claim, "lbrycrd_testnet"); err == nil {
+		t.Error("expected a mainnet address to be invalid on testnet")
+	}
+
+	claim.GetStream().Fee.Address = addr[:24]
+	if err := ValidateClaimFeeAddress(claim, "lbrycrd_main"); err == nil {
+		t.Error("expected a short address to be invalid")
+	}
+
+	noFee := &pb.Claim{Type: &pb.Claim_Stream{Stream: &pb.Stream{}}}
+	if err := ValidateClaimFeeAddress(noFee, "lbrycrd_main"); err != nil {
+		t.Error(err)
+	}
+}
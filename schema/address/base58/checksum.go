@@ -4,17 +4,22 @@ import "crypto/sha256"
 
 const checksumLength = 4
 
+// Base58Checksum returns the checksum that base58check appends to v, the first four bytes of its double sha256
+func Base58Checksum(v []byte) [checksumLength]byte {
+	checksum := [checksumLength]byte{}
+	hash := sha256.Sum256(v)
+	hash = sha256.Sum256(hash[:])
+	copy(checksum[:], hash[:checksumLength])
+	return checksum
+}
+
 func VerifyBase58Checksum(v []byte) bool {
+	if len(v) < checksumLength {
+		return false
+	}
 	checksum := [checksumLength]byte{}
 	for i := range checksum {
 		checksum[i] = v[len(v)-checksumLength+i]
 	}
-	real_checksum := sha256.Sum256(v[:len(v)-checksumLength])
-	real_checksum = sha256.Sum256(real_checksum[:])
-	for i, c := range checksum {
-		if c != real_checksum[i] {
-			return false
-		}
-	}
-	return true
+	return checksum == Base58Checksum(v[:len(v)-checksumLength])
 }
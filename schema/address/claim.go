@@ -0,0 +1,28 @@
+package address
+
+import (
+	"strconv"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	pb "github.com/lbryio/types/v2/go"
+)
+
+// ValidateAddressBytes checks that a raw address, as it's stored in a claim, is a valid address on the blockchain
+func ValidateAddressBytes(address []byte, blockchainName string) error {
+	if len(address) != addressLength {
+		return errors.Err("invalid address length: " + strconv.Itoa(len(address)) + "!")
+	}
+	buf := [addressLength]byte{}
+	copy(buf[:], address)
+	_, err := ValidateAddress(buf, blockchainName)
+	return err
+}
+
+// ValidateClaimFeeAddress checks the address that a stream's fee is paid to. claims without a fee are valid.
+func ValidateClaimFeeAddress(claim *pb.Claim, blockchainName string) error {
+	fee := claim.GetStream().GetFee()
+	if fee == nil {
+		return nil
+	}
+	return errors.Prefix("fee address", ValidateAddressBytes(fee.GetAddress(), blockchainName))
+}
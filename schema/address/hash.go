@@ -0,0 +1,110 @@
+package address
+
+import (
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/schema/address/base58"
+)
+
+// Type is the kind of output script an address pays to
+type Type int
+
+const (
+	PubKeyHash Type = iota // pay to pubkey hash (p2pkh)
+	ScriptHash             // pay to script hash (p2sh)
+)
+
+func (t Type) String() string {
+	switch t {
+	case PubKeyHash:
+		return "pubkeyhash"
+	case ScriptHash:
+		return "scripthash"
+	}
+	return "unknown"
+}
+
+// DetectNetwork returns the blockchains an address is valid on and the type of the address. testnet and regtest
+// share their prefixes, so an address that is valid on one of them is always valid on the other.
+func DetectNetwork(address string) ([]string, Type, error) {
+	buf, err := decodeChecked(address)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var networks []string
+	var t Type
+	for _, name := range []string{lbrycrdMain, lbrycrdTestnet, lbrycrdRegtest} {
+		for i, prefix := range addressPrefixes[name] {
+			if buf[0] == prefix {
+				networks = append(networks, name)
+				t = Type(i)
+			}
+		}
+	}
+	if len(networks) == 0 {
+		return nil, 0, errors.Err("unknown address prefix %d", buf[0])
+	}
+	return networks, t, nil
+}
+
+// EncodePubKeyHash returns the address that pays to the given hash160 of a public key
+func EncodePubKeyHash(hash [pubkeyLength]byte, blockchainName string) (string, error) {
+	return encodeHash(hash, PubKeyHash, blockchainName)
+}
+
+// EncodeScriptHash returns the address that pays to the given hash160 of a redeem script
+func EncodeScriptHash(hash [pubkeyLength]byte, blockchainName string) (string, error) {
+	return encodeHash(hash, ScriptHash, blockchainName)
+}
+
+// DecodeHash returns the hash160 an address pays to, and whether it's a pubkey hash or a script hash
+func DecodeHash(address string, blockchainName string) ([pubkeyLength]byte, Type, error) {
+	var hash [pubkeyLength]byte
+	buf, err := DecodeAddress(address, blockchainName)
+	if err != nil {
+		return hash, 0, err
+	}
+	copy(hash[:], buf[prefixLength:prefixLength+pubkeyLength])
+	if buf[0] == addressPrefixes[blockchainName][ScriptHash] {
+		return hash, ScriptHash, nil
+	}
+	return hash, PubKeyHash, nil
+}
+
+// DecodeScriptHash returns the hash160 of the redeem script a p2sh address pays to
+func DecodeScriptHash(address string, blockchainName string) ([pubkeyLength]byte, error) {
+	hash, t, err := DecodeHash(address, blockchainName)
+	if err != nil {
+		return hash, err
+	}
+	if t != ScriptHash {
+		return hash, errors.Err("%s is not a script hash address", address)
+	}
+	return hash, nil
+}
+
+func encodeHash(hash [pubkeyLength]byte, t Type, blockchainName string) (string, error) {
+	prefixes, ok := addressPrefixes[blockchainName]
+	if !ok {
+		return "", errors.Err("invalid blockchain name")
+	}
+	buf := [addressLength]byte{}
+	buf[0] = prefixes[t]
+	copy(buf[prefixLength:], hash[:])
+	checksum := base58.Base58Checksum(buf[:prefixLength+pubkeyLength])
+	copy(buf[prefixLength+pubkeyLength:], checksum[:])
+	return EncodeAddress(buf, blockchainName)
+}
+
+func decodeChecked(address string) ([addressLength]byte, error) {
+	buf := [addressLength]byte{}
+	decoded, err := base58.DecodeBase58(address, addressLength)
+	if err != nil {
+		return buf, errors.Err("failed to decode")
+	}
+	copy(buf[:], decoded)
+	if !ChecksumIsValid(buf) {
+		return buf, errors.Err("invalid address checksum")
+	}
+	return buf, nil
+}
@@ -0,0 +1,24 @@
+package keys
+
+import "crypto/sha256"
+
+// ClaimDigest returns the hash that a channel signs for a claim or support. firstInputOutpointHash is the hash of the
+// first input's outpoint in the transaction, channelClaimID is the channel's claim id in the reversed order it's
+// stored in the value, and payload is the serialized claim.
+func ClaimDigest(firstInputOutpointHash, channelClaimID, payload []byte) [32]byte {
+	var combined []byte
+	combined = append(combined, firstInputOutpointHash...)
+	combined = append(combined, channelClaimID...)
+	combined = append(combined, payload...)
+	return sha256.Sum256(combined)
+}
+
+// LegacyClaimDigest returns the hash that a channel signs for a v1 claim. address is the decoded 25 byte claim address,
+// claim is the claim serialized without its signature, and certificateID is the channel claim id in display order.
+func LegacyClaimDigest(address, claim, certificateID []byte) [32]byte {
+	var combined []byte
+	combined = append(combined, address...)
+	combined = append(combined, claim...)
+	combined = append(combined, certificateID...)
+	return sha256.Sum256(combined)
+}
@@ -0,0 +1,83 @@
+package keys
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+const (
+	ecPrivateKeyPEMType    = "EC PRIVATE KEY"
+	pkcs8PrivateKeyPEMType = "PRIVATE KEY"
+)
+
+// pkcs8 is the wrapper some versions of the SDK put around the ec private key
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// GenerateKeyPair creates a new secp256k1 key pair for a channel
+func GenerateKeyPair() (*btcec.PrivateKey, *btcec.PublicKey, error) {
+	priv, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		return nil, nil, errors.Err(err)
+	}
+	return priv, priv.PubKey(), nil
+}
+
+// PublicKeyToCompressedDER is like PublicKeyToDER, but the key point is compressed to 33 bytes
+func PublicKeyToCompressedDER(publicKey *btcec.PublicKey) ([]byte, error) {
+	der, err := PublicKeyToDER(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	info := publicKeyInfo{}
+	_, err = asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	compressed := publicKey.SerializeCompressed()
+	return asn1.Marshal(publicKeyInfo{
+		Algorithm: info.Algorithm,
+		PublicKey: asn1.BitString{
+			Bytes:     compressed,
+			BitLength: 8 * len(compressed),
+		},
+	})
+}
+
+// PrivateKeyToPEM encodes a private key the same way the SDK stores channel keys in the wallet
+func PrivateKeyToPEM(key *btcec.PrivateKey) (string, error) {
+	der, err := PrivateKeyToDER(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: ecPrivateKeyPEMType, Bytes: der})), nil
+}
+
+// PrivateKeyFromPEM decodes a private key from a pem, either a plain ec private key or one wrapped in pkcs8
+func PrivateKeyFromPEM(pm string) (*btcec.PrivateKey, *btcec.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pm))
+	if block == nil {
+		return nil, nil, errors.Err("no pem block found")
+	}
+
+	switch block.Type {
+	case ecPrivateKeyPEMType:
+		return GetPrivateKeyFromBytes(block.Bytes)
+	case pkcs8PrivateKeyPEMType:
+		wrapped := pkcs8{}
+		_, err := asn1.Unmarshal(block.Bytes, &wrapped)
+		if err != nil {
+			return nil, nil, errors.Err(err)
+		}
+		return GetPrivateKeyFromBytes(wrapped.PrivateKey)
+	}
+	return nil, nil, errors.Err("unsupported pem type %s", block.Type)
+}
@@ -2,7 +2,9 @@ package keys
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"encoding/pem"
 	"testing"
 
@@ -93,3 +95,89 @@ func TestEncodePEMAndBack(t *testing.T) {
 		t.Error("private keys dont match")
 	}
 }
+
+func TestGenerateKeyPair(t *testing.T) {
+	priv, pub, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !priv.PubKey().IsEqual(pub) {
+		t.Error("public key doesn't match the private key")
+	}
+
+	der, err := PublicKeyToCompressedDER(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uncompressed, err := PublicKeyToDER(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(der), len(uncompressed)-32)
+
+	parsed, err := GetPublicKeyFromBytes(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Assert(t, parsed.IsEqual(pub), "compressed DER must parse back to the same key")
+}
+
+func TestPrivateKeyPEMRoundTrip(t *testing.T) {
+	priv, _, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm, err := PrivateKeyToPEM(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv2, pub2, err := PrivateKeyFromPEM(pm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Assert(t, priv.ToECDSA().Equal(priv2.ToECDSA()), "private keys must match")
+	assert.Assert(t, priv.PubKey().IsEqual(pub2), "public keys must match")
+
+	if _, _, err := PrivateKeyFromPEM("not a pem"); err == nil {
+		t.Error("expected an error for an invalid pem")
+	}
+}
+
+func TestChannelKeysFromWallet(t *testing.T) {
+	priv, _, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm, err := PrivateKeyToPEM(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet, err := json.Marshal(map[string]interface{}{
+		"version": 1,
+		"name":    "My Wallet",
+		"accounts": []map[string]interface{}{{
+			"ledger":       "lbc_mainnet",
+			"name":         "Account #1",
+			"encrypted":    false,
+			"certificates": map[string]string{"bTWJhuZrUSVR7mxsDRYtoqjRhP7nBsF3w8": pm},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	channelKeys, err := ChannelKeysFromWallet(wallet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(channelKeys), 1)
+	key, ok := channelKeys["bTWJhuZrUSVR7mxsDRYtoqjRhP7nBsF3w8"]
+	assert.Assert(t, ok, "channel key missing")
+	assert.Assert(t, priv.ToECDSA().Equal(key.ToECDSA()), "private keys must match")
+}
+
+func TestClaimDigest(t *testing.T) {
+	digest := ClaimDigest([]byte{1}, []byte{2}, []byte{3})
+	expected := sha256.Sum256([]byte{1, 2, 3})
+	assert.Equal(t, digest, expected)
+}
@@ -0,0 +1,38 @@
+package keys
+
+import (
+	"encoding/json"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	"github.com/btcsuite/btcd/btcec"
+)
+
+type walletFile struct {
+	Accounts []struct {
+		Name         string            `json:"name"`
+		Certificates map[string]string `json:"certificates"`
+	} `json:"accounts"`
+}
+
+// ChannelKeysFromWallet reads the channel private keys out of an lbrynet wallet file. lbrynet keys them by channel
+// claim id in older wallets, and by the address of the channel's public key in newer ones, and that key is kept as is.
+func ChannelKeysFromWallet(walletJSON []byte) (map[string]*btcec.PrivateKey, error) {
+	wallet := walletFile{}
+	err := json.Unmarshal(walletJSON, &wallet)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+
+	channelKeys := make(map[string]*btcec.PrivateKey)
+	for _, account := range wallet.Accounts {
+		for id, pm := range account.Certificates {
+			priv, _, err := PrivateKeyFromPEM(pm)
+			if err != nil {
+				return nil, errors.Prefix("account "+account.Name+", channel key "+id, err)
+			}
+			channelKeys[id] = priv
+		}
+	}
+	return channelKeys, nil
+}
@@ -0,0 +1,411 @@
+package stake
+
+import (
+	"encoding/hex"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/schema/keys"
+	pb "github.com/lbryio/types/v2/go"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil/base58"
+)
+
+const (
+	hashLength    = 48 // sd hashes and file hashes are sha384
+	claimIDLength = 20
+
+	deweysPerLBC = 100000000
+	satsPerBTC   = 100000000
+	centsPerUSD  = 100
+)
+
+// ClaimBuilder builds a claim one field at a time, e.g.
+//
+//	helper, err := stake.NewStreamClaim().Title("title").SDHash(sdHash).Fee("LBC", 1.5, address).Build()
+//
+// Setters can be chained, so instead of returning errors they are collected and Build returns them all at once.
+type ClaimBuilder struct {
+	claim *pb.Claim
+	errs  []string
+}
+
+// NewStreamClaim starts building a stream claim
+func NewStreamClaim() *ClaimBuilder {
+	return &ClaimBuilder{claim: newStreamClaim()}
+}
+
+// NewChannelClaim starts building a channel claim
+func NewChannelClaim() *ClaimBuilder {
+	return &ClaimBuilder{claim: newChannelClaim()}
+}
+
+// NewRepost starts building a repost of the claim with the given claim id
+func NewRepost(claimID string) *ClaimBuilder {
+	b := &ClaimBuilder{claim: &pb.Claim{Type: &pb.Claim_Repost{Repost: &pb.ClaimReference{}}}}
+	b.claim.GetRepost().ClaimHash = b.claimHash(claimID)
+	return b
+}
+
+// NewCollection starts building a collection of the claims with the given claim ids
+func NewCollection(claimIDs ...string) *ClaimBuilder {
+	b := &ClaimBuilder{claim: &pb.Claim{Type: &pb.Claim_Collection{Collection: &pb.ClaimList{
+		ListType: pb.ClaimList_COLLECTION,
+	}}}}
+	return b.Claims(claimIDs...)
+}
+
+// NewSupport returns a support value, which only has an emoji. The emoji can be empty, e.g. for a support that's only
+// there to carry a channel signature (see Sign).
+func NewSupport(emoji string) *StakeHelper {
+	return &StakeHelper{Support: &pb.Support{Emoji: emoji}, Version: NoSig}
+}
+
+func (b *ClaimBuilder) addErr(err error) {
+	b.errs = append(b.errs, err.Error())
+}
+
+// Title sets the claim title
+func (b *ClaimBuilder) Title(title string) *ClaimBuilder {
+	b.claim.Title = title
+	return b
+}
+
+// Description sets the claim description
+func (b *ClaimBuilder) Description(description string) *ClaimBuilder {
+	b.claim.Description = description
+	return b
+}
+
+// Thumbnail sets the url of the claim thumbnail
+func (b *ClaimBuilder) Thumbnail(url string) *ClaimBuilder {
+	b.claim.Thumbnail = &pb.Source{Url: url}
+	return b
+}
+
+// Tags adds tags to the claim. tags are lowercased and trimmed, and duplicates are dropped
+func (b *ClaimBuilder) Tags(tags ...string) *ClaimBuilder {
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || containsString(b.claim.Tags, tag) {
+			continue
+		}
+		b.claim.Tags = append(b.claim.Tags, tag)
+	}
+	return b
+}
+
+// Languages adds languages to the claim, as language tags like "en", "en-US" or "zh-Hans-CN"
+func (b *ClaimBuilder) Languages(tags ...string) *ClaimBuilder {
+	for _, tag := range tags {
+		l, err := ParseLanguage(tag)
+		if err != nil {
+			b.addErr(err)
+			continue
+		}
+		b.claim.Languages = append(b.claim.Languages, l)
+	}
+	return b
+}
+
+// Location adds a location to the claim. country is an ISO 3166-1 alpha-2 code. state and city are optional
+func (b *ClaimBuilder) Location(country, state, city string) *ClaimBuilder {
+	c, err := ParseCountry(country)
+	if err != nil {
+		b.addErr(err)
+		return b
+	}
+	b.claim.Locations = append(b.claim.Locations, &pb.Location{
+		Country: c,
+		State:   strings.TrimSpace(state),
+		City:    strings.TrimSpace(city),
+	})
+	return b
+}
+
+func (b *ClaimBuilder) stream(field string) *pb.Stream {
+	s := b.claim.GetStream()
+	if s == nil {
+		b.addErr(errors.Base("%s can only be set on a stream claim", field))
+		return &pb.Stream{} // so callers don't have to check for nil
+	}
+	return s
+}
+
+func (b *ClaimBuilder) source(field string) *pb.Source {
+	s := b.stream(field)
+	if s.Source == nil {
+		s.Source = &pb.Source{}
+	}
+	return s.Source
+}
+
+// Author sets the author of a stream
+func (b *ClaimBuilder) Author(author string) *ClaimBuilder {
+	b.stream("author").Author = author
+	return b
+}
+
+// License sets the license of a stream, and optionally a url for it
+func (b *ClaimBuilder) License(license, url string) *ClaimBuilder {
+	s := b.stream("license")
+	s.License = license
+	s.LicenseUrl = url
+	return b
+}
+
+// ReleaseTime sets the release time of a stream
+func (b *ClaimBuilder) ReleaseTime(t time.Time) *ClaimBuilder {
+	b.stream("release time").ReleaseTime = t.Unix()
+	return b
+}
+
+// Fee sets the price of a stream. currency is LBC, BTC, or USD. amount is in whole units (e.g. 1.5 LBC), and is
+// stored the way the SDK stores it: deweys for LBC, satoshis for BTC, and cents for USD.
+func (b *ClaimBuilder) Fee(currency string, amount float64, address string) *ClaimBuilder {
+	s := b.stream("fee")
+
+	c, ok := pb.Fee_Currency_value[strings.ToUpper(currency)]
+	if !ok || c == int32(pb.Fee_UNKNOWN_CURRENCY) {
+		b.addErr(errors.Base("unknown fee currency '%s'", currency))
+		return b
+	}
+	if amount <= 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		b.addErr(errors.Base("fee amount must be positive"))
+		return b
+	}
+	addressBytes := base58.Decode(address)
+	if len(addressBytes) != 25 {
+		b.addErr(errors.Base("invalid fee address '%s'", address))
+		return b
+	}
+
+	s.Fee = &pb.Fee{
+		Currency: pb.Fee_Currency(c),
+		Amount:   uint64(math.Round(amount * float64(feeUnits(pb.Fee_Currency(c))))),
+		Address:  addressBytes,
+	}
+	return b
+}
+
+// feeUnits returns how many of the units a fee is stored in make up one whole unit of its currency
+func feeUnits(currency pb.Fee_Currency) int64 {
+	switch currency {
+	case pb.Fee_USD:
+		return centsPerUSD
+	case pb.Fee_BTC:
+		return satsPerBTC
+	}
+	return deweysPerLBC
+}
+
+// SDHash sets the hash of the stream's sd blob, hex-encoded
+func (b *ClaimBuilder) SDHash(sdHash string) *ClaimBuilder {
+	hash, err := hex.DecodeString(sdHash)
+	if err != nil || len(hash) != hashLength {
+		b.addErr(errors.Base("sd hash must be %d hex-encoded bytes", hashLength))
+		return b
+	}
+	b.source("sd hash").SdHash = hash
+	return b
+}
+
+// FileHash sets the hash of the stream's file, hex-encoded
+func (b *ClaimBuilder) FileHash(fileHash string) *ClaimBuilder {
+	hash, err := hex.DecodeString(fileHash)
+	if err != nil || len(hash) != hashLength {
+		b.addErr(errors.Base("file hash must be %d hex-encoded bytes", hashLength))
+		return b
+	}
+	b.source("file hash").Hash = hash
+	return b
+}
+
+// File sets the name, size, and media type of the stream's file
+func (b *ClaimBuilder) File(name string, size uint64, mediaType string) *ClaimBuilder {
+	s := b.source("file")
+	s.Name = name
+	s.Size = size
+	s.MediaType = mediaType
+	return b
+}
+
+// Video marks the stream as a video. duration is in seconds
+func (b *ClaimBuilder) Video(width, height, duration uint32) *ClaimBuilder {
+	b.stream("video").Type = &pb.Stream_Video{Video: &pb.Video{Width: width, Height: height, Duration: duration}}
+	return b
+}
+
+// Audio marks the stream as audio. duration is in seconds
+func (b *ClaimBuilder) Audio(duration uint32) *ClaimBuilder {
+	b.stream("audio").Type = &pb.Stream_Audio{Audio: &pb.Audio{Duration: duration}}
+	return b
+}
+
+// Image marks the stream as an image
+func (b *ClaimBuilder) Image(width, height uint32) *ClaimBuilder {
+	b.stream("image").Type = &pb.Stream_Image{Image: &pb.Image{Width: width, Height: height}}
+	return b
+}
+
+// Software marks the stream as software for the given os
+func (b *ClaimBuilder) Software(os string) *ClaimBuilder {
+	b.stream("software").Type = &pb.Stream_Software{Software: &pb.Software{Os: os}}
+	return b
+}
+
+func (b *ClaimBuilder) channel(field string) *pb.Channel {
+	c := b.claim.GetChannel()
+	if c == nil {
+		b.addErr(errors.Base("%s can only be set on a channel claim", field))
+		return &pb.Channel{}
+	}
+	return c
+}
+
+// PublicKey sets the channel's public key
+func (b *ClaimBuilder) PublicKey(publicKey *btcec.PublicKey) *ClaimBuilder {
+	c := b.channel("public key")
+	if publicKey == nil {
+		b.addErr(errors.Base("public key is nil"))
+		return b
+	}
+	der, err := keys.PublicKeyToDER(publicKey)
+	if err != nil {
+		b.addErr(err)
+		return b
+	}
+	c.PublicKey = der
+	return b
+}
+
+// Email sets the channel's contact email
+func (b *ClaimBuilder) Email(email string) *ClaimBuilder {
+	b.channel("email").Email = email
+	return b
+}
+
+// WebsiteURL sets the channel's website
+func (b *ClaimBuilder) WebsiteURL(url string) *ClaimBuilder {
+	b.channel("website url").WebsiteUrl = url
+	return b
+}
+
+// Cover sets the url of the channel's cover image
+func (b *ClaimBuilder) Cover(url string) *ClaimBuilder {
+	b.channel("cover").Cover = &pb.Source{Url: url}
+	return b
+}
+
+// Claims adds claims to a collection, by claim id
+func (b *ClaimBuilder) Claims(claimIDs ...string) *ClaimBuilder {
+	c := b.claim.GetCollection()
+	if c == nil {
+		b.addErr(errors.Base("claims can only be added to a collection"))
+		return b
+	}
+	for _, id := range claimIDs {
+		hash := b.claimHash(id)
+		if hash != nil {
+			c.ClaimReferences = append(c.ClaimReferences, &pb.ClaimReference{ClaimHash: hash})
+		}
+	}
+	return b
+}
+
+// claimHash converts a claim id to the byte order claims are referenced by
+func (b *ClaimBuilder) claimHash(claimID string) []byte {
+	id, err := hex.DecodeString(claimID)
+	if err != nil || len(id) != claimIDLength {
+		b.addErr(errors.Base("invalid claim id '%s'", claimID))
+		return nil
+	}
+	return reverseBytes(id)
+}
+
+// Build checks that the required fields are set and returns a helper that's ready to sign or serialize
+func (b *ClaimBuilder) Build() (*StakeHelper, error) {
+	errs := b.errs
+
+	switch {
+	case b.claim.GetStream() != nil:
+		if len(b.claim.GetStream().GetSource().GetSdHash()) == 0 {
+			errs = append(errs, "stream claims need an sd hash")
+		}
+	case b.claim.GetChannel() != nil:
+		if len(b.claim.GetChannel().GetPublicKey()) == 0 {
+			errs = append(errs, "channel claims need a public key")
+		}
+	case b.claim.GetRepost() != nil:
+		if len(b.claim.GetRepost().GetClaimHash()) == 0 {
+			errs = append(errs, "reposts need a claim id")
+		}
+	case b.claim.GetCollection() != nil:
+		if len(b.claim.GetCollection().GetClaimReferences()) == 0 {
+			errs = append(errs, "collections need at least one claim")
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Err("invalid claim: %s", strings.Join(errs, "; "))
+	}
+
+	return &StakeHelper{Claim: b.claim, Version: NoSig}, nil
+}
+
+// ParseLanguage parses a language tag like "en", "en-US", or "zh-Hans-CN" into a language, with an optional script
+// and region
+func ParseLanguage(tag string) (*pb.Language, error) {
+	parts := strings.FieldsFunc(strings.TrimSpace(tag), func(r rune) bool { return r == '-' || r == '_' })
+	if len(parts) == 0 {
+		return nil, errors.Err("empty language tag")
+	}
+
+	l, ok := pb.Language_Language_value[strings.ToLower(parts[0])]
+	if !ok || l == int32(pb.Language_UNKNOWN_LANGUAGE) {
+		return nil, errors.Err("unknown language '%s' in tag '%s'", parts[0], tag)
+	}
+	lang := &pb.Language{Language: pb.Language_Language(l)}
+
+	for _, part := range parts[1:] {
+		switch {
+		case len(part) == 4 && lang.Script == pb.Language_UNKNOWN_SCRIPT && lang.Region == pb.Location_UNKNOWN_COUNTRY:
+			s, ok := pb.Language_Script_value[strings.ToUpper(part[:1])+strings.ToLower(part[1:])]
+			if !ok {
+				return nil, errors.Err("unknown script '%s' in tag '%s'", part, tag)
+			}
+			lang.Script = pb.Language_Script(s)
+		case len(part) == 2 && lang.Region == pb.Location_UNKNOWN_COUNTRY:
+			c, err := ParseCountry(part)
+			if err != nil {
+				return nil, errors.Prefix("tag '"+tag+"'", err)
+			}
+			lang.Region = c
+		default:
+			return nil, errors.Err("unsupported subtag '%s' in tag '%s'", part, tag)
+		}
+	}
+
+	return lang, nil
+}
+
+// ParseCountry parses an ISO 3166-1 alpha-2 country code
+func ParseCountry(code string) (pb.Location_Country, error) {
+	c, ok := pb.Location_Country_value[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok || c == int32(pb.Location_UNKNOWN_COUNTRY) {
+		return pb.Location_UNKNOWN_COUNTRY, errors.Err("unknown country '%s'", code)
+	}
+	return pb.Location_Country(c), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package stake
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	pb "github.com/lbryio/types/v2/go"
+
+	"github.com/btcsuite/btcd/btcec"
+	"gotest.tools/assert"
+)
+
+const (
+	testSDHash  = "040e8ac6e89c061f982528c23ad33829fd7146435bf7a4cc22f0bff70c4fe0b91fd36da9a375e3e1c171db825bf5d1f3"
+	testAddress = "bSkUov7HMWpYBiXackDwRnR5ishhGHvtJt"
+	testClaimID = "251305ca93d4dbedb50dceb282ebcb7b07b7ac65"
+)
+
+func TestNewStreamClaim(t *testing.T) {
+	helper, err := NewStreamClaim().
+		Title("Test title").
+		Description("Test description").
+		Tags("Music", " music ", "live").
+		Languages("en-US", "zh-Hans-CN").
+		Location("us", "NJ", "some city").
+		Author("Someone").
+		License("Creative Commons", "https://creativecommons.org").
+		SDHash(testSDHash).
+		File("video.mp4", 1024, "video/mp4").
+		Video(1920, 1080, 60).
+		Fee("lbc", 1.5, testAddress).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream := helper.GetStream()
+	assert.Assert(t, stream != nil)
+	assert.Equal(t, helper.Claim.GetTitle(), "Test title")
+	assert.DeepEqual(t, helper.Claim.GetTags(), []string{"music", "live"})
+	assert.Equal(t, hex.EncodeToString(stream.GetSource().GetSdHash()), testSDHash)
+	assert.Equal(t, stream.GetSource().GetMediaType(), "video/mp4")
+	assert.Equal(t, stream.GetVideo().GetWidth(), uint32(1920))
+	assert.Equal(t, stream.GetFee().GetCurrency(), pb.Fee_LBC)
+	assert.Equal(t, stream.GetFee().GetAmount(), uint64(150000000))
+
+	languages := helper.Claim.GetLanguages()
+	assert.Equal(t, len(languages), 2)
+	assert.Equal(t, languages[0].GetLanguage(), pb.Language_en)
+	assert.Equal(t, languages[0].GetRegion(), pb.Location_US)
+	assert.Equal(t, languages[1].GetLanguage(), pb.Language_zh)
+	assert.Equal(t, languages[1].GetScript(), pb.Language_Hans)
+	assert.Equal(t, languages[1].GetRegion(), pb.Location_CN)
+	assert.Equal(t, helper.Claim.GetLocations()[0].GetCountry(), pb.Location_US)
+
+	value, err := helper.CompileValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeClaimBytes(value, "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, decoded.Claim.GetTitle(), "Test title")
+	assert.Equal(t, hex.EncodeToString(decoded.GetStream().GetSource().GetSdHash()), testSDHash)
+}
+
+func TestNewStreamClaim_Invalid(t *testing.T) {
+	_, err := NewStreamClaim().
+		Languages("xx").
+		Location("XX", "", "").
+		Fee("DOGE", 1, testAddress).
+		Email("someone@example.com").
+		Build()
+	assert.Assert(t, err != nil)
+	for _, msg := range []string{"unknown language", "unknown country", "unknown fee currency", "channel claim", "sd hash"} {
+		assert.Assert(t, strings.Contains(err.Error(), msg), "expected '%s' in '%s'", msg, err.Error())
+	}
+}
+
+func TestNewChannelClaim(t *testing.T) {
+	privateKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewChannelClaim().Title("no key").Build()
+	assert.Assert(t, err != nil, "channel without a public key should not build")
+
+	helper, err := NewChannelClaim().
+		Title("Test Channel").
+		PublicKey(privateKey.PubKey()).
+		WebsiteURL("http://homepageurl.com").
+		Cover("http://testcoverurl.com").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := helper.CompileValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeClaimBytes(value, "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := decoded.GetPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Assert(t, publicKey.IsEqual(privateKey.PubKey()))
+}
+
+func TestNewRepostAndCollection(t *testing.T) {
+	repost, err := NewRepost(testClaimID).Title("repost").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, hex.EncodeToString(reverseBytes(repost.Claim.GetRepost().GetClaimHash())), testClaimID)
+
+	collection, err := NewCollection(testClaimID, "cf3f7c898af87cc69b06a6ac7899efb9a4878fdb").Title("list").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(collection.Claim.GetCollection().GetClaimReferences()), 2)
+
+	_, err = NewRepost("not a claim id").Build()
+	assert.Assert(t, err != nil)
+	_, err = NewCollection().Build()
+	assert.Assert(t, err != nil)
+}
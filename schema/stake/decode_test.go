@@ -2,8 +2,10 @@ package stake
 
 import (
 	"encoding/hex"
+	"strings"
 	"testing"
 
+	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"github.com/lbryio/lbry.go/v2/schema/keys"
 
 	pb "github.com/lbryio/types/v2/go"
@@ -103,3 +105,105 @@ func TestCreateChannelClaim(t *testing.T) {
 	}
 
 }
+
+// claimCorpus is values from the chain, made by lbrynet and the sdks before it
+var claimCorpus = []struct {
+	name   string
+	hex    string
+	format Format
+	signed bool
+}{
+	{"v2 unsigned stream", "000aa4010a8a010a30f1303989f58396694b0c5982c97f7e9d9435841d92aa13f4b80f671c27110c469babc4fbf4bd764155eaac089cfc49e8121454554d205045204d45524e45204c41472e6d703418cad0c8012209766964656f2f6d70343230c2c9389731e2a9568f66c78d703736a8c341015ada2e46f5dcc87aa6f08ab17c02df2121d9f6ef74055827a29dfc75801a044e6f6e6532040803180a5a0908b001109001188102421054554d205045204d45524e45204c41474a0944657369206c6f636b62020801", FormatProtobuf, false},
+	{"v2 channel", "00125a0a583056301006072a8648ce3d020106052b8104000a034200045a0343c155302280da01ae0001b7295241eb03c42a837acf92ccb9680892f7db50fd1d3c14b28bb594e304f05fc4ae7c1f222a85d1d1a3461b3cfb9906f66cb5", FormatProtobuf, false},
+	{"v2 signed stream", "015cb78e424a34fbf79b67f9107430427aa62373e69b4998a29ecec8f14a9e0a213a043ced8064c069d7e464b5fd3ccb92b45bd59b15c0e1bb27e3c366d43f86a9a6b5ad42647a1aad69a73ac50b19ae3ec978c2c70aa2010a99010a301c662f19abc461e7eddecf165adfa7fca569e209773f3db31241c1e297f0a8d5b3e4768828b065fbeb1d6776f61073f6121b3031202d20556e6d6173746572656420496d70756c7365732e377a187a22146170706c69636174696f6e2f782d6578742d377a32302eb61ea475017e28c013616a56c1219ba90dc35fffff453d9675146f648f66634e0d1516528d37aba9f5801229d9f2181a044e6f6e6542087465737420707562520062020801", FormatProtobuf, true},
+	{"v1 channel", raw_claims[0], FormatLegacyProtobuf, false},
+	{"v1 signed stream", raw_claims[1], FormatLegacyProtobuf, true},
+	{"v1 unsigned stream", raw_claims[2], FormatLegacyProtobuf, false},
+	{"v1 signed ytsync stream", raw_claims[3], FormatLegacyProtobuf, true},
+	{"json 0.0.1", "7b22666565223a207b224c4243223a207b22616d6f756e74223a20312e302c202261646472657373223a2022625077474139683775696a6f79357541767a565051773951794c6f595a6568484a6f227d7d2c20226465736372697074696f6e223a2022313030304d4220746573742066696c6520746f206d65617375726520646f776e6c6f6164207370656564206f6e204c627279207032702d6e6574776f726b2e222c20226c6963656e7365223a20224e6f6e65222c2022617574686f72223a2022726f6f74222c20226c616e6775616765223a2022456e676c697368222c20227469746c65223a2022313030304d4220737065656420746573742066696c65222c2022736f7572636573223a207b226c6272795f73645f68617368223a2022626439343033336431336634663339303837303837303163616635363562666130396366616466326633346661646634613733666238366232393564316232316137653634383035393934653435623566626336353066333062616334383734227d2c2022636f6e74656e742d74797065223a20226170706c69636174696f6e2f6f637465742d73747265616d222c20227468756d626e61696c223a20222f686f6d65726f626572742f6c6272792f73706565642e6a7067227d", FormatJSON, false},
+}
+
+func TestDecodeClaimBytes_Corpus(t *testing.T) {
+	for _, test := range claimCorpus {
+		t.Run(test.name, func(t *testing.T) {
+			helper, err := DecodeClaimHex(test.hex, "lbrycrd_main")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if helper.Format() != test.format {
+				t.Errorf("expected format %s, got %s", test.format, helper.Format())
+			}
+			if helper.IsSigned() != test.signed {
+				t.Errorf("expected signed to be %t", test.signed)
+			}
+			if !helper.IsClaim() {
+				t.Error("expected a claim")
+			}
+		})
+	}
+}
+
+func TestDecodeClaimBytes_Malformed(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+		err  error
+	}{
+		{"empty", "", ErrNothingToDecode},
+		{"not hex", "zz", ErrInvalidHex},
+		{"odd length hex", "0", ErrInvalidHex},
+		{"truncated signature", "01" + strings.Repeat("ab", 40), ErrTruncatedSignature},
+		{"garbage", "deadbeef", ErrNoMatchingVersion},
+		{"bad json", "7b2276657222", ErrNoMatchingVersion},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := DecodeClaimHex(test.hex, "lbrycrd_main")
+			if !errors.Is(err, test.err) {
+				t.Errorf("expected error '%v', got '%v'", test.err, err)
+			}
+		})
+	}
+}
+
+func TestDecodeSupportHex(t *testing.T) {
+	support := &StakeHelper{Support: &pb.Support{Emoji: "👍"}, Version: NoSig}
+	value, err := support.CompileValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := DecodeSupportHex(hex.EncodeToString(value), "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.IsSupport() || decoded.Support.GetEmoji() != "👍" {
+		t.Error("support was not decoded")
+	}
+	if decoded.Format() != FormatProtobuf {
+		t.Errorf("expected format %s, got %s", FormatProtobuf, decoded.Format())
+	}
+
+	_, err = DecodeSupportHex("", "lbrycrd_main")
+	if !errors.Is(err, ErrNothingToDecode) {
+		t.Errorf("expected error '%v', got '%v'", ErrNothingToDecode, err)
+	}
+
+	_, err = DecodeSupportHex("01"+strings.Repeat("00", 40), "lbrycrd_main")
+	if !errors.Is(err, ErrTruncatedSignature) {
+		t.Errorf("expected error '%v', got '%v'", ErrTruncatedSignature, err)
+	}
+
+	// a legacy claim is not a support, even though it decodes as a claim
+	legacyClaim, err := hex.DecodeString(raw_claims[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeClaimBytes(legacyClaim, "lbrycrd_main"); err != nil {
+		t.Fatal(err)
+	}
+	if support, err := DecodeSupportBytes(legacyClaim, "lbrycrd_main"); err == nil {
+		t.Errorf("legacy claim decoded as a support: %+v", support)
+	}
+}
@@ -0,0 +1,544 @@
+package stake
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	pb "github.com/lbryio/types/v2/go"
+
+	"github.com/btcsuite/btcutil/base58"
+)
+
+const gpsPrecision = 10000000 // locations store coordinates as integers, in units of 1e-7 degrees
+
+// the json types below follow the claim output of the SDK's resolve and claim_search. numbers that can be large or
+// fractional (sizes, fees, times, coordinates) are strings, like they are in the SDK.
+
+type stakeJSON struct {
+	ValueType      string              `json:"value_type"`
+	Value          stakeValueJSON      `json:"value"`
+	SigningChannel *signingChannelJSON `json:"signing_channel,omitempty"`
+	Signature      string              `json:"signature,omitempty"`
+}
+
+type signingChannelJSON struct {
+	ClaimID string `json:"claim_id"`
+}
+
+type stakeValueJSON struct {
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Thumbnail   *sourceJSON    `json:"thumbnail,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Languages   []string       `json:"languages,omitempty"`
+	Locations   []locationJSON `json:"locations,omitempty"`
+
+	// streams
+	Source      *sourceJSON   `json:"source,omitempty"`
+	Author      string        `json:"author,omitempty"`
+	License     string        `json:"license,omitempty"`
+	LicenseURL  string        `json:"license_url,omitempty"`
+	ReleaseTime string        `json:"release_time,omitempty"`
+	Fee         *feeJSON      `json:"fee,omitempty"`
+	StreamType  string        `json:"stream_type,omitempty"`
+	Video       *videoJSON    `json:"video,omitempty"`
+	Audio       *audioJSON    `json:"audio,omitempty"`
+	Image       *imageJSON    `json:"image,omitempty"`
+	Software    *softwareJSON `json:"software,omitempty"`
+
+	// channels
+	PublicKey  string      `json:"public_key,omitempty"`
+	Email      string      `json:"email,omitempty"`
+	WebsiteURL string      `json:"website_url,omitempty"`
+	Cover      *sourceJSON `json:"cover,omitempty"`
+	Featured   []string    `json:"featured,omitempty"`
+
+	// reposts
+	ClaimID string `json:"claim_id,omitempty"`
+
+	// collections
+	Claims []string `json:"claims,omitempty"`
+
+	// supports
+	Emoji string `json:"emoji,omitempty"`
+}
+
+type sourceJSON struct {
+	Hash       string `json:"hash,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Size       string `json:"size,omitempty"`
+	MediaType  string `json:"media_type,omitempty"`
+	URL        string `json:"url,omitempty"`
+	SDHash     string `json:"sd_hash,omitempty"`
+	BTInfohash string `json:"bt_infohash,omitempty"`
+}
+
+type feeJSON struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+	Address  string `json:"address,omitempty"`
+}
+
+type locationJSON struct {
+	Country   string `json:"country,omitempty"`
+	State     string `json:"state,omitempty"`
+	City      string `json:"city,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Latitude  string `json:"latitude,omitempty"`
+	Longitude string `json:"longitude,omitempty"`
+}
+
+type videoJSON struct {
+	Width    uint32 `json:"width,omitempty"`
+	Height   uint32 `json:"height,omitempty"`
+	Duration uint32 `json:"duration,omitempty"`
+}
+
+type audioJSON struct {
+	Duration uint32 `json:"duration,omitempty"`
+}
+
+type imageJSON struct {
+	Width  uint32 `json:"width,omitempty"`
+	Height uint32 `json:"height,omitempty"`
+}
+
+type softwareJSON struct {
+	OS string `json:"os,omitempty"`
+}
+
+// MarshalJSON encodes the claim or support the way the SDK returns it from resolve. Legacy claims are encoded as
+// the v2 claims they migrate to.
+func (c *StakeHelper) MarshalJSON() ([]byte, error) {
+	s := stakeJSON{}
+
+	if c.IsSupport() {
+		s.ValueType = "support"
+		s.Value.Emoji = c.Support.GetEmoji()
+	} else if c.Claim != nil {
+		err := claimToJSON(c.Claim, &s)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, errors.Err("not initialized")
+	}
+
+	if c.Version == WithSig && len(c.ClaimID) > 0 {
+		claimID := c.ClaimID
+		if c.LegacyClaim == nil {
+			claimID = reverseBytes(claimID) // v1 claims stored the claim id without reversing it
+		}
+		s.SigningChannel = &signingChannelJSON{ClaimID: hex.EncodeToString(claimID)}
+		s.Signature = hex.EncodeToString(c.Signature)
+	}
+
+	return json.Marshal(s)
+}
+
+func claimToJSON(claim *pb.Claim, s *stakeJSON) error {
+	v := &s.Value
+	v.Title = claim.GetTitle()
+	v.Description = claim.GetDescription()
+	v.Thumbnail = sourceToJSON(claim.GetThumbnail())
+	v.Tags = claim.GetTags()
+	for _, l := range claim.GetLanguages() {
+		if l.GetLanguage() == pb.Language_UNKNOWN_LANGUAGE {
+			continue // claims migrated from v1 can have an unknown language, which the SDK leaves out
+		}
+		v.Languages = append(v.Languages, LanguageTag(l))
+	}
+	for _, l := range claim.GetLocations() {
+		v.Locations = append(v.Locations, locationToJSON(l))
+	}
+
+	switch {
+	case claim.GetStream() != nil:
+		s.ValueType = "stream"
+		stream := claim.GetStream()
+		v.Source = sourceToJSON(stream.GetSource())
+		v.Author = stream.GetAuthor()
+		v.License = stream.GetLicense()
+		v.LicenseURL = stream.GetLicenseUrl()
+		if stream.GetReleaseTime() != 0 {
+			v.ReleaseTime = strconv.FormatInt(stream.GetReleaseTime(), 10)
+		}
+		if fee := stream.GetFee(); fee != nil {
+			v.Fee = &feeJSON{
+				Amount:   feeAmountFromUnits(fee.GetCurrency(), fee.GetAmount()),
+				Currency: fee.GetCurrency().String(),
+			}
+			if len(fee.GetAddress()) > 0 {
+				v.Fee.Address = base58.Encode(fee.GetAddress())
+			}
+		}
+		switch {
+		case stream.GetVideo() != nil:
+			v.StreamType = "video"
+			v.Video = &videoJSON{Width: stream.GetVideo().GetWidth(), Height: stream.GetVideo().GetHeight(), Duration: stream.GetVideo().GetDuration()}
+		case stream.GetAudio() != nil:
+			v.StreamType = "audio"
+			v.Audio = &audioJSON{Duration: stream.GetAudio().GetDuration()}
+		case stream.GetImage() != nil:
+			v.StreamType = "image"
+			v.Image = &imageJSON{Width: stream.GetImage().GetWidth(), Height: stream.GetImage().GetHeight()}
+		case stream.GetSoftware() != nil:
+			v.StreamType = "software"
+			v.Software = &softwareJSON{OS: stream.GetSoftware().GetOs()}
+		}
+
+	case claim.GetChannel() != nil:
+		s.ValueType = "channel"
+		channel := claim.GetChannel()
+		if len(channel.GetPublicKey()) > 0 {
+			v.PublicKey = hex.EncodeToString(channel.GetPublicKey())
+		}
+		v.Email = channel.GetEmail()
+		v.WebsiteURL = channel.GetWebsiteUrl()
+		v.Cover = sourceToJSON(channel.GetCover())
+		v.Featured = claimListToJSON(channel.GetFeatured())
+
+	case claim.GetRepost() != nil:
+		s.ValueType = "repost"
+		v.ClaimID = hex.EncodeToString(reverseBytes(claim.GetRepost().GetClaimHash()))
+
+	case claim.GetCollection() != nil:
+		s.ValueType = "collection"
+		v.Claims = claimListToJSON(claim.GetCollection())
+
+	default:
+		return errors.Err("claim has no type")
+	}
+
+	return nil
+}
+
+// UnmarshalJSON decodes a claim or support in the format MarshalJSON produces
+func (c *StakeHelper) UnmarshalJSON(b []byte) error {
+	var s stakeJSON
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return errors.Err(err)
+	}
+
+	helper := StakeHelper{Version: NoSig}
+
+	if s.ValueType == "support" {
+		helper.Support = &pb.Support{Emoji: s.Value.Emoji}
+	} else {
+		helper.Claim, err = claimFromJSON(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.SigningChannel != nil {
+		claimID, err := hex.DecodeString(s.SigningChannel.ClaimID)
+		if err != nil || len(claimID) != claimIDLength {
+			return errors.Err("invalid signing channel claim id '%s'", s.SigningChannel.ClaimID)
+		}
+		signature, err := hex.DecodeString(s.Signature)
+		if err != nil || len(signature) != 64 {
+			return errors.Err("invalid signature")
+		}
+		helper.Version = WithSig
+		helper.ClaimID = reverseBytes(claimID)
+		helper.Signature = signature
+	}
+
+	*c = helper
+	return nil
+}
+
+func claimFromJSON(s stakeJSON) (*pb.Claim, error) {
+	v := s.Value
+	claim := &pb.Claim{
+		Title:       v.Title,
+		Description: v.Description,
+		Tags:        v.Tags,
+	}
+
+	var err error
+	claim.Thumbnail, err = sourceFromJSON(v.Thumbnail)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range v.Languages {
+		l, err := ParseLanguage(tag)
+		if err != nil {
+			return nil, err
+		}
+		claim.Languages = append(claim.Languages, l)
+	}
+	for _, l := range v.Locations {
+		location, err := locationFromJSON(l)
+		if err != nil {
+			return nil, err
+		}
+		claim.Locations = append(claim.Locations, location)
+	}
+
+	switch s.ValueType {
+	case "stream":
+		stream := &pb.Stream{
+			Author:     v.Author,
+			License:    v.License,
+			LicenseUrl: v.LicenseURL,
+		}
+		stream.Source, err = sourceFromJSON(v.Source)
+		if err != nil {
+			return nil, err
+		}
+		if v.ReleaseTime != "" {
+			stream.ReleaseTime, err = strconv.ParseInt(v.ReleaseTime, 10, 64)
+			if err != nil {
+				return nil, errors.Err("invalid release time '%s'", v.ReleaseTime)
+			}
+		}
+		if v.Fee != nil {
+			stream.Fee, err = feeFromJSON(*v.Fee)
+			if err != nil {
+				return nil, err
+			}
+		}
+		switch {
+		case v.Video != nil:
+			stream.Type = &pb.Stream_Video{Video: &pb.Video{Width: v.Video.Width, Height: v.Video.Height, Duration: v.Video.Duration}}
+		case v.Audio != nil:
+			stream.Type = &pb.Stream_Audio{Audio: &pb.Audio{Duration: v.Audio.Duration}}
+		case v.Image != nil:
+			stream.Type = &pb.Stream_Image{Image: &pb.Image{Width: v.Image.Width, Height: v.Image.Height}}
+		case v.Software != nil:
+			stream.Type = &pb.Stream_Software{Software: &pb.Software{Os: v.Software.OS}}
+		}
+		claim.Type = &pb.Claim_Stream{Stream: stream}
+
+	case "channel":
+		channel := &pb.Channel{
+			Email:      v.Email,
+			WebsiteUrl: v.WebsiteURL,
+		}
+		channel.PublicKey, err = hex.DecodeString(v.PublicKey)
+		if err != nil {
+			return nil, errors.Err("invalid public key: %s", err.Error())
+		}
+		channel.Cover, err = sourceFromJSON(v.Cover)
+		if err != nil {
+			return nil, err
+		}
+		if len(v.Featured) > 0 {
+			channel.Featured, err = claimListFromJSON(v.Featured)
+			if err != nil {
+				return nil, err
+			}
+		}
+		claim.Type = &pb.Claim_Channel{Channel: channel}
+
+	case "repost":
+		hash, err := claimHashFromJSON(v.ClaimID)
+		if err != nil {
+			return nil, err
+		}
+		claim.Type = &pb.Claim_Repost{Repost: &pb.ClaimReference{ClaimHash: hash}}
+
+	case "collection":
+		list, err := claimListFromJSON(v.Claims)
+		if err != nil {
+			return nil, err
+		}
+		claim.Type = &pb.Claim_Collection{Collection: list}
+
+	default:
+		return nil, errors.Err("unknown value type '%s'", s.ValueType)
+	}
+
+	return claim, nil
+}
+
+func sourceToJSON(s *pb.Source) *sourceJSON {
+	if s == nil {
+		return nil
+	}
+	j := &sourceJSON{
+		Name:      s.GetName(),
+		MediaType: s.GetMediaType(),
+		URL:       s.GetUrl(),
+	}
+	if len(s.GetHash()) > 0 {
+		j.Hash = hex.EncodeToString(s.GetHash())
+	}
+	if s.GetSize() > 0 {
+		j.Size = strconv.FormatUint(s.GetSize(), 10)
+	}
+	if len(s.GetSdHash()) > 0 {
+		j.SDHash = hex.EncodeToString(s.GetSdHash())
+	}
+	if len(s.GetBtInfohash()) > 0 {
+		j.BTInfohash = hex.EncodeToString(s.GetBtInfohash())
+	}
+	return j
+}
+
+func sourceFromJSON(j *sourceJSON) (*pb.Source, error) {
+	if j == nil {
+		return nil, nil
+	}
+	s := &pb.Source{
+		Name:      j.Name,
+		MediaType: j.MediaType,
+		Url:       j.URL,
+	}
+	var err error
+	if s.Hash, err = hex.DecodeString(j.Hash); err != nil {
+		return nil, errors.Err("invalid source hash: %s", err.Error())
+	}
+	if s.SdHash, err = hex.DecodeString(j.SDHash); err != nil {
+		return nil, errors.Err("invalid sd hash: %s", err.Error())
+	}
+	if s.BtInfohash, err = hex.DecodeString(j.BTInfohash); err != nil {
+		return nil, errors.Err("invalid bt infohash: %s", err.Error())
+	}
+	if j.Size != "" {
+		if s.Size, err = strconv.ParseUint(j.Size, 10, 64); err != nil {
+			return nil, errors.Err("invalid source size '%s'", j.Size)
+		}
+	}
+	// hex.DecodeString returns an empty slice for an empty string, but proto leaves unset fields nil
+	if len(s.Hash) == 0 {
+		s.Hash = nil
+	}
+	if len(s.SdHash) == 0 {
+		s.SdHash = nil
+	}
+	if len(s.BtInfohash) == 0 {
+		s.BtInfohash = nil
+	}
+	return s, nil
+}
+
+func feeFromJSON(j feeJSON) (*pb.Fee, error) {
+	c, ok := pb.Fee_Currency_value[strings.ToUpper(j.Currency)]
+	if !ok || c == int32(pb.Fee_UNKNOWN_CURRENCY) {
+		return nil, errors.Err("unknown fee currency '%s'", j.Currency)
+	}
+	amount, ok := new(big.Rat).SetString(j.Amount)
+	if !ok || amount.Sign() < 0 {
+		return nil, errors.Err("invalid fee amount '%s'", j.Amount)
+	}
+	fee := &pb.Fee{
+		Currency: pb.Fee_Currency(c),
+		Amount:   feeAmountToUnitsRat(pb.Fee_Currency(c), amount),
+	}
+	if j.Address != "" {
+		fee.Address = base58.Decode(j.Address)
+		if len(fee.Address) != 25 {
+			return nil, errors.Err("invalid fee address '%s'", j.Address)
+		}
+	}
+	return fee, nil
+}
+
+// feeAmountFromUnits formats a stored fee amount as a decimal string in whole units, e.g. "1.5"
+func feeAmountFromUnits(currency pb.Fee_Currency, units uint64) string {
+	return decimalString(new(big.Rat).SetFrac(new(big.Int).SetUint64(units), big.NewInt(feeUnits(currency))), 8)
+}
+
+func feeAmountToUnitsRat(currency pb.Fee_Currency, amount *big.Rat) uint64 {
+	units := new(big.Rat).Mul(amount, new(big.Rat).SetInt64(feeUnits(currency)))
+	return new(big.Int).Quo(units.Num(), units.Denom()).Uint64()
+}
+
+// decimalString formats r with up to prec decimal places and no trailing zeros
+func decimalString(r *big.Rat, prec int) string {
+	return strings.TrimRight(strings.TrimRight(r.FloatString(prec), "0"), ".")
+}
+
+func locationToJSON(l *pb.Location) locationJSON {
+	j := locationJSON{
+		State: l.GetState(),
+		City:  l.GetCity(),
+		Code:  l.GetCode(),
+	}
+	if l.GetCountry() != pb.Location_UNKNOWN_COUNTRY {
+		j.Country = l.GetCountry().String()
+	}
+	if l.GetLatitude() != 0 || l.GetLongitude() != 0 {
+		j.Latitude = decimalString(big.NewRat(int64(l.GetLatitude()), gpsPrecision), 7)
+		j.Longitude = decimalString(big.NewRat(int64(l.GetLongitude()), gpsPrecision), 7)
+	}
+	return j
+}
+
+func locationFromJSON(j locationJSON) (*pb.Location, error) {
+	l := &pb.Location{
+		State: j.State,
+		City:  j.City,
+		Code:  j.Code,
+	}
+	if j.Country != "" {
+		c, err := ParseCountry(j.Country)
+		if err != nil {
+			return nil, err
+		}
+		l.Country = c
+	}
+	for _, coord := range []struct {
+		value string
+		dest  *int32
+	}{{j.Latitude, &l.Latitude}, {j.Longitude, &l.Longitude}} {
+		if coord.value == "" {
+			continue
+		}
+		r, ok := new(big.Rat).SetString(coord.value)
+		if !ok {
+			return nil, errors.Err("invalid coordinate '%s'", coord.value)
+		}
+		r.Mul(r, new(big.Rat).SetInt64(gpsPrecision))
+		*coord.dest = int32(new(big.Int).Quo(r.Num(), r.Denom()).Int64())
+	}
+	return l, nil
+}
+
+func claimListToJSON(list *pb.ClaimList) []string {
+	var ids []string
+	for _, ref := range list.GetClaimReferences() {
+		ids = append(ids, hex.EncodeToString(reverseBytes(ref.GetClaimHash())))
+	}
+	return ids
+}
+
+func claimListFromJSON(ids []string) (*pb.ClaimList, error) {
+	list := &pb.ClaimList{ListType: pb.ClaimList_COLLECTION}
+	for _, id := range ids {
+		hash, err := claimHashFromJSON(id)
+		if err != nil {
+			return nil, err
+		}
+		list.ClaimReferences = append(list.ClaimReferences, &pb.ClaimReference{ClaimHash: hash})
+	}
+	return list, nil
+}
+
+func claimHashFromJSON(claimID string) ([]byte, error) {
+	id, err := hex.DecodeString(claimID)
+	if err != nil || len(id) != claimIDLength {
+		return nil, errors.Err("invalid claim id '%s'", claimID)
+	}
+	return reverseBytes(id), nil
+}
+
+// LanguageTag formats a language as a tag like "en", "en-US", or "zh-Hans-CN"
+func LanguageTag(l *pb.Language) string {
+	tag := l.GetLanguage().String()
+	if l.GetScript() != pb.Language_UNKNOWN_SCRIPT {
+		tag += "-" + l.GetScript().String()
+	}
+	if l.GetRegion() != pb.Location_UNKNOWN_COUNTRY {
+		tag += "-" + l.GetRegion().String()
+	}
+	return tag
+}
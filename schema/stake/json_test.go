@@ -0,0 +1,128 @@
+package stake
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"gotest.tools/assert"
+)
+
+func TestStakeHelperJSON_Stream(t *testing.T) {
+	helper, err := NewStreamClaim().
+		Title("Test title").
+		Tags("music").
+		Languages("zh-Hans-CN").
+		Location("US", "NJ", "").
+		SDHash(testSDHash).
+		File("video.mp4", 1024, "video/mp4").
+		Video(1920, 1080, 60).
+		Fee("LBC", 1.5, testAddress).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j, err := json.Marshal(helper)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var generic struct {
+		ValueType string `json:"value_type"`
+		Value     struct {
+			Languages []string `json:"languages"`
+			Source    struct {
+				SDHash string `json:"sd_hash"`
+				Size   string `json:"size"`
+			} `json:"source"`
+			Fee struct {
+				Amount   string `json:"amount"`
+				Currency string `json:"currency"`
+				Address  string `json:"address"`
+			} `json:"fee"`
+			StreamType string `json:"stream_type"`
+		} `json:"value"`
+	}
+	err = json.Unmarshal(j, &generic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, generic.ValueType, "stream")
+	assert.Equal(t, generic.Value.Languages[0], "zh-Hans-CN")
+	assert.Equal(t, generic.Value.Source.SDHash, testSDHash)
+	assert.Equal(t, generic.Value.Source.Size, "1024")
+	assert.Equal(t, generic.Value.Fee.Amount, "1.5")
+	assert.Equal(t, generic.Value.Fee.Currency, "LBC")
+	assert.Equal(t, generic.Value.Fee.Address, testAddress)
+	assert.Equal(t, generic.Value.StreamType, "video")
+
+	decoded := &StakeHelper{}
+	err = json.Unmarshal(j, decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Assert(t, proto.Equal(decoded.Claim, helper.Claim), "claim changed after json round trip")
+}
+
+func TestStakeHelperJSON_Types(t *testing.T) {
+	channel, err := DecodeClaimHex(raw_claims[0], "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repost, err := NewRepost(testClaimID).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection, err := NewCollection(testClaimID).Title("list").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for valueType, helper := range map[string]*StakeHelper{"channel": channel, "repost": repost, "collection": collection} {
+		j, err := json.Marshal(helper)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := &StakeHelper{}
+		err = json.Unmarshal(j, decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Assert(t, proto.Equal(decoded.Claim, helper.Claim), "%s changed after json round trip", valueType)
+	}
+}
+
+func TestStakeHelperJSON_Signed(t *testing.T) {
+	claim, err := DecodeClaimHex(raw_claims[1], "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := json.Marshal(claim)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var generic struct {
+		SigningChannel struct {
+			ClaimID string `json:"claim_id"`
+		} `json:"signing_channel"`
+	}
+	err = json.Unmarshal(j, &generic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, generic.SigningChannel.ClaimID, "251305ca93d4dbedb50dceb282ebcb7b07b7ac65")
+}
+
+func TestStakeHelperJSON_Invalid(t *testing.T) {
+	for _, j := range []string{
+		`{"value_type": "unknown", "value": {}}`,
+		`{"value_type": "stream", "value": {"fee": {"amount": "one", "currency": "LBC"}}}`,
+		`{"value_type": "stream", "value": {"languages": ["xx"]}}`,
+		`{"value_type": "repost", "value": {"claim_id": "abc"}}`,
+	} {
+		err := json.Unmarshal([]byte(j), &StakeHelper{})
+		assert.Assert(t, err != nil, "expected an error for %s", j)
+	}
+}
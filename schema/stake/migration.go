@@ -56,6 +56,38 @@ func setMetaData(claim *pb.Claim, author string, description string, language pb
 	}
 }
 
+// Migrate turns a legacy claim into the v2 claim it maps to, so it can be published again. Streams keep their
+// source, fee, and metadata, and certificates become channels. A v1 signature covers the v1 serialization, so it
+// doesn't carry over: a signed claim comes back unsigned, but ClaimID still holds the channel it was signed with so it
+// can be passed to Sign. Claims that were migrated from json when they were decoded get their v2 payload filled in.
+func (c *StakeHelper) Migrate() error {
+	if c.IsSupport() {
+		return errors.Err("supports have no legacy format to migrate from")
+	}
+
+	if c.LegacyClaim != nil {
+		claim, err := migrateV1PBClaim(*c.LegacyClaim)
+		if err != nil {
+			return errors.Prefix(migrationErrorMessage, err)
+		}
+
+		var channelClaimID []byte
+		if len(c.ClaimID) > 0 {
+			channelClaimID = reverseBytes(c.ClaimID) // v1 claims stored the claim id without reversing it
+		}
+		*c = StakeHelper{Claim: claim, ClaimID: channelClaimID, Version: NoSig}
+	} else if c.Claim == nil {
+		return errors.Err("not initialized")
+	}
+
+	payload, err := c.serialized()
+	if err != nil {
+		return err
+	}
+	c.Payload = payload
+	return nil
+}
+
 func migrateV1PBClaim(vClaim v1pb.Claim) (*pb.Claim, error) {
 	if *vClaim.ClaimType == v1pb.Claim_streamType {
 		return migrateV1PBStream(vClaim)
@@ -280,3 +280,53 @@ func TestMigrationFromV1UnsignedWithFee(t *testing.T) {
 	assert.Assert(t, claim.GetStream().GetFee().GetCurrency().String() == "LBC")
 
 }
+
+func TestMigrate(t *testing.T) {
+	claim, err := DecodeClaimHex(raw_claims[1], "lbrycrd_main") // signed v1 stream
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = claim.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, claim.Format(), FormatProtobuf)
+	assert.Assert(t, !claim.IsSigned())
+	assert.Equal(t, hex.EncodeToString(reverseBytes(claim.ClaimID)), "251305ca93d4dbedb50dceb282ebcb7b07b7ac65")
+	assert.Equal(t, claim.Claim.GetTitle(), "Game of life")
+	assert.Equal(t, claim.GetStream().GetSource().GetMediaType(), "image/gif")
+
+	value, err := claim.CompileValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, value[0], NoSig.byte())
+	assert.Assert(t, bytes.Equal(value[1:], claim.Payload))
+
+	migrated, err := DecodeClaimBytes(value, "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, migrated.Format(), FormatProtobuf)
+	assert.Equal(t, migrated.Claim.GetTitle(), "Game of life")
+	assert.Assert(t, bytes.Equal(migrated.GetStream().GetSource().GetSdHash(), claim.GetStream().GetSource().GetSdHash()))
+}
+
+func TestMigrate_Channel(t *testing.T) {
+	channel, err := DecodeClaimHex(raw_claims[0], "lbrycrd_main") // v1 certificate
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := channel.Claim.GetChannel().GetPublicKey()
+
+	err = channel.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, channel.Format(), FormatProtobuf)
+	assert.Assert(t, bytes.Equal(channel.Claim.GetChannel().GetPublicKey(), publicKey))
+
+	err = (&StakeHelper{}).Migrate()
+	assert.Assert(t, err != nil, "expected an error migrating an empty helper")
+}
@@ -2,69 +2,132 @@ package stake
 
 import (
 	"encoding/hex"
+	"sort"
 
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 	legacy "github.com/lbryio/types/v1/go"
 	pb "github.com/lbryio/types/v2/go"
 
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protowire"
+	protov2 "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
+// marshalCanonical serializes m the same way every time: known fields in field number order, map entries sorted by
+// key, and unknown fields last, as they were read. This is what lbrynet's python protobuf does, so the bytes match the
+// ones another implementation signed. Go's marshaler puts oneof fields (like a claim's stream) after the others, so
+// its output is put in order afterwards.
+func marshalCanonical(m proto.Message) ([]byte, error) {
+	msg := proto.MessageV2(m)
+	b, err := protov2.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return canonicalOrder(b, msg.ProtoReflect().Descriptor())
+}
+
+// canonicalOrder puts the fields in b, an encoded message of type md, in field number order, and does the same for
+// every sub-message. Values of a repeated field keep their order, and unknown fields stay at the end.
+func canonicalOrder(b []byte, md protoreflect.MessageDescriptor) ([]byte, error) {
+	type field struct {
+		num protowire.Number
+		raw []byte
+	}
+	var known, unknown []field
+	size := len(b)
+
+	for len(b) > 0 {
+		num, typ, tagLen := protowire.ConsumeTag(b)
+		if tagLen < 0 {
+			return nil, errors.Err(protowire.ParseError(tagLen))
+		}
+		valLen := protowire.ConsumeFieldValue(num, typ, b[tagLen:])
+		if valLen < 0 {
+			return nil, errors.Err(protowire.ParseError(valLen))
+		}
+		raw := b[:tagLen+valLen]
+		b = b[tagLen+valLen:]
+
+		fd := md.Fields().ByNumber(num)
+		if fd == nil {
+			unknown = append(unknown, field{num, raw})
+			continue
+		}
+		if typ == protowire.BytesType && fd.Kind() == protoreflect.MessageKind {
+			sub, _ := protowire.ConsumeBytes(raw[tagLen:])
+			ordered, err := canonicalOrder(sub, fd.Message())
+			if err != nil {
+				return nil, err
+			}
+			raw = protowire.AppendBytes(protowire.AppendTag(nil, num, typ), ordered)
+		}
+		known = append(known, field{num, raw})
+	}
+
+	sort.SliceStable(known, func(i, j int) bool { return known[i].num < known[j].num })
+
+	out := make([]byte, 0, size)
+	for _, f := range append(known, unknown...) {
+		out = append(out, f.raw...)
+	}
+	return out, nil
+}
+
 func (c *StakeHelper) serialized() ([]byte, error) {
+	if c.IsSupport() {
+		// a support with no fields is still a valid value, e.g. a signed support with no emoji
+		return marshalCanonical(c.getSupportProtobuf())
+	}
+
 	serialized := c.Claim.String() + c.Support.String()
 	if serialized == "" {
 		return nil, errors.Err("not initialized")
 	}
 
 	if c.LegacyClaim != nil {
-		return proto.Marshal(c.getLegacyProtobuf())
-	} else if c.IsSupport() {
-		return proto.Marshal(c.getSupportProtobuf())
+		return marshalCanonical(c.getLegacyProtobuf())
 	}
 
-	return proto.Marshal(c.getClaimProtobuf())
+	return marshalCanonical(c.getClaimProtobuf())
 }
 
-func (c *StakeHelper) getClaimProtobuf() *pb.Claim {
-	claim := &pb.Claim{
-		Title:       c.Claim.GetTitle(),
-		Description: c.Claim.GetDescription(),
-		Thumbnail:   c.Claim.GetThumbnail(),
-		Tags:        c.Claim.GetTags(),
-		Languages:   c.Claim.GetLanguages(),
-		Locations:   c.Claim.GetLocations(),
-	}
-	if c.Claim.GetChannel() != nil {
-		claim.Type = &pb.Claim_Channel{Channel: c.Claim.GetChannel()}
-	} else if c.GetStream() != nil {
-		claim.Type = &pb.Claim_Stream{Stream: c.GetStream()}
-	} else if c.Claim.GetCollection() != nil {
-		claim.Type = &pb.Claim_Collection{Collection: c.Claim.GetCollection()}
-	} else if c.Claim.GetRepost() != nil {
-		claim.Type = &pb.Claim_Repost{Repost: c.Claim.GetRepost()}
+// ClaimHash returns the double sha256 of the serialized value, i.e. of what CompileValue returns
+func (c *StakeHelper) ClaimHash() ([]byte, error) {
+	value, err := c.CompileValue()
+	if err != nil {
+		return nil, err
 	}
+	return chainhash.DoubleHashB(value), nil
+}
 
-	return claim
+// getClaimProtobuf copies the whole claim, including fields this version doesn't know about, for the same reason as
+// getSupportProtobuf
+func (c *StakeHelper) getClaimProtobuf() *pb.Claim {
+	if c.Claim == nil {
+		return &pb.Claim{}
+	}
+	return proto.Clone(c.Claim).(*pb.Claim)
 }
 
+// getSupportProtobuf copies the whole support, including fields this version doesn't know about, so a support
+// decoded from the chain serializes to the same bytes it was signed with
 func (c *StakeHelper) getSupportProtobuf() *pb.Support {
-	return &pb.Support{
-		Emoji:                c.Support.GetEmoji(),
-		XXX_NoUnkeyedLiteral: struct{}{},
-		XXX_unrecognized:     nil,
-		XXX_sizecache:        0,
+	if c.Support == nil {
+		return &pb.Support{}
 	}
+	return proto.Clone(c.Support).(*pb.Support)
 }
 
 func (c *StakeHelper) getLegacyProtobuf() *legacy.Claim {
+	claim := proto.Clone(c.LegacyClaim).(*legacy.Claim)
+	// version and type are required, so they're always written even if they were missing
 	v := c.LegacyClaim.GetVersion()
 	t := c.LegacyClaim.GetClaimType()
-	return &legacy.Claim{
-		Version:            &v,
-		ClaimType:          &t,
-		Stream:             c.LegacyClaim.GetStream(),
-		Certificate:        c.LegacyClaim.GetCertificate(),
-		PublisherSignature: c.LegacyClaim.GetPublisherSignature()}
+	claim.Version = &v
+	claim.ClaimType = &t
+	return claim
 }
 
 func (c *StakeHelper) serializedHexString() (string, error) {
@@ -77,6 +140,10 @@ func (c *StakeHelper) serializedHexString() (string, error) {
 }
 
 func (c *StakeHelper) serializedNoSignature() ([]byte, error) {
+	if c.IsSupport() {
+		// the signature isn't part of a support's protobuf, so there's nothing to remove
+		return c.serialized()
+	}
 	if c.Claim.String() == "" && c.Support.String() == "" {
 		return nil, errors.Err("not initialized")
 	}
@@ -88,18 +155,10 @@ func (c *StakeHelper) serializedNoSignature() ([]byte, error) {
 		return serialized, nil
 	} else {
 		if c.LegacyClaim != nil {
-			clone := &legacy.Claim{}
-			proto.Merge(clone, c.getLegacyProtobuf())
-			proto.ClearAllExtensions(clone.PublisherSignature)
+			clone := c.getLegacyProtobuf()
 			clone.PublisherSignature = nil
-			return proto.Marshal(clone)
-		} else if c.IsSupport() {
-			clone := &pb.Support{}
-			proto.Merge(clone, c.getSupportProtobuf())
-			return proto.Marshal(clone)
+			return marshalCanonical(clone)
 		}
-		clone := &pb.Claim{}
-		proto.Merge(clone, c.getClaimProtobuf())
-		return proto.Marshal(clone)
+		return marshalCanonical(c.getClaimProtobuf())
 	}
 }
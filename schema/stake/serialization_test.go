@@ -0,0 +1,129 @@
+package stake
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	pb "github.com/lbryio/types/v2/go"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func TestCanonicalSerialization_Corpus(t *testing.T) {
+	for _, test := range claimCorpus {
+		if test.format != FormatProtobuf && test.format != FormatLegacyProtobuf {
+			continue // json claims are migrated, so they can't serialize to what they were
+		}
+		t.Run(test.name, func(t *testing.T) {
+			raw, err := hex.DecodeString(test.hex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			helper, err := DecodeClaimBytes(raw, "lbrycrd_main")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < 3; i++ {
+				value, err := helper.CompileValue()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(value, raw) {
+					t.Fatalf("serialized value doesn't match lbrynet's:\n%x\n%x", value, raw)
+				}
+			}
+
+			first := sha256.Sum256(raw)
+			want := sha256.Sum256(first[:])
+			hash, err := helper.ClaimHash()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(hash, want[:]) {
+				t.Errorf("expected claim hash %x, got %x", want, hash)
+			}
+		})
+	}
+}
+
+func TestCanonicalSerialization_FieldOrder(t *testing.T) {
+	// protobuf lets fields come in any order, so this is a valid claim with its description before its title
+	description, err := proto.Marshal(&pb.Claim{Description: "description"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, err := proto.Marshal(&pb.Claim{Title: "title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outOfOrder := append(append([]byte{byte(NoSig)}, description...), title...)
+
+	helper, err := DecodeClaimBytes(outOfOrder, "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serialized, err := helper.serializedNoSignature()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append(append([]byte{}, title...), description...)
+	if !bytes.Equal(serialized, want) {
+		t.Errorf("expected fields in field number order:\n%x\n%x", want, serialized)
+	}
+}
+
+func TestCanonicalSerialization_UnknownFields(t *testing.T) {
+	// field 99, varint 1. a field some newer implementation added
+	unknown := []byte{0x98, 0x06, 0x01}
+
+	t.Run("v2", func(t *testing.T) {
+		raw, err := hex.DecodeString(claimCorpus[0].hex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw = append(raw, unknown...)
+		helper, err := DecodeClaimBytes(raw, "lbrycrd_main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, err := helper.CompileValue()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(value, raw) {
+			t.Error("unknown fields were not kept")
+		}
+	})
+
+	t.Run("v1 without signature", func(t *testing.T) {
+		raw, err := hex.DecodeString(raw_claims[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw = append(raw, unknown...)
+		helper, err := DecodeClaimBytes(raw, "lbrycrd_main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !helper.IsSigned() {
+			t.Fatal("expected a signed claim")
+		}
+
+		noSig, err := helper.serializedNoSignature()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasSuffix(noSig, unknown) {
+			t.Error("unknown fields were not kept")
+		}
+		if bytes.Contains(noSig, helper.Signature) {
+			t.Error("signature was not removed")
+		}
+		if helper.LegacyClaim.GetPublisherSignature() == nil {
+			t.Error("removing the signature changed the claim")
+		}
+	})
+}
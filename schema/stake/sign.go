@@ -7,6 +7,7 @@ import (
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"github.com/lbryio/lbry.go/v2/schema/address"
 	"github.com/lbryio/lbry.go/v2/schema/keys"
+	legacy "github.com/lbryio/types/v1/go"
 
 	"github.com/btcsuite/btcd/btcec"
 )
@@ -89,6 +90,94 @@ func (c *StakeHelper) signV1(privKey btcec.PrivateKey, channel StakeHelper, clai
 	return &keys.Signature{Signature: *sig}, nil
 }
 
+// Sign signs the claim or support with the private key of the channel with the given claim id (hex, in the usual
+// display order). k is the claim address for legacy v1 claims, and the hash of the first input's outpoint (see
+// GetOutpointHash) for everything else. The channel claim id and signature are set on the helper, so CompileValue
+// returns the signed value.
+func (c *StakeHelper) Sign(channelPrivateKey btcec.PrivateKey, channelClaimID string, k string, blockchainName string) error {
+	claimIDBytes, err := hex.DecodeString(channelClaimID)
+	if err != nil {
+		return errors.Err(err)
+	}
+	if len(claimIDBytes) != 20 {
+		return errors.Err("channel claim id must be 20 bytes, got %d", len(claimIDBytes))
+	}
+
+	// any existing signature must be removed before the digest is computed
+	c.Signature = nil
+	if c.LegacyClaim != nil {
+		c.LegacyClaim.PublisherSignature = nil
+		c.ClaimID = claimIDBytes // v1 claims stored the claim id without reversing it
+	} else {
+		c.ClaimID = reverseBytes(claimIDBytes)
+		c.Payload, err = c.serialized()
+		if err != nil {
+			return err
+		}
+	}
+
+	digest, err := c.signatureDigest(k, blockchainName)
+	if err != nil {
+		return err
+	}
+
+	sig, err := channelPrivateKey.Sign(digest[:])
+	if err != nil {
+		return errors.Err(err)
+	}
+	signature, err := (&keys.Signature{Signature: *sig}).LBRYSDKEncode()
+	if err != nil {
+		return err
+	}
+
+	c.Signature = signature
+	c.Version = WithSig
+	if c.LegacyClaim != nil {
+		c.LegacyClaim.PublisherSignature = &legacy.Signature{
+			Version:       legacy.Signature__0_0_1.Enum(),
+			SignatureType: legacy.KeyType_SECP256k1.Enum(),
+			Signature:     signature,
+			CertificateId: c.ClaimID,
+		}
+	}
+
+	return nil
+}
+
+// signatureDigest returns the hash that the channel signs. v1 claims hash the claim address, the claim without its
+// signature, and the channel claim id. everything since hashes the first input outpoint, the channel claim id, and the
+// payload.
+func (c *StakeHelper) signatureDigest(k string, blockchainName string) ([32]byte, error) {
+	if len(c.ClaimID) != 20 {
+		return [32]byte{}, errors.Err("channel claim id must be 20 bytes, got %d", len(c.ClaimID))
+	}
+
+	if c.LegacyClaim != nil {
+		addressBytes, err := address.DecodeAddress(k, blockchainName)
+		if err != nil {
+			return [32]byte{}, errors.Prefix("V1 signing requires claim address and the decode failed with: ", err)
+		}
+		serializedNoSig, err := c.serializedNoSignature()
+		if err != nil {
+			return [32]byte{}, err
+		}
+		return keys.LegacyClaimDigest(addressBytes[:], serializedNoSig, c.ClaimID), nil
+	}
+
+	firstInputBytes, err := hex.DecodeString(k)
+	if err != nil {
+		return [32]byte{}, errors.Err(err)
+	}
+	payload := c.Payload
+	if payload == nil {
+		payload, err = c.serialized()
+		if err != nil {
+			return [32]byte{}, err
+		}
+	}
+	return keys.ClaimDigest(firstInputBytes, c.ClaimID, payload), nil
+}
+
 // rev reverses a byte slice. useful for switching endian-ness
 func reverseBytes(b []byte) []byte {
 	r := make([]byte, len(b))
@@ -194,3 +194,237 @@ func TestSignWithV1Channel(t *testing.T) {
 	assert.Assert(t, valid, "could not verify signature")
 
 }
+
+func TestStakeHelperSign(t *testing.T) {
+	privateKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	channelClaimID := "cf3f7c898af87cc69b06a6ac7899efb9a4878fdb" //Fake
+	outpointHash, err := GetOutpointHash("4c1df9e022e396859175f9bfa69b38e444db10fb53355fa99a0989a83bcdb82f", 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	claim := &StakeHelper{Claim: newStreamClaim(), Version: NoSig}
+	claim.Claim.Title = "Test title"
+	err = claim.Sign(*privateKey, channelClaimID, outpointHash, "lbrycrd_main")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	rawClaim, err := claim.CompileValue()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	claim, err = DecodeClaimBytes(rawClaim, "lbrycrd_main")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.Assert(t, claim.IsSigned())
+	assert.Equal(t, hex.EncodeToString(reverseBytes(claim.ClaimID)), channelClaimID)
+
+	valid, err := claim.ValidateSignature(privateKey.PubKey(), outpointHash, "lbrycrd_main")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.Assert(t, valid, "could not verify signature")
+
+	otherKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	valid, err = claim.ValidateSignature(otherKey.PubKey(), outpointHash, "lbrycrd_main")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.Assert(t, !valid, "signature should not verify with a different key")
+
+	otherOutpointHash, err := GetOutpointHash("4c1df9e022e396859175f9bfa69b38e444db10fb53355fa99a0989a83bcdb82f", 1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	valid, err = claim.ValidateSignature(privateKey.PubKey(), otherOutpointHash, "lbrycrd_main")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.Assert(t, !valid, "signature should not verify for a different input")
+}
+
+func TestStakeHelperSign_V1(t *testing.T) {
+	privateKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	channelClaimID := "251305ca93d4dbedb50dceb282ebcb7b07b7ac65"
+	claimAddress := "bSkUov7HMWpYBiXackDwRnR5ishhGHvtJt"
+
+	claim, err := DecodeClaimHex(raw_claims[2], "lbrycrd_main") // unsigned v1 claim
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = claim.Sign(*privateKey, channelClaimID, claimAddress, "lbrycrd_main")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	rawClaim, err := claim.CompileValue()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	claim, err = DecodeClaimBytes(rawClaim, "lbrycrd_main")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.Equal(t, claim.Format(), FormatLegacyProtobuf)
+	assert.Assert(t, claim.IsSigned())
+
+	valid, err := claim.ValidateSignature(privateKey.PubKey(), claimAddress, "lbrycrd_main")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.Assert(t, valid, "could not verify signature")
+}
+
+func TestValidateSignature_Mainnet(t *testing.T) {
+	// the v1 claim and channel from TestV1ValidateClaimSignature
+	channel, err := DecodeClaimHex(raw_claims[0], "lbrycrd_main")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	publicKey, err := channel.GetPublicKey()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	claim, err := DecodeClaimHex(raw_claims[1], "lbrycrd_main")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	valid, err := claim.ValidateSignature(publicKey, "bSkUov7HMWpYBiXackDwRnR5ishhGHvtJt", "lbrycrd_main")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.Assert(t, valid, "could not verify signature")
+}
+
+func TestStakeHelperSign_Support(t *testing.T) {
+	privateKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	channelClaimID := "cf3f7c898af87cc69b06a6ac7899efb9a4878fdb" //Fake
+	outpointHash, err := GetOutpointHash("4c1df9e022e396859175f9bfa69b38e444db10fb53355fa99a0989a83bcdb82f", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, emoji := range []string{"👍", ""} {
+		support := NewSupport(emoji)
+		err = support.Sign(*privateKey, channelClaimID, outpointHash, "lbrycrd_main")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := support.CompileValue()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeSupportBytes(raw, "lbrycrd_main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Assert(t, decoded.IsSupport() && decoded.IsSigned())
+		assert.Equal(t, decoded.Support.GetEmoji(), emoji)
+		assert.Equal(t, decoded.Format(), FormatProtobuf)
+		assert.Equal(t, hex.EncodeToString(reverseBytes(decoded.ClaimID)), channelClaimID)
+
+		// the signature isn't in the protobuf, so stripping it changes nothing
+		noSig, err := decoded.serializedNoSignature()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.DeepEqual(t, noSig, decoded.Payload)
+		serializedHex, err := decoded.serializedHexString()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, serializedHex, hex.EncodeToString(decoded.Payload))
+
+		valid, err := decoded.ValidateSignature(privateKey.PubKey(), outpointHash, "lbrycrd_main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Assert(t, valid, "could not verify support signature")
+	}
+}
+
+func TestStakeHelperSign_SupportUnknownFields(t *testing.T) {
+	privateKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+	channelClaimID := "cf3f7c898af87cc69b06a6ac7899efb9a4878fdb" //Fake
+	outpointHash, err := GetOutpointHash("4c1df9e022e396859175f9bfa69b38e444db10fb53355fa99a0989a83bcdb82f", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a support from a newer version, with a field (2, varint 1) that this version doesn't know about
+	support := NewSupport("🔥")
+	payload, err := support.serialized()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload = append(payload, 0x10, 0x01)
+	newer, err := DecodeSupportBytes(append([]byte{NoSig.byte()}, payload...), "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = newer.Sign(*privateKey, channelClaimID, outpointHash, "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := newer.CompileValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeSupportBytes(raw, "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.DeepEqual(t, decoded.Payload, payload)
+
+	reserialized, err := decoded.serialized()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.DeepEqual(t, reserialized, payload)
+
+	valid, err := decoded.ValidateSignature(privateKey.PubKey(), outpointHash, "lbrycrd_main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Assert(t, valid, "could not verify signature over unknown fields")
+}
@@ -2,7 +2,6 @@ package stake
 
 import (
 	"encoding/hex"
-	"strconv"
 
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"github.com/lbryio/lbry.go/v2/schema/address"
@@ -39,6 +38,43 @@ type StakeHelper struct {
 
 const migrationErrorMessage = "migration from v1 to v2 protobuf failed with: "
 
+var (
+	// ErrNothingToDecode is returned when a claim or support value is empty
+	ErrNothingToDecode = errors.Base("there is nothing to decode")
+	// ErrInvalidHex is returned when a hex-encoded value can't be decoded
+	ErrInvalidHex = errors.Base("value is not valid hex")
+	// ErrTruncatedSignature is returned when the version byte says the value is signed, but it's too short to hold
+	// the channel claim id and signature
+	ErrTruncatedSignature = errors.Base("signature version indicated by 1st byte but not enough bytes for valid format")
+	// ErrNoMatchingVersion is returned when a value is not protobuf or any of the json metadata versions
+	ErrNoMatchingVersion = errors.Base("claim value has no matching version")
+)
+
+// Format is the serialization a claim or support was decoded from
+type Format int
+
+const (
+	FormatUnknown Format = iota
+	// FormatJSON is the json metadata (versions 0.0.1 to 0.0.3) that was used before protobuf. it's migrated to v2
+	FormatJSON
+	// FormatLegacyProtobuf is the v1 protobuf claim. it's migrated to v2, but the original is kept in LegacyClaim
+	FormatLegacyProtobuf
+	// FormatProtobuf is the current v2 protobuf claim or support
+	FormatProtobuf
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatJSON:
+		return "json"
+	case FormatLegacyProtobuf:
+		return "legacy protobuf"
+	case FormatProtobuf:
+		return "protobuf"
+	}
+	return "unknown"
+}
+
 func (c *StakeHelper) ValidateAddresses(blockchainName string) error {
 	if c.Claim != nil { // V2
 		// check the validity of a fee address
@@ -58,19 +94,7 @@ func (c *StakeHelper) ValidateAddresses(blockchainName string) error {
 }
 
 func validateAddress(tmp_addr []byte, blockchainName string) error {
-	if len(tmp_addr) != 25 {
-		return errors.Err("invalid address length: " + strconv.FormatInt(int64(len(tmp_addr)), 10) + "!")
-	}
-	addr := [25]byte{}
-	for i := range addr {
-		addr[i] = tmp_addr[i]
-	}
-	_, err := address.EncodeAddress(addr, blockchainName)
-	if err != nil {
-		return errors.Err(err)
-	}
-
-	return nil
+	return errors.Err(address.ValidateAddressBytes(tmp_addr, blockchainName))
 }
 
 func getVersionFromByte(versionByte byte) version {
@@ -102,6 +126,23 @@ func (c *StakeHelper) IsSupport() bool {
 	return c.Support != nil
 }
 
+// Format returns the serialization the claim or support was decoded from
+func (c *StakeHelper) Format() Format {
+	if c.LegacyClaim != nil {
+		return FormatLegacyProtobuf
+	} else if c.Payload != nil || c.IsSupport() {
+		return FormatProtobuf // supports have only ever been protobuf
+	} else if c.Claim != nil {
+		return FormatJSON // json values are migrated without keeping the payload
+	}
+	return FormatUnknown
+}
+
+// IsSigned returns true if the claim or support was signed by a channel
+func (c *StakeHelper) IsSigned() bool {
+	return c.Version == WithSig
+}
+
 func (c *StakeHelper) LoadFromBytes(raw_claim []byte, blockchainName string) error {
 	return c.loadFromBytes(raw_claim, false, blockchainName)
 }
@@ -115,7 +156,7 @@ func (c *StakeHelper) loadFromBytes(raw_claim []byte, isSupport bool, blockchain
 		return errors.Err("already initialized")
 	}
 	if len(raw_claim) < 1 {
-		return errors.Err("there is nothing to decode")
+		return errors.Err(ErrNothingToDecode)
 	}
 
 	var claim_pb *pb.Claim
@@ -128,7 +169,7 @@ func (c *StakeHelper) loadFromBytes(raw_claim []byte, isSupport bool, blockchain
 	var signature []byte
 	if version == WithSig {
 		if len(raw_claim) < 85 {
-			return errors.Err("signature version indicated by 1st byte but not enough bytes for valid format")
+			return errors.Err(ErrTruncatedSignature)
 		}
 		claimID = raw_claim[1:21]    // channel claimid = next 20 bytes
 		signature = raw_claim[21:85] // signature = next 64 bytes
@@ -146,7 +187,9 @@ func (c *StakeHelper) loadFromBytes(raw_claim []byte, isSupport bool, blockchain
 			support_pb = support
 		}
 	}
-	if err != nil {
+	if err != nil && isSupport {
+		return errors.Err(err) // supports have no legacy format to fall back on
+	} else if err != nil {
 		legacy_claim_pb = &legacy_pb.Claim{}
 		legacyErr := proto.Unmarshal(raw_claim, legacy_claim_pb)
 		if legacyErr == nil {
@@ -194,7 +237,7 @@ func (c *StakeHelper) loadFromBytes(raw_claim []byte, isSupport bool, blockchain
 func (c *StakeHelper) LoadFromHexString(claim_hex string, blockchainName string) error {
 	buf, err := hex.DecodeString(claim_hex)
 	if err != nil {
-		return err
+		return errors.Err("%w: %s", ErrInvalidHex, err.Error())
 	}
 	return c.LoadFromBytes(buf, blockchainName)
 }
@@ -202,7 +245,7 @@ func (c *StakeHelper) LoadFromHexString(claim_hex string, blockchainName string)
 func (c *StakeHelper) LoadSupportFromHexString(claim_hex string, blockchainName string) error {
 	buf, err := hex.DecodeString(claim_hex)
 	if err != nil {
-		return err
+		return errors.Err("%w: %s", ErrInvalidHex, err.Error())
 	}
 	return c.LoadSupportFromBytes(buf, blockchainName)
 }
@@ -225,19 +268,29 @@ func DecodeSupportProtoBytes(serialized []byte, blockchainName string) (*StakeHe
 	return claim, nil
 }
 
+// DecodeClaimHex decodes a hex-encoded claim value. see DecodeClaimBytes
 func DecodeClaimHex(serialized string, blockchainName string) (*StakeHelper, error) {
 	claim_bytes, err := hex.DecodeString(serialized)
 	if err != nil {
-		return nil, errors.Err(err)
+		return nil, errors.Err("%w: %s", ErrInvalidHex, err.Error())
 	}
 	return DecodeClaimBytes(claim_bytes, blockchainName)
 }
 
-// DecodeClaimBytes take a byte array and tries to decode it to a protobuf claim or migrate it from either json v1,2,3 or pb v1
+// DecodeClaimBytes take a byte array and tries to decode it to a protobuf claim or migrate it from either json v1,2,3 or pb v1.
+// The helper's Format() says which one it was. Supports can't be told apart from claims by their bytes, so they must
+// be decoded with DecodeSupportBytes. Malformed values return one of the Err* errors in this package, which can be
+// checked with errors.Is.
 func DecodeClaimBytes(serialized []byte, blockchainName string) (*StakeHelper, error) {
+	if len(serialized) == 0 {
+		return nil, errors.Err(ErrNothingToDecode)
+	}
+
 	helper, err := DecodeClaimProtoBytes(serialized, blockchainName)
 	if err == nil {
 		return helper, nil
+	} else if errors.Is(err, ErrTruncatedSignature) {
+		return nil, err // json never starts with the signature version byte, so there's no point trying it
 	}
 	helper = &StakeHelper{}
 	//If protobuf fails, try json versions before returning an error.
@@ -250,7 +303,7 @@ func DecodeClaimBytes(serialized []byte, blockchainName string) (*StakeHelper, e
 			v3Claim := new(V3Claim)
 			err := v3Claim.Unmarshal(serialized)
 			if err != nil {
-				return nil, errors.Prefix("Claim value has no matching version", err)
+				return nil, errors.Err("%w: %s", ErrNoMatchingVersion, err.Error())
 			}
 			helper.Claim, err = migrateV3Claim(*v3Claim)
 			if err != nil {
@@ -272,8 +325,20 @@ func DecodeClaimBytes(serialized []byte, blockchainName string) (*StakeHelper, e
 	return helper, nil
 }
 
+// DecodeSupportHex decodes a hex-encoded support value. see DecodeSupportBytes
+func DecodeSupportHex(serialized string, blockchainName string) (*StakeHelper, error) {
+	support_bytes, err := hex.DecodeString(serialized)
+	if err != nil {
+		return nil, errors.Err("%w: %s", ErrInvalidHex, err.Error())
+	}
+	return DecodeSupportBytes(support_bytes, blockchainName)
+}
+
 // DecodeSupportBytes take a byte array and tries to decode it to a protobuf support
 func DecodeSupportBytes(serialized []byte, blockchainName string) (*StakeHelper, error) {
+	if len(serialized) == 0 {
+		return nil, errors.Err(ErrNothingToDecode)
+	}
 	helper, err := DecodeSupportProtoBytes(serialized, blockchainName)
 	if err != nil {
 		return nil, errors.Err(err)
@@ -293,6 +358,9 @@ func (c *StakeHelper) CompileValue() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if c.LegacyClaim != nil {
+		return payload, nil // v1 claims have no version byte. the signature is part of the protobuf
+	}
 	var value []byte
 	value = append(value, c.Version.byte())
 	if c.Version == WithSig {
@@ -0,0 +1,157 @@
+package stake
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/lbryio/lbry.go/v2/schema/keys"
+	pb "github.com/lbryio/types/v2/go"
+)
+
+const (
+	maxLBCSupply     = 1083202000 // the total supply of LBC, so no fee can be more than this
+	btInfohashLength = 20
+)
+
+// Violation is a rule that a claim breaks
+type Violation struct {
+	// the field that breaks the rule, e.g. "stream.fee.amount" or "languages[1]"
+	Field   string
+	Message string
+}
+
+func (v Violation) String() string {
+	return v.Field + ": " + v.Message
+}
+
+// Validate checks a claim against the rules the SDK and the blockchain enforce, so a bad claim can be caught before
+// it's broadcast. It returns every rule the claim breaks, or nil if it's valid. Supports have nothing to check.
+func (c *StakeHelper) Validate(blockchainName string) []Violation {
+	if c.Claim == nil || c.IsSupport() {
+		return nil
+	}
+
+	v := &violations{}
+	claim := c.Claim
+
+	v.url("thumbnail.url", claim.GetThumbnail().GetUrl())
+	for i, l := range claim.GetLanguages() {
+		v.language("languages["+strconv.Itoa(i)+"]", l)
+	}
+	for i, l := range claim.GetLocations() {
+		v.location("locations["+strconv.Itoa(i)+"]", l)
+	}
+
+	switch {
+	case claim.GetStream() != nil:
+		v.stream(claim.GetStream(), blockchainName)
+	case claim.GetChannel() != nil:
+		channel := claim.GetChannel()
+		if len(channel.GetPublicKey()) == 0 {
+			v.add("channel.public_key", "is required")
+		} else if _, err := keys.GetPublicKeyFromBytes(channel.GetPublicKey()); err != nil {
+			v.add("channel.public_key", "is not a valid secp256k1 public key")
+		}
+		v.url("channel.cover.url", channel.GetCover().GetUrl())
+		v.claimList("channel.featured", channel.GetFeatured())
+	case claim.GetRepost() != nil:
+		v.claimHash("repost.claim_hash", claim.GetRepost().GetClaimHash())
+	case claim.GetCollection() != nil:
+		v.claimList("collection", claim.GetCollection())
+	default:
+		v.add("type", "claim must be a stream, channel, repost, or collection")
+	}
+
+	return v.list
+}
+
+type violations struct {
+	list []Violation
+}
+
+func (v *violations) add(field, message string) {
+	v.list = append(v.list, Violation{Field: field, Message: message})
+}
+
+func (v *violations) stream(stream *pb.Stream, blockchainName string) {
+	source := stream.GetSource()
+	if source == nil {
+		v.add("stream.source", "is required")
+	} else {
+		v.hash("stream.source.sd_hash", source.GetSdHash(), hashLength, true)
+		v.hash("stream.source.hash", source.GetHash(), hashLength, false)
+		v.hash("stream.source.bt_infohash", source.GetBtInfohash(), btInfohashLength, false)
+	}
+
+	if fee := stream.GetFee(); fee != nil {
+		if _, ok := pb.Fee_Currency_name[int32(fee.GetCurrency())]; !ok || fee.GetCurrency() == pb.Fee_UNKNOWN_CURRENCY {
+			v.add("stream.fee.currency", "must be LBC, BTC, or USD")
+		}
+		if fee.GetAmount() == 0 {
+			v.add("stream.fee.amount", "must be positive")
+		} else if fee.GetCurrency() == pb.Fee_LBC && fee.GetAmount() > maxLBCSupply*deweysPerLBC {
+			v.add("stream.fee.amount", "is more than the total supply of LBC")
+		}
+		if len(fee.GetAddress()) == 0 {
+			v.add("stream.fee.address", "is required")
+		} else if err := validateAddress(fee.GetAddress(), blockchainName); err != nil {
+			v.add("stream.fee.address", "is not a valid "+blockchainName+" address")
+		}
+	}
+}
+
+func (v *violations) hash(field string, hash []byte, length int, required bool) {
+	if len(hash) == 0 {
+		if required {
+			v.add(field, "is required")
+		}
+	} else if len(hash) != length {
+		v.add(field, "must be "+strconv.Itoa(length)+" bytes, not "+strconv.Itoa(len(hash)))
+	}
+}
+
+func (v *violations) url(field, u string) {
+	if u == "" {
+		return
+	}
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		v.add(field, "must be an http or https url")
+	}
+}
+
+func (v *violations) language(field string, l *pb.Language) {
+	if _, ok := pb.Language_Language_name[int32(l.GetLanguage())]; !ok || l.GetLanguage() == pb.Language_UNKNOWN_LANGUAGE {
+		v.add(field, "unknown language")
+	}
+	if _, ok := pb.Language_Script_name[int32(l.GetScript())]; !ok {
+		v.add(field, "unknown script")
+	}
+	if _, ok := pb.Location_Country_name[int32(l.GetRegion())]; !ok {
+		v.add(field, "unknown region")
+	}
+}
+
+func (v *violations) location(field string, l *pb.Location) {
+	if _, ok := pb.Location_Country_name[int32(l.GetCountry())]; !ok {
+		v.add(field+".country", "unknown country")
+	}
+	if l.GetLatitude() < -90*gpsPrecision || l.GetLatitude() > 90*gpsPrecision {
+		v.add(field+".latitude", "must be between -90 and 90")
+	}
+	if l.GetLongitude() < -180*gpsPrecision || l.GetLongitude() > 180*gpsPrecision {
+		v.add(field+".longitude", "must be between -180 and 180")
+	}
+}
+
+func (v *violations) claimHash(field string, hash []byte) {
+	if len(hash) != claimIDLength {
+		v.add(field, "must be a "+strconv.Itoa(claimIDLength)+" byte claim id")
+	}
+}
+
+func (v *violations) claimList(field string, list *pb.ClaimList) {
+	for i, ref := range list.GetClaimReferences() {
+		v.claimHash(field+".claim_references["+strconv.Itoa(i)+"]", ref.GetClaimHash())
+	}
+}
@@ -0,0 +1,76 @@
+package stake
+
+import (
+	"testing"
+
+	pb "github.com/lbryio/types/v2/go"
+
+	"github.com/btcsuite/btcd/btcec"
+	"gotest.tools/assert"
+)
+
+func TestValidate_Valid(t *testing.T) {
+	privateKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := NewStreamClaim().
+		Thumbnail("https://thumbnails.lbry.com/abc.jpg").
+		Languages("en-US").
+		Location("US", "NJ", "").
+		SDHash(testSDHash).
+		Fee("USD", 2.99, testAddress).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	channel, err := NewChannelClaim().PublicKey(privateKey.PubKey()).Cover("http://cover.com/a.png").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repost, err := NewRepost(testClaimID).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	support := &StakeHelper{Support: &pb.Support{Emoji: "👍"}}
+
+	for _, helper := range []*StakeHelper{stream, channel, repost, support} {
+		violations := helper.Validate("lbrycrd_main")
+		assert.Assert(t, violations == nil, "unexpected violations: %v", violations)
+	}
+}
+
+func TestValidate_Violations(t *testing.T) {
+	claim := newStreamClaim()
+	claim.Thumbnail = &pb.Source{Url: "not a url"}
+	claim.Languages = []*pb.Language{{Language: pb.Language_en}, {Language: pb.Language_UNKNOWN_LANGUAGE}}
+	claim.Locations = []*pb.Location{{Country: pb.Location_US, Latitude: 91 * gpsPrecision}}
+	claim.GetStream().Source = &pb.Source{SdHash: []byte{1, 2, 3}}
+	claim.GetStream().Fee = &pb.Fee{Currency: pb.Fee_UNKNOWN_CURRENCY}
+
+	violations := (&StakeHelper{Claim: claim}).Validate("lbrycrd_main")
+
+	fields := map[string]bool{}
+	for _, v := range violations {
+		fields[v.Field] = true
+	}
+	for _, field := range []string{
+		"thumbnail.url",
+		"languages[1]",
+		"locations[0].latitude",
+		"stream.source.sd_hash",
+		"stream.fee.currency",
+		"stream.fee.amount",
+		"stream.fee.address",
+	} {
+		assert.Assert(t, fields[field], "expected a violation for %s, got %v", field, violations)
+	}
+	assert.Equal(t, len(violations), 7)
+
+	channel := newChannelClaim()
+	channel.GetChannel().PublicKey = []byte("not a key")
+	violations = (&StakeHelper{Claim: channel}).Validate("lbrycrd_main")
+	assert.Equal(t, len(violations), 1)
+	assert.Equal(t, violations[0].Field, "channel.public_key")
+}
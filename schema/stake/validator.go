@@ -9,6 +9,8 @@ import (
 
 	"github.com/lbryio/lbry.go/v2/extras/errors"
 	"github.com/lbryio/lbry.go/v2/schema/address"
+
+	"github.com/btcsuite/btcd/btcec"
 )
 
 const SECP256k1 = "SECP256k1"
@@ -31,15 +33,40 @@ func (c *StakeHelper) VerifyDigest(certificate *StakeHelper, signature [64]byte,
 		return false
 	}
 
+	pk, err := certificate.GetPublicKey()
+	if err != nil {
+		return false
+	}
+	return verifyDigest(pk, signature[:], digest)
+}
+
+func verifyDigest(pk *btcec.PublicKey, signature []byte, digest [32]byte) bool {
+	if len(signature) != 64 {
+		return false
+	}
 	R := &big.Int{}
 	S := &big.Int{}
 	R.SetBytes(signature[0:32])
 	S.SetBytes(signature[32:64])
-	pk, err := certificate.GetPublicKey()
+	return ecdsa.Verify(pk.ToECDSA(), digest[:], R, S)
+}
+
+// ValidateSignature checks the helper's signature against the public key of the channel that signed it. k is the
+// claim address for legacy v1 claims, and the hash of the first input's outpoint (see GetOutpointHash) for everything
+// else.
+func (c *StakeHelper) ValidateSignature(channelPublicKey *btcec.PublicKey, k string, blockchainName string) (bool, error) {
+	if channelPublicKey == nil {
+		return false, errors.Err("channel public key is required")
+	}
+	if c.Signature == nil {
+		return false, errors.Err("claim does not have a signature")
+	}
+
+	digest, err := c.signatureDigest(k, blockchainName)
 	if err != nil {
-		return false
+		return false, err
 	}
-	return ecdsa.Verify(pk.ToECDSA(), digest[:], R, S)
+	return verifyDigest(channelPublicKey, c.Signature, digest), nil
 }
 
 func (c *StakeHelper) ValidateClaimSignature(certificate *StakeHelper, k string, certificateId string, blockchainName string) (bool, error) {
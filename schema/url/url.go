@@ -0,0 +1,189 @@
+package url
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/extras/util"
+)
+
+const (
+	Scheme           = "lbry://"
+	claimIDMaxLength = 40
+)
+
+var (
+	ErrInvalidURL  = errors.Base("invalid LBRY URL")
+	ErrInvalidName = errors.Base("invalid claim name")
+)
+
+// nameChars is every character that's allowed in a claim name
+const nameChars = `[^=&#:$@%*?;"/\\<>{}|^~` + "`" + `\[\]\x{0000}-\x{0020}\x{FFFE}-\x{FFFF}]+`
+
+func claimRegex(name, prefix string) string {
+	return `(?:(?P<` + name + `_name>` + prefix + nameChars + `)` +
+		`(?:(?:[:#](?P<` + name + `_claim_id>[0-9a-f]{1,40}))` +
+		`|(?:\*(?P<` + name + `_sequence>[1-9][0-9]*))` +
+		`|(?:\$(?P<` + name + `_amount_order>[1-9][0-9]*)))?)`
+}
+
+var nameRegex = regexp.MustCompile(`^` + nameChars + `$`)
+
+// urlRegex is the same expression the SDK uses to parse urls
+var urlRegex = regexp.MustCompile(`^(?P<scheme>lbry://)?(?:` +
+	`(?:` + claimRegex("channel_with_stream", "@") + "/" + claimRegex("stream_in_channel", "") + `)` +
+	`|` + claimRegex("channel", "@") +
+	`|` + claimRegex("stream", "") +
+	`)$`)
+
+// PathSegment is a claim name in a url, with at most one of the modifiers that picks which claim with that name it
+// refers to
+type PathSegment struct {
+	Name string
+	// a full claim id, or a prefix of one
+	ClaimID string
+	// the nth claim made with this name, starting at 1
+	Sequence int
+	// the claim with the nth largest amount staked on it, starting at 1
+	AmountOrder int
+}
+
+// Normalized is the name as the claimtrie stores it
+func (p PathSegment) Normalized() string {
+	return util.NormalizeName(p.Name)
+}
+
+// IsShortID is true if the segment has a claim id that is only a prefix of the full id
+func (p PathSegment) IsShortID() bool {
+	return p.ClaimID != "" && len(p.ClaimID) < claimIDMaxLength
+}
+
+// IsFullID is true if the segment has a full claim id
+func (p PathSegment) IsFullID() bool {
+	return len(p.ClaimID) == claimIDMaxLength
+}
+
+func (p PathSegment) String() string {
+	switch {
+	case p.ClaimID != "":
+		return p.Name + "#" + p.ClaimID
+	case p.Sequence > 0:
+		return p.Name + "*" + strconv.Itoa(p.Sequence)
+	case p.AmountOrder > 0:
+		return p.Name + "$" + strconv.Itoa(p.AmountOrder)
+	}
+	return p.Name
+}
+
+// URL is a parsed lbry:// url. It has a channel, a stream, or a stream in a channel.
+type URL struct {
+	Channel *PathSegment
+	Stream  *PathSegment
+}
+
+// HasChannel is true if the url has a channel
+func (u URL) HasChannel() bool { return u.Channel != nil }
+
+// HasStream is true if the url has a stream
+func (u URL) HasStream() bool { return u.Stream != nil }
+
+// HasStreamInChannel is true if the url is a stream in a channel
+func (u URL) HasStreamInChannel() bool { return u.HasChannel() && u.HasStream() }
+
+// Parts returns the segments of the url in order
+func (u URL) Parts() []PathSegment {
+	var parts []PathSegment
+	if u.Channel != nil {
+		parts = append(parts, *u.Channel)
+	}
+	if u.Stream != nil {
+		parts = append(parts, *u.Stream)
+	}
+	return parts
+}
+
+func (u URL) String() string {
+	parts := u.Parts()
+	strs := make([]string, len(parts))
+	for i, p := range parts {
+		strs[i] = p.String()
+	}
+	return Scheme + strings.Join(strs, "/")
+}
+
+// Normalized returns the url with every name normalized, which is what the SDK resolves
+func (u URL) Normalized() URL {
+	n := URL{}
+	if u.Channel != nil {
+		c := *u.Channel
+		c.Name = c.Normalized()
+		n.Channel = &c
+	}
+	if u.Stream != nil {
+		s := *u.Stream
+		s.Name = s.Normalized()
+		n.Stream = &s
+	}
+	return n
+}
+
+// Parse parses a url following the SDK's rules. The lbry:// scheme is optional.
+func Parse(url string) (*URL, error) {
+	match := urlRegex.FindStringSubmatch(url)
+	if match == nil {
+		return nil, errors.Err(ErrInvalidURL)
+	}
+	groups := make(map[string]string)
+	for i, name := range urlRegex.SubexpNames() {
+		if name != "" {
+			groups[name] = match[i]
+		}
+	}
+
+	u := &URL{}
+	var err error
+	for _, segment := range []string{"channel", "stream", "channel_with_stream", "stream_in_channel"} {
+		if groups[segment+"_name"] == "" {
+			continue
+		}
+		p := &PathSegment{Name: groups[segment+"_name"], ClaimID: groups[segment+"_claim_id"]}
+		p.Sequence, err = parseModifier(groups[segment+"_sequence"])
+		if err != nil {
+			return nil, err
+		}
+		p.AmountOrder, err = parseModifier(groups[segment+"_amount_order"])
+		if err != nil {
+			return nil, err
+		}
+
+		if segment == "channel" || segment == "channel_with_stream" {
+			u.Channel = p
+		} else {
+			u.Stream = p
+		}
+	}
+
+	return u, nil
+}
+
+// ValidateName checks that a claim can have this name and still be reached by a url. Channel names start with @, and
+// no other name can have an @ in it.
+func ValidateName(name string) error {
+	if !nameRegex.MatchString(strings.TrimPrefix(name, "@")) {
+		return errors.Prefix(strconv.Quote(name), ErrInvalidName)
+	}
+	return nil
+}
+
+func parseModifier(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.Prefix(ErrInvalidURL.Error(), err)
+	}
+	return n, nil
+}
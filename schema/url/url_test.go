@@ -0,0 +1,141 @@
+package url
+
+import (
+	"testing"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		url     string
+		channel *PathSegment
+		stream  *PathSegment
+	}{
+		{"lbry://test", nil, &PathSegment{Name: "test"}},
+		{"test", nil, &PathSegment{Name: "test"}},
+		{"lbry://test#1c8a", nil, &PathSegment{Name: "test", ClaimID: "1c8a"}},
+		{"lbry://test:1c8a", nil, &PathSegment{Name: "test", ClaimID: "1c8a"}},
+		{"lbry://test*2", nil, &PathSegment{Name: "test", Sequence: 2}},
+		{"lbry://test$3", nil, &PathSegment{Name: "test", AmountOrder: 3}},
+		{"lbry://@chan", &PathSegment{Name: "@chan"}, nil},
+		{"lbry://@chan#f0", &PathSegment{Name: "@chan", ClaimID: "f0"}, nil},
+		{"lbry://@chan:1/test$2", &PathSegment{Name: "@chan", ClaimID: "1"}, &PathSegment{Name: "test", AmountOrder: 2}},
+		{"lbry://@chan*4/test", &PathSegment{Name: "@chan", Sequence: 4}, &PathSegment{Name: "test"}},
+		{"lbry://Ünicode", nil, &PathSegment{Name: "Ünicode"}},
+	}
+
+	for _, test := range tests {
+		u, err := Parse(test.url)
+		if err != nil {
+			t.Errorf("%s: %v", test.url, err)
+			continue
+		}
+		if !segmentsEqual(u.Channel, test.channel) {
+			t.Errorf("%s: expected channel %v, got %v", test.url, test.channel, u.Channel)
+		}
+		if !segmentsEqual(u.Stream, test.stream) {
+			t.Errorf("%s: expected stream %v, got %v", test.url, test.stream, u.Stream)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, url := range []string{
+		"",
+		"lbry://",
+		"lbry://@",
+		"lbry://test#",
+		"lbry://test#xyz",
+		"lbry://test*0",
+		"lbry://test$",
+		"lbry://test#1*2",
+		"lbry://te st",
+		"lbry://stream/@chan",
+		"lbry://@chan/",
+		"lbry://@chan/test/another",
+		"https://lbry.tv/test",
+		"lbry://test#0123456789012345678901234567890123456789a",
+	} {
+		_, err := Parse(url)
+		if !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("%s: expected an invalid url error, got %v", url, err)
+		}
+	}
+}
+
+func TestURL_String(t *testing.T) {
+	for _, url := range []string{
+		"lbry://test",
+		"lbry://test#1c8a",
+		"lbry://test*2",
+		"lbry://test$3",
+		"lbry://@chan",
+		"lbry://@chan#f0/test$2",
+		"lbry://@chan*4/test#abc",
+	} {
+		u, err := Parse(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.String() != url {
+			t.Errorf("expected %s, got %s", url, u.String())
+		}
+	}
+
+	u, err := Parse("test:1c8a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.String() != "lbry://test#1c8a" {
+		t.Errorf("expected lbry://test#1c8a, got %s", u.String())
+	}
+}
+
+func TestURL_Normalized(t *testing.T) {
+	u, err := Parse("lbry://@Chan/ÉCOLE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := u.Normalized()
+	if n.Channel.Name != "@chan" {
+		t.Errorf("expected @chan, got %s", n.Channel.Name)
+	}
+	if n.Stream.Name != "école" {
+		t.Errorf("expected a decomposed, lower case name, got %q", n.Stream.Name)
+	}
+	if u.Stream.Name != "ÉCOLE" {
+		t.Error("normalizing must not change the original url")
+	}
+}
+
+func TestPathSegment_ID(t *testing.T) {
+	short := PathSegment{Name: "test", ClaimID: "1c8a"}
+	full := PathSegment{Name: "test", ClaimID: "1c8a3d3c5dc8e8e1e0d1d4a7a3b2c1d0e9f8a7b6"}
+	if !short.IsShortID() || short.IsFullID() {
+		t.Error("expected a short id")
+	}
+	if full.IsShortID() || !full.IsFullID() {
+		t.Error("expected a full id")
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"test", "@chan", "Ünicode", "with-dash_and.dot", "123"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "@", "te st", "test#1", "a:b", "a/b", "a$b", "a*b", "a@b", "@@chan", "a?b", "tab\t"} {
+		if err := ValidateName(name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("%q: expected an invalid name error, got %v", name, err)
+		}
+	}
+}
+
+func segmentsEqual(a, b *PathSegment) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
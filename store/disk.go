@@ -0,0 +1,270 @@
+package store
+
+import (
+	"container/list"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/stream"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// blobs are sharded into subdirectories named after this many leading characters of their hash, so no single
+// directory gets huge
+const prefixLength = 2
+
+// DiskStore keeps blobs as files in a directory. When the total size goes over the cap, the least recently used blobs
+// are deleted. Blobs are checked against their hash when they're read.
+type DiskStore struct {
+	dir     string
+	maxSize int64
+
+	mu      sync.Mutex
+	lru     *list.List // of *diskEntry, most recently used first
+	entries map[string]*list.Element
+	size    int64
+}
+
+type diskEntry struct {
+	hash string
+	size int64
+}
+
+// NewDiskStore returns a store in dir that holds at most maxSize bytes of blobs, or any amount if maxSize is 0. Blobs
+// already in dir are kept, and are evicted in order of when they were last used.
+func NewDiskStore(dir string, maxSize int64) (*DiskStore, error) {
+	d := &DiskStore{
+		dir:     dir,
+		maxSize: maxSize,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	err = d.load()
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.evict()
+	return d, nil
+}
+
+// load finds the blobs that are already on disk
+func (d *DiskStore) load() error {
+	type found struct {
+		diskEntry
+		used time.Time
+	}
+	var blobs []found
+
+	shards, err := os.ReadDir(d.dir)
+	if err != nil {
+		return errors.Err(err)
+	}
+	for _, shard := range shards {
+		if !shard.IsDir() || len(shard.Name()) != prefixLength {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(d.dir, shard.Name()))
+		if err != nil {
+			return errors.Err(err)
+		}
+		for _, f := range files {
+			if f.IsDir() || len(f.Name()) != stream.BlobHashHexLength {
+				continue // probably a temp file from an interrupted Put
+			}
+			info, err := f.Info()
+			if err != nil {
+				return errors.Err(err)
+			}
+			blobs = append(blobs, found{diskEntry{hash: f.Name(), size: info.Size()}, info.ModTime()})
+		}
+	}
+
+	sort.Slice(blobs, func(i, j int) bool { return blobs[i].used.After(blobs[j].used) })
+	for _, b := range blobs {
+		e := b.diskEntry
+		d.entries[e.hash] = d.lru.PushBack(&e)
+		d.size += e.size
+	}
+	return nil
+}
+
+// Has returns whether the blob is in the store
+func (d *DiskStore) Has(hash string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.entries[hash]
+	return ok, nil
+}
+
+// Get returns the blob, or ErrBlobNotFound. If the blob on disk doesn't match its hash, it's deleted and
+// ErrBlobCorrupt is returned.
+func (d *DiskStore) Get(hash string) (stream.Blob, error) {
+	// the lock only covers the lru, so reads and hashing of different blobs can happen at once
+	d.mu.Lock()
+	el, ok := d.entries[hash]
+	if ok {
+		d.lru.MoveToFront(el)
+	}
+	d.mu.Unlock()
+	if !ok {
+		return nil, errors.Err(ErrBlobNotFound)
+	}
+
+	blob, err := os.ReadFile(d.path(hash))
+	if os.IsNotExist(err) {
+		// deleted or evicted since the lookup
+		d.forget(hash, el, false)
+		return nil, errors.Err(ErrBlobNotFound)
+	} else if err != nil {
+		return nil, errors.Err(err)
+	}
+
+	err = checkHash(hash, blob)
+	if err != nil {
+		log.Warnf("deleting corrupt blob %s", hash)
+		d.forget(hash, el, true)
+		return nil, err
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(d.path(hash), now, now) // so the order survives a restart
+	return blob, nil
+}
+
+// forget removes the blob from the lru if el is still its entry, and deletes its file too if del is set. the entry
+// may have been replaced or removed while d.mu wasn't held.
+func (d *DiskStore) forget(hash string, el *list.Element, del bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.entries[hash] != el {
+		return
+	}
+	if !del {
+		d.remove(el)
+		return
+	}
+	if err := d.delete(el); err != nil {
+		log.Errorf("deleting corrupt blob: %s", errors.FullTrace(err))
+	}
+}
+
+// Put stores the blob, and evicts blobs if the store is over its size cap
+func (d *DiskStore) Put(hash string, blob stream.Blob) error {
+	err := checkHash(hash, blob)
+	if err != nil {
+		return err
+	}
+	size := int64(blob.Size())
+	if d.maxSize > 0 && size > d.maxSize {
+		return errors.Err("blob is bigger than the store")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if el, ok := d.entries[hash]; ok {
+		d.lru.MoveToFront(el)
+		return nil
+	}
+
+	path := d.path(hash)
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return errors.Err(err)
+	}
+	// write to a temp file first, so a crash never leaves half a blob behind
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".tmp")
+	if err != nil {
+		return errors.Err(err)
+	}
+	_, err = tmp.Write(blob)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return errors.Err(err)
+	}
+
+	d.entries[hash] = d.lru.PushFront(&diskEntry{hash: hash, size: size})
+	d.size += size
+	d.evict()
+	return nil
+}
+
+// Delete removes the blob
+func (d *DiskStore) Delete(hash string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	el, ok := d.entries[hash]
+	if !ok {
+		return nil
+	}
+	return d.delete(el)
+}
+
+// Size returns the total size of the blobs in the store
+func (d *DiskStore) Size() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.size
+}
+
+// Hashes returns the hashes of all the blobs in the store, most recently used first
+func (d *DiskStore) Hashes() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	hashes := make([]string, 0, d.lru.Len())
+	for el := d.lru.Front(); el != nil; el = el.Next() {
+		hashes = append(hashes, el.Value.(*diskEntry).hash)
+	}
+	return hashes
+}
+
+// evict deletes the least recently used blobs until the store is under its size cap. d.mu must be held.
+func (d *DiskStore) evict() {
+	for d.maxSize > 0 && d.size > d.maxSize && d.lru.Len() > 0 {
+		err := d.delete(d.lru.Back())
+		if err != nil {
+			log.Errorf("evicting blob: %s", errors.FullTrace(err))
+			return
+		}
+	}
+}
+
+// delete removes a blob from disk and from the lru. d.mu must be held.
+func (d *DiskStore) delete(el *list.Element) error {
+	err := os.Remove(d.path(el.Value.(*diskEntry).hash))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Err(err)
+	}
+	d.remove(el)
+	return nil
+}
+
+// remove removes a blob from the lru. d.mu must be held.
+func (d *DiskStore) remove(el *list.Element) {
+	e := d.lru.Remove(el).(*diskEntry)
+	delete(d.entries, e.hash)
+	d.size -= e.size
+}
+
+func (d *DiskStore) path(hash string) string {
+	return filepath.Join(d.dir, hash[:prefixLength], hash)
+}
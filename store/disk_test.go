@@ -0,0 +1,227 @@
+package store
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/stream"
+)
+
+var _ BlobStore = &DiskStore{}
+var _ stream.BlobGetter = &DiskStore{}
+
+func testBlob(t *testing.T, size int) stream.Blob {
+	b := make([]byte, size)
+	_, err := rand.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDiskStore(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDiskStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blob := testBlob(t, 100)
+	hash := blob.HashHex()
+	if has, _ := d.Has(hash); has {
+		t.Error("empty store should not have the blob")
+	}
+	if _, err := d.Get(hash); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("expected ErrBlobNotFound, got %v", err)
+	}
+
+	err = d.Put(hash, blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, hash[:2], hash)); err != nil {
+		t.Errorf("expected the blob to be sharded by prefix: %v", err)
+	}
+	if has, _ := d.Has(hash); !has {
+		t.Error("store should have the blob")
+	}
+	got, err := d.Get(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.HashHex() != hash {
+		t.Error("got a different blob back")
+	}
+	if d.Size() != 100 {
+		t.Errorf("expected size 100, got %d", d.Size())
+	}
+
+	if err := d.Put(testBlob(t, 10).HashHex(), blob); !errors.Is(err, ErrBlobCorrupt) {
+		t.Errorf("expected ErrBlobCorrupt for the wrong hash, got %v", err)
+	}
+
+	err = d.Delete(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if has, _ := d.Has(hash); has {
+		t.Error("blob should be deleted")
+	}
+	if err := d.Delete(hash); err != nil {
+		t.Errorf("deleting a missing blob should not fail: %v", err)
+	}
+	if d.Size() != 0 {
+		t.Errorf("expected size 0, got %d", d.Size())
+	}
+}
+
+func TestDiskStore_Corrupt(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDiskStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob := testBlob(t, 100)
+	hash := blob.HashHex()
+	err = d.Put(hash, blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, hash[:2], hash), []byte("bit rot"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Get(hash); !errors.Is(err, ErrBlobCorrupt) {
+		t.Errorf("expected ErrBlobCorrupt, got %v", err)
+	}
+	if has, _ := d.Has(hash); has {
+		t.Error("corrupt blob should be deleted")
+	}
+}
+
+func TestDiskStore_Eviction(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDiskStore(dir, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blobs := []stream.Blob{testBlob(t, 100), testBlob(t, 100), testBlob(t, 100)}
+	for _, b := range blobs[:2] {
+		err = d.Put(b.HashHex(), b)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// using the first blob makes the second one the least recently used
+	_, err = d.Get(blobs[0].HashHex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Put(blobs[2].HashHex(), blobs[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, expected := range []bool{true, false, true} {
+		if has, _ := d.Has(blobs[i].HashHex()); has != expected {
+			t.Errorf("blob %d: expected has to be %t", i, expected)
+		}
+	}
+	if d.Size() != 200 {
+		t.Errorf("expected size 200, got %d", d.Size())
+	}
+
+	if err := d.Put(testBlob(t, 300).HashHex(), testBlob(t, 300)); err == nil {
+		t.Error("expected an error for a blob bigger than the store")
+	}
+}
+
+func TestDiskStore_Reload(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDiskStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, recent := testBlob(t, 100), testBlob(t, 100)
+	for _, b := range []stream.Blob{old, recent} {
+		err = d.Put(b.HashHex(), b)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	past := time.Now().Add(-time.Hour)
+	err = os.Chtimes(filepath.Join(dir, old.HashHex()[:2], old.HashHex()), past, past)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a smaller cap on restart evicts the blob that was used longest ago
+	d, err = NewDiskStore(dir, 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := d.Hashes()
+	if len(hashes) != 1 || hashes[0] != recent.HashHex() {
+		t.Errorf("expected only the recent blob to be kept, got %v", hashes)
+	}
+}
+
+func TestDiskStore_Concurrent(t *testing.T) {
+	d, err := NewDiskStore(t.TempDir(), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blobs := make([]stream.Blob, 20)
+	for i := range blobs {
+		blobs[i] = testBlob(t, 100)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				b := blobs[(i*7+w)%len(blobs)]
+				switch i % 4 {
+				case 0:
+					if err := d.Put(b.HashHex(), b); err != nil {
+						t.Error(err)
+					}
+				case 1:
+					if err := d.Delete(b.HashHex()); err != nil {
+						t.Error(err)
+					}
+				default:
+					got, err := d.Get(b.HashHex())
+					if err != nil && !errors.Is(err, ErrBlobNotFound) {
+						t.Error(err)
+					} else if err == nil && got.HashHex() != b.HashHex() {
+						t.Error("got the wrong blob")
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var size int64
+	for _, hash := range d.Hashes() {
+		b, err := d.Get(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size += int64(len(b))
+	}
+	if d.Size() != size || size > 1000 {
+		t.Errorf("store says it has %d bytes, but its blobs add up to %d", d.Size(), size)
+	}
+}
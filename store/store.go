@@ -0,0 +1,36 @@
+// Package store keeps blobs around so they can be announced on the dht, served to peers, and used to encode and
+// decode streams
+package store
+
+import (
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+	"github.com/lbryio/lbry.go/v2/stream"
+)
+
+var (
+	ErrBlobNotFound = errors.Base("blob not found")
+	ErrBlobCorrupt  = errors.Base("blob does not match its hash")
+)
+
+// BlobStore is a place to keep blobs, by their hex-encoded hash. A BlobStore is also a stream.BlobGetter.
+type BlobStore interface {
+	// Has returns whether the blob is in the store
+	Has(hash string) (bool, error)
+	// Get returns the blob, or ErrBlobNotFound
+	Get(hash string) (stream.Blob, error)
+	// Put stores the blob
+	Put(hash string, blob stream.Blob) error
+	// Delete removes the blob. deleting a blob that isn't there is not an error
+	Delete(hash string) error
+}
+
+// checkHash checks that the blob is the one that hash says it is
+func checkHash(hash string, blob stream.Blob) error {
+	if len(hash) != stream.BlobHashHexLength {
+		return errors.Err("invalid blob hash %s", hash)
+	}
+	if blob.HashHex() != hash {
+		return errors.Err(ErrBlobCorrupt)
+	}
+	return nil
+}
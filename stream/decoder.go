@@ -0,0 +1,219 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// BlobGetter gets blobs by their hex-encoded hash, from wherever they are (a local dir, a reflector, dht peers...)
+type BlobGetter interface {
+	Get(hash string) (Blob, error)
+}
+
+// BlobGetterFunc lets a function be a BlobGetter
+type BlobGetterFunc func(hash string) (Blob, error)
+
+func (f BlobGetterFunc) Get(hash string) (Blob, error) { return f(hash) }
+
+// DirGetter gets blobs from a directory where each blob is a file named after its hash
+type DirGetter string
+
+func (d DirGetter) Get(hash string) (Blob, error) {
+	if len(hash) != BlobHashHexLength {
+		return nil, errors.Err("invalid blob hash %s", hash)
+	}
+	b, err := os.ReadFile(filepath.Join(string(d), hash))
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return b, nil
+}
+
+// Decoder reads the original file from a stream, getting blobs as they're needed. It's an io.ReadSeeker, so only the
+// blobs around a seek position are fetched, e.g. to serve range requests.
+type Decoder struct {
+	sd     *SDBlob
+	getter BlobGetter
+
+	// the plaintext length of each content blob, or -1 if it's not known yet
+	lengths []int64
+	// position in the file, and the blob and offset in that blob that it's at
+	pos     int64
+	blobNum int
+	blobPos int64
+	// the plaintext of the blob that was last fetched
+	buf    []byte
+	bufNum int
+}
+
+// NewDecoder returns a decoder for the stream with the given sd blob
+func NewDecoder(sdBlob *SDBlob, getter BlobGetter) (*Decoder, error) {
+	if !sdBlob.IsValid() {
+		return nil, errors.Err("sd blob is not valid")
+	}
+	infos := sdBlob.BlobInfos
+	if len(infos) == 0 || infos[len(infos)-1].Length != 0 {
+		return nil, errors.Err("sd blob is missing the terminating 0-length blob")
+	}
+
+	d := &Decoder{sd: sdBlob, getter: getter, lengths: make([]int64, len(infos)-1), bufNum: -1}
+	for i, info := range infos[:len(infos)-1] {
+		if info.BlobNum != i {
+			return nil, errors.Err("blobs are out of order in sd blob")
+		}
+		if info.Length <= 0 {
+			return nil, errors.Err("got 0-length blob before end of stream")
+		}
+		d.lengths[i] = -1
+		if info.Length == MaxBlobSize {
+			// a full blob has exactly one byte of padding
+			d.lengths[i] = maxBlobDataSize
+		}
+	}
+	return d, nil
+}
+
+// NewDecoderFromHash gets the sd blob with getter and returns a decoder for its stream
+func NewDecoderFromHash(sdHash string, getter BlobGetter) (*Decoder, error) {
+	b, err := getter.Get(sdHash)
+	if err != nil {
+		return nil, err
+	}
+	if b.HashHex() != sdHash {
+		return nil, errors.Err("sd blob hash doesn't match")
+	}
+	sdBlob := &SDBlob{}
+	err = sdBlob.FromBlob(b)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	return NewDecoder(sdBlob, getter)
+}
+
+// Read reads the decrypted file
+func (d *Decoder) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if d.blobNum >= len(d.lengths) {
+			if n == 0 {
+				return 0, io.EOF
+			}
+			break
+		}
+		plaintext, err := d.blob(d.blobNum)
+		if err != nil {
+			return n, err
+		}
+
+		copied := copy(p[n:], plaintext[d.blobPos:])
+		n += copied
+		d.pos += int64(copied)
+		d.blobPos += int64(copied)
+		if d.blobPos >= int64(len(plaintext)) {
+			d.blobNum++
+			d.blobPos = 0
+		}
+	}
+	return n, nil
+}
+
+// Seek moves to a position in the decrypted file. Seeking from the end needs the length of the last blob, which means
+// fetching it.
+func (d *Decoder) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = d.pos + offset
+	case io.SeekEnd:
+		size, err := d.Size()
+		if err != nil {
+			return d.pos, err
+		}
+		pos = size + offset
+	default:
+		return d.pos, errors.Err("invalid whence %d", whence)
+	}
+	if pos < 0 {
+		return d.pos, errors.Err("cannot seek to negative position %d", pos)
+	}
+
+	// find the blob that the position is in
+	blobNum, blobPos := 0, pos
+	for ; blobNum < len(d.lengths); blobNum++ {
+		length, err := d.length(blobNum)
+		if err != nil {
+			return d.pos, err
+		}
+		if blobPos < length {
+			break
+		}
+		blobPos -= length
+	}
+
+	d.pos, d.blobNum, d.blobPos = pos, blobNum, blobPos
+	return pos, nil
+}
+
+// Size returns the size of the decrypted file
+func (d *Decoder) Size() (int64, error) {
+	var size int64
+	for i := range d.lengths {
+		length, err := d.length(i)
+		if err != nil {
+			return 0, err
+		}
+		size += length
+	}
+	return size, nil
+}
+
+// SDBlob returns the sd blob of the stream being decoded
+func (d *Decoder) SDBlob() *SDBlob {
+	return d.sd
+}
+
+// length returns the plaintext length of a content blob, fetching it if it's not full
+func (d *Decoder) length(i int) (int64, error) {
+	if d.lengths[i] < 0 {
+		_, err := d.blob(i)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return d.lengths[i], nil
+}
+
+// blob fetches, checks, and decrypts a content blob
+func (d *Decoder) blob(i int) ([]byte, error) {
+	if d.bufNum == i {
+		return d.buf, nil
+	}
+
+	info := d.sd.BlobInfos[i]
+	hash := hex.EncodeToString(info.BlobHash)
+	b, err := d.getter.Get(hash)
+	if err != nil {
+		return nil, errors.Prefix("blob "+hash, err)
+	}
+	if !bytes.Equal(b.Hash(), info.BlobHash) {
+		return nil, errors.Err("blob %s doesn't match its hash", hash)
+	}
+	plaintext, err := b.Plaintext(d.sd.Key, info.IV)
+	if err != nil {
+		return nil, err
+	}
+	if d.lengths[i] >= 0 && int64(len(plaintext)) != d.lengths[i] {
+		return nil, errors.Err("blob %s has %d bytes of data, expected %d", hash, len(plaintext), d.lengths[i])
+	}
+
+	d.lengths[i] = int64(len(plaintext))
+	d.buf, d.bufNum = plaintext, i
+	return plaintext, nil
+}
@@ -0,0 +1,178 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// mapGetter gets blobs from a map and counts how many times each is fetched
+type mapGetter struct {
+	blobs   map[string]Blob
+	fetched map[string]int
+}
+
+func newMapGetter(s Stream) *mapGetter {
+	g := &mapGetter{blobs: make(map[string]Blob), fetched: make(map[string]int)}
+	for _, b := range s {
+		g.blobs[b.HashHex()] = b
+	}
+	return g
+}
+
+func (g *mapGetter) Get(hash string) (Blob, error) {
+	b, ok := g.blobs[hash]
+	if !ok {
+		return nil, errors.Err("blob not found")
+	}
+	g.fetched[hash]++
+	return b, nil
+}
+
+func testStream(t *testing.T, size int) ([]byte, Stream) {
+	data := make([]byte, size)
+	_, err := rand.Read(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, s
+}
+
+func TestDecoder(t *testing.T) {
+	data, s := testStream(t, 2*maxBlobDataSize+100)
+	getter := newMapGetter(s)
+
+	dec, err := NewDecoderFromHash(s[0].HashHex(), getter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := io.ReadAll(dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Error("decoded file does not match")
+	}
+
+	size, err := dec.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(len(data)) {
+		t.Errorf("expected size %d, got %d", len(data), size)
+	}
+}
+
+func TestDecoder_Seek(t *testing.T) {
+	data, s := testStream(t, 2*maxBlobDataSize+100)
+	getter := newMapGetter(s)
+	sdBlob := &SDBlob{}
+	err := sdBlob.FromBlob(s[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := NewDecoder(sdBlob, getter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a range across the first two blobs only needs those blobs
+	start := int64(maxBlobDataSize - 5)
+	pos, err := dec.Seek(start, io.SeekStart)
+	if err != nil || pos != start {
+		t.Fatalf("seek: %v, pos %d", err, pos)
+	}
+	buf := make([]byte, 10)
+	_, err = io.ReadFull(dec, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, data[start:start+10]) {
+		t.Error("range does not match")
+	}
+	if getter.fetched[s[3].HashHex()] != 0 {
+		t.Error("the last blob should not have been fetched")
+	}
+
+	_, err = dec.Seek(-3, io.SeekEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tail, err := io.ReadAll(dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tail, data[len(data)-3:]) {
+		t.Error("tail does not match")
+	}
+
+	_, err = dec.Seek(-int64(len(data)), io.SeekCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head := make([]byte, 5)
+	_, err = io.ReadFull(dec, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(head, data[:5]) {
+		t.Error("head does not match")
+	}
+
+	if _, err := dec.Seek(-1, io.SeekStart); err == nil {
+		t.Error("expected an error seeking before the start")
+	}
+	_, err = dec.Seek(int64(len(data))+10, io.SeekStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dec.Read(buf); err != io.EOF {
+		t.Errorf("expected io.EOF past the end, got %v", err)
+	}
+}
+
+func TestDecoder_BadBlob(t *testing.T) {
+	_, s := testStream(t, 100)
+	getter := newMapGetter(s)
+	getter.blobs[s[1].HashHex()] = append(Blob{}, s[1][:len(s[1])-1]...)
+
+	dec, err := NewDecoderFromHash(s[0].HashHex(), getter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(dec); err == nil {
+		t.Error("expected an error for a blob that doesn't match its hash")
+	}
+}
+
+func TestDirGetter(t *testing.T) {
+	data, s := testStream(t, 100)
+	dir := t.TempDir()
+	for _, b := range s {
+		err := os.WriteFile(filepath.Join(dir, b.HashHex()), b, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dec, err := NewDecoderFromHash(s[0].HashHex(), DirGetter(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := io.ReadAll(dec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Error("decoded file does not match")
+	}
+}
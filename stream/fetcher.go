@@ -0,0 +1,503 @@
+package stream
+
+import (
+	"context"
+	"encoding/hex"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+
+	"go.uber.org/atomic"
+)
+
+const (
+	defaultFetchConcurrency = 8
+	// how many downloads to run from one peer at once
+	defaultPerPeerConcurrency = 2
+	// how many times to try a blob, across all peers, before giving up on the stream
+	defaultMaxBlobAttempts = 5
+	// a peer that fails this many times in a row isn't used again
+	maxPeerFailures = 3
+	// how much the latest download counts toward a peer's throughput
+	throughputWeight = 0.3
+)
+
+// PeerFinder finds the addresses of peers that have a blob. *dht.DHT is one.
+type PeerFinder interface {
+	FindPeers(hash string) ([]string, error)
+}
+
+// PeerFinderFunc lets a function be a PeerFinder
+type PeerFinderFunc func(hash string) ([]string, error)
+
+func (f PeerFinderFunc) FindPeers(hash string) ([]string, error) { return f(hash) }
+
+// PeerBlobGetter gets a blob from a specific peer. It should give up when ctx is done, since that means the fetch no
+// longer needs the blob.
+type PeerBlobGetter interface {
+	GetFromPeer(ctx context.Context, peer, hash string) (Blob, error)
+}
+
+// PeerBlobGetterFunc lets a function be a PeerBlobGetter
+type PeerBlobGetterFunc func(ctx context.Context, peer, hash string) (Blob, error)
+
+func (f PeerBlobGetterFunc) GetFromPeer(ctx context.Context, peer, hash string) (Blob, error) {
+	return f(ctx, peer, hash)
+}
+
+// PeerStats is how a peer has done so far
+type PeerStats struct {
+	Blobs    int
+	Failures int
+	Bytes    int64
+	// bytes per second, weighted toward recent downloads. 0 until the peer has sent a blob
+	Throughput float64
+	// set once the peer has failed too many times in a row to be used again
+	Dropped bool
+
+	inFlight            int
+	consecutiveFailures int
+}
+
+// Progress is passed to the progress callback every time a blob finishes or fails
+type Progress struct {
+	Done  int
+	Total int
+	Bytes int64
+	// the blob that finished, the peer it came from, and the error if it failed
+	BlobNum int
+	Peer    string
+	Err     error
+}
+
+const (
+	pieceMissing = iota
+	pieceInFlight
+	pieceDone
+)
+
+// PieceMap tracks which peers have which blobs of a stream and which blobs are done, like a torrent's piece map.
+// Peers that have the sd blob are assumed to have every blob, since that's what a peer announcing a stream usually
+// means. The rest only have the blobs they were found for.
+type PieceMap struct {
+	lock   sync.RWMutex
+	state  []int
+	seeds  map[string]bool
+	have   []map[string]bool
+	failed []map[string]bool
+	peers  map[string]*PeerStats
+}
+
+func newPieceMap(n int) *PieceMap {
+	p := &PieceMap{
+		state:  make([]int, n),
+		seeds:  make(map[string]bool),
+		have:   make([]map[string]bool, n),
+		failed: make([]map[string]bool, n),
+		peers:  make(map[string]*PeerStats),
+	}
+	for i := range p.have {
+		p.have[i] = make(map[string]bool)
+		p.failed[i] = make(map[string]bool)
+	}
+	return p
+}
+
+// Len returns how many blobs are in the stream
+func (p *PieceMap) Len() int {
+	return len(p.state)
+}
+
+// Done returns whether blob num has been fetched
+func (p *PieceMap) Done(num int) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.state[num] == pieceDone
+}
+
+// Availability returns how many usable peers are known to have each blob
+func (p *PieceMap) Availability() []int {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	avail := make([]int, len(p.state))
+	for i := range avail {
+		avail[i] = len(p.candidates(i))
+	}
+	return avail
+}
+
+// Peers returns the stats of every peer that's been found
+func (p *PieceMap) Peers() map[string]PeerStats {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	peers := make(map[string]PeerStats, len(p.peers))
+	for addr, s := range p.peers {
+		peers[addr] = *s
+	}
+	return peers
+}
+
+// addSeeds adds peers that have every blob, and returns how many weren't known before
+func (p *PieceMap) addSeeds(peers []string) int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	added := 0
+	for _, peer := range peers {
+		if !p.seeds[peer] {
+			p.seeds[peer] = true
+			added++
+		}
+		p.addPeer(peer)
+	}
+	return added
+}
+
+// addHolders adds peers that have blob num, and returns how many weren't known to have it
+func (p *PieceMap) addHolders(num int, peers []string) int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	added := 0
+	for _, peer := range peers {
+		if !p.seeds[peer] && !p.have[num][peer] {
+			p.have[num][peer] = true
+			added++
+		}
+		p.addPeer(peer)
+	}
+	return added
+}
+
+func (p *PieceMap) addPeer(peer string) {
+	if _, ok := p.peers[peer]; !ok {
+		p.peers[peer] = &PeerStats{}
+	}
+}
+
+// candidates returns the peers that have blob num, haven't failed it, and haven't been dropped
+func (p *PieceMap) candidates(num int) []string {
+	var peers []string
+	for peer := range p.peers {
+		if (p.seeds[peer] || p.have[num][peer]) && !p.failed[num][peer] && !p.peers[peer].Dropped {
+			peers = append(peers, peer)
+		}
+	}
+	return peers
+}
+
+// next picks the blob to fetch next and the peer to fetch it from. Blobs are taken in order starting at pos, so a
+// player gets the blobs it needs first, and then the ones before pos. For each blob, peers that haven't been measured
+// yet are tried first, then the fastest. Peers that are already busy are skipped.
+func (p *PieceMap) next(pos, perPeer int) (int, string, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	n := len(p.state)
+	for i := 0; i < n; i++ {
+		num := (pos + i) % n
+		if p.state[num] != pieceMissing {
+			continue
+		}
+		best := ""
+		for _, peer := range p.candidates(num) {
+			if p.peers[peer].inFlight < perPeer && (best == "" || p.faster(peer, best)) {
+				best = peer
+			}
+		}
+		if best != "" {
+			p.state[num] = pieceInFlight
+			p.peers[best].inFlight++
+			return num, best, true
+		}
+	}
+	return 0, "", false
+}
+
+// faster returns whether peer a should be used before peer b
+func (p *PieceMap) faster(a, b string) bool {
+	sa, sb := p.peers[a], p.peers[b]
+	ra, rb := sa.Throughput, sb.Throughput
+	if sa.Blobs == 0 {
+		ra = math.Inf(1)
+	}
+	if sb.Blobs == 0 {
+		rb = math.Inf(1)
+	}
+	if ra != rb {
+		return ra > rb
+	}
+	if sa.inFlight != sb.inFlight {
+		return sa.inFlight < sb.inFlight
+	}
+	return a < b
+}
+
+// succeeded marks blob num as done and updates the peer's throughput
+func (p *PieceMap) succeeded(num int, peer string, size int, took time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.state[num] = pieceDone
+	s := p.peers[peer]
+	s.inFlight--
+	s.consecutiveFailures = 0
+	s.Blobs++
+	s.Bytes += int64(size)
+	if took <= 0 {
+		took = time.Millisecond
+	}
+	rate := float64(size) / took.Seconds()
+	if s.Throughput == 0 {
+		s.Throughput = rate
+	} else {
+		s.Throughput = throughputWeight*rate + (1-throughputWeight)*s.Throughput
+	}
+}
+
+// failedFrom puts blob num back to be fetched from some other peer
+func (p *PieceMap) failedFrom(num int, peer string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.state[num] = pieceMissing
+	p.failed[num][peer] = true
+	s := p.peers[peer]
+	s.inFlight--
+	s.Failures++
+	s.consecutiveFailures++
+	if s.consecutiveFailures >= maxPeerFailures {
+		s.Dropped = true
+	}
+}
+
+// stuck returns the blobs that aren't done and have no peers left to try
+func (p *PieceMap) stuck() []int {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	var nums []int
+	for num, state := range p.state {
+		if state == pieceMissing && len(p.candidates(num)) == 0 {
+			nums = append(nums, num)
+		}
+	}
+	return nums
+}
+
+// Fetcher downloads the blobs of a stream from many peers at once
+type Fetcher struct {
+	sd     *SDBlob
+	hashes []string
+	finder PeerFinder
+	getter PeerBlobGetter
+	pieces *PieceMap
+
+	concurrency int
+	perPeer     int
+	maxAttempts int
+	onProgress  func(Progress)
+	pos         *atomic.Int64
+}
+
+// NewFetcher returns a fetcher for the stream with the given sd blob. Peers are found with finder and blobs are
+// downloaded with getter.
+func NewFetcher(sd *SDBlob, finder PeerFinder, getter PeerBlobGetter) (*Fetcher, error) {
+	if !sd.IsValid() {
+		return nil, errors.Err("sd blob is not valid")
+	}
+	infos := sd.BlobInfos
+	if len(infos) == 0 || infos[len(infos)-1].Length != 0 {
+		return nil, errors.Err("sd blob is missing the terminating 0-length blob")
+	}
+	hashes := make([]string, len(infos)-1)
+	for i, info := range infos[:len(infos)-1] {
+		hashes[i] = hex.EncodeToString(info.BlobHash)
+	}
+
+	return &Fetcher{
+		sd:          sd,
+		hashes:      hashes,
+		finder:      finder,
+		getter:      getter,
+		pieces:      newPieceMap(len(hashes)),
+		concurrency: defaultFetchConcurrency,
+		perPeer:     defaultPerPeerConcurrency,
+		maxAttempts: defaultMaxBlobAttempts,
+		pos:         atomic.NewInt64(0),
+	}, nil
+}
+
+// Concurrency sets how many blobs are downloaded at once, and how many of those can come from the same peer
+func (f *Fetcher) Concurrency(total, perPeer int) *Fetcher {
+	if total > 0 {
+		f.concurrency = total
+	}
+	if perPeer > 0 {
+		f.perPeer = perPeer
+	}
+	return f
+}
+
+// MaxAttempts sets how many times a blob is tried, across all peers, before the fetch fails
+func (f *Fetcher) MaxAttempts(n int) *Fetcher {
+	if n > 0 {
+		f.maxAttempts = n
+	}
+	return f
+}
+
+// OnProgress sets a function that's called every time a blob finishes or fails. It's called from Fetch's goroutine,
+// so it shouldn't block.
+func (f *Fetcher) OnProgress(fn func(Progress)) *Fetcher {
+	f.onProgress = fn
+	return f
+}
+
+// SetPosition makes blobs from num on be fetched before the rest, e.g. when a player seeks. It's safe to call while
+// Fetch is running.
+func (f *Fetcher) SetPosition(num int) {
+	if num < 0 || num >= len(f.hashes) {
+		return
+	}
+	f.pos.Store(int64(num))
+}
+
+// Pieces returns the piece map, which shows which peers have which blobs and how fast each peer is
+func (f *Fetcher) Pieces() *PieceMap {
+	return f.pieces
+}
+
+type fetchResult struct {
+	num  int
+	peer string
+	blob Blob
+	took time.Duration
+	err  error
+}
+
+// Fetch downloads every content blob of the stream and passes each one to handler as it arrives, which is not
+// necessarily in order. It returns when all blobs are fetched, ctx is done, handler returns an error, or a blob can't
+// be fetched from any peer.
+func (f *Fetcher) Fetch(ctx context.Context, handler func(num int, hash string, blob Blob) error) error {
+	if len(f.hashes) == 0 {
+		return nil
+	}
+	if err := f.findSeeds(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// big enough that downloads still running when Fetch returns don't block
+	results := make(chan fetchResult, f.concurrency)
+	attempts := make([]int, len(f.hashes))
+	inFlight := 0
+	done := 0
+	var bytes int64
+
+	for done < len(f.hashes) {
+		for inFlight < f.concurrency {
+			num, peer, ok := f.pieces.next(int(f.pos.Load()), f.perPeer)
+			if !ok {
+				break
+			}
+			inFlight++
+			go f.download(ctx, num, peer, results)
+		}
+
+		if inFlight == 0 {
+			// every missing blob has run out of peers
+			found, err := f.findMorePeers()
+			if err != nil {
+				return err
+			}
+			if !found {
+				stuck := f.pieces.stuck()
+				if len(stuck) == 0 {
+					return errors.Err("no peers available")
+				}
+				return errors.Err("no peers have blob %d (%s)", stuck[0], f.hashes[stuck[0]])
+			}
+			continue
+		}
+
+		var r fetchResult
+		select {
+		case r = <-results:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		inFlight--
+
+		if r.err != nil {
+			f.pieces.failedFrom(r.num, r.peer)
+			attempts[r.num]++
+			f.progress(Progress{Done: done, Total: len(f.hashes), Bytes: bytes, BlobNum: r.num, Peer: r.peer, Err: r.err})
+			if attempts[r.num] >= f.maxAttempts {
+				return errors.Prefix("blob "+strconv.Itoa(r.num), r.err)
+			}
+			continue
+		}
+
+		f.pieces.succeeded(r.num, r.peer, len(r.blob), r.took)
+		done++
+		bytes += int64(len(r.blob))
+		if err := handler(r.num, f.hashes[r.num], r.blob); err != nil {
+			return err
+		}
+		f.progress(Progress{Done: done, Total: len(f.hashes), Bytes: bytes, BlobNum: r.num, Peer: r.peer})
+	}
+	return nil
+}
+
+func (f *Fetcher) download(ctx context.Context, num int, peer string, results chan<- fetchResult) {
+	hash := f.hashes[num]
+	start := time.Now()
+	b, err := f.getter.GetFromPeer(ctx, peer, hash)
+	r := fetchResult{num: num, peer: peer, blob: b, took: time.Since(start), err: err}
+	if err == nil && b.HashHex() != hash {
+		r.err = errors.Err("peer %s sent a blob that doesn't match its hash", peer)
+	}
+	select {
+	case results <- r:
+	case <-ctx.Done():
+	}
+}
+
+func (f *Fetcher) progress(p Progress) {
+	if f.onProgress != nil {
+		f.onProgress(p)
+	}
+}
+
+// findSeeds finds peers that have the whole stream
+func (f *Fetcher) findSeeds() error {
+	peers, err := f.finder.FindPeers(f.sd.HashHex())
+	if err != nil {
+		return errors.Prefix("finding peers", err)
+	}
+	f.pieces.addSeeds(peers)
+	return nil
+}
+
+// findMorePeers looks for peers for the blobs that have none left, and returns whether any new ones turned up
+func (f *Fetcher) findMorePeers() (bool, error) {
+	added := 0
+	peers, err := f.finder.FindPeers(f.sd.HashHex())
+	if err != nil {
+		return false, errors.Prefix("finding peers", err)
+	}
+	added += f.pieces.addSeeds(peers)
+
+	stuck := f.pieces.stuck()
+	sort.Ints(stuck)
+	for _, num := range stuck {
+		peers, err := f.finder.FindPeers(f.hashes[num])
+		if err != nil {
+			return false, errors.Prefix("finding peers for blob "+strconv.Itoa(num), err)
+		}
+		added += f.pieces.addHolders(num, peers)
+	}
+	return added > 0, nil
+}
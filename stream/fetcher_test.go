@@ -0,0 +1,241 @@
+package stream
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+// testPeers is a swarm of fake peers. seeds have the whole stream, holders only have the blobs they're listed for,
+// broken peers claim to have everything but fail every request, and hanging peers don't answer until the request is
+// cancelled
+type testPeers struct {
+	lock    sync.Mutex
+	blobs   map[string]Blob
+	seeds   []string
+	holders map[string][]string
+	broken  map[string]bool
+	corrupt map[string]bool
+	hanging map[string]bool
+	got     map[string]int
+	// gets a value each time a hanging peer's request is cancelled
+	cancelled chan string
+}
+
+func newTestPeers(s Stream) *testPeers {
+	p := &testPeers{
+		blobs:   make(map[string]Blob),
+		holders: make(map[string][]string),
+		broken:  make(map[string]bool),
+		corrupt: make(map[string]bool),
+		hanging: make(map[string]bool),
+		got:     make(map[string]int),
+	}
+	for _, b := range s[1:] {
+		p.blobs[b.HashHex()] = b
+	}
+	return p
+}
+
+func (p *testPeers) FindPeers(hash string) ([]string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.blobs[hash]; ok {
+		return p.holders[hash], nil
+	}
+	return p.seeds, nil
+}
+
+func (p *testPeers) GetFromPeer(ctx context.Context, peer, hash string) (Blob, error) {
+	p.lock.Lock()
+	hanging := p.hanging[peer]
+	p.lock.Unlock()
+	if hanging {
+		<-ctx.Done()
+		p.cancelled <- peer
+		return nil, ctx.Err()
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.got[peer]++
+	if p.broken[peer] {
+		return nil, errors.Err("connection refused")
+	}
+	if p.corrupt[peer] {
+		return Blob("garbage"), nil
+	}
+	return p.blobs[hash], nil
+}
+
+func testFetcher(t *testing.T, blobs int) (Stream, *testPeers, *Fetcher) {
+	_, s := testStream(t, (blobs-1)*maxBlobDataSize+100)
+	sd := &SDBlob{}
+	if err := sd.FromBlob(s[0]); err != nil {
+		t.Fatal(err)
+	}
+	peers := newTestPeers(s)
+	f, err := NewFetcher(sd, peers, peers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, peers, f
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	s, peers, f := testFetcher(t, 4)
+	peers.seeds = []string{"good", "broken", "corrupt"}
+	peers.broken["broken"] = true
+	peers.corrupt["corrupt"] = true
+
+	var progress []Progress
+	f.OnProgress(func(p Progress) { progress = append(progress, p) })
+
+	got := make(map[int]Blob)
+	err := f.Fetch(context.Background(), func(num int, hash string, b Blob) error {
+		if hash != s[num+1].HashHex() {
+			t.Errorf("blob %d: got hash %s", num, hash)
+		}
+		got[num] = b
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 4 {
+		t.Fatalf("expected 4 blobs, got %d", len(got))
+	}
+	for num, b := range got {
+		if b.HashHex() != s[num+1].HashHex() {
+			t.Errorf("blob %d doesn't match", num)
+		}
+	}
+
+	last := progress[len(progress)-1]
+	if last.Done != 4 || last.Total != 4 || last.Err != nil {
+		t.Errorf("unexpected final progress %+v", last)
+	}
+
+	stats := f.Pieces().Peers()
+	if stats["good"].Blobs != 4 || stats["good"].Throughput <= 0 {
+		t.Errorf("unexpected stats for the good peer: %+v", stats["good"])
+	}
+	if stats["broken"].Blobs != 0 || stats["broken"].Failures == 0 {
+		t.Errorf("unexpected stats for the broken peer: %+v", stats["broken"])
+	}
+	if stats["corrupt"].Blobs != 0 || stats["corrupt"].Failures == 0 {
+		t.Errorf("a peer that sent corrupt blobs should have failed: %+v", stats["corrupt"])
+	}
+	for i := 0; i < 4; i++ {
+		if !f.Pieces().Done(i) {
+			t.Errorf("blob %d should be done", i)
+		}
+	}
+}
+
+func TestFetcher_Sequential(t *testing.T) {
+	_, peers, f := testFetcher(t, 4)
+	peers.seeds = []string{"a"}
+	f.Concurrency(1, 1)
+	f.SetPosition(2)
+
+	var order []int
+	err := f.Fetch(context.Background(), func(num int, hash string, b Blob) error {
+		order = append(order, num)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, 3, 0, 1}; !reflect.DeepEqual(order, want) {
+		t.Errorf("expected blobs in order %v, got %v", want, order)
+	}
+}
+
+func TestFetcher_FindsHolders(t *testing.T) {
+	s, peers, f := testFetcher(t, 3)
+	// nobody has the whole stream, and the only seed is broken
+	peers.seeds = []string{"broken"}
+	peers.broken["broken"] = true
+	peers.holders[s[1].HashHex()] = []string{"first"}
+	peers.holders[s[2].HashHex()] = []string{"second"}
+	peers.holders[s[3].HashHex()] = []string{"first", "second"}
+
+	n := 0
+	err := f.Fetch(context.Background(), func(num int, hash string, b Blob) error {
+		n++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 blobs, got %d", n)
+	}
+	if !f.Pieces().Peers()["broken"].Dropped {
+		t.Error("the broken peer should have been dropped")
+	}
+}
+
+func TestFetcher_NoPeers(t *testing.T) {
+	s, peers, f := testFetcher(t, 2)
+	peers.seeds = []string{"broken"}
+	peers.broken["broken"] = true
+	peers.holders[s[1].HashHex()] = []string{"ok"}
+
+	var fetched []int
+	err := f.Fetch(context.Background(), func(num int, hash string, b Blob) error {
+		fetched = append(fetched, num)
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected an error when a blob has no peers")
+	}
+	if !reflect.DeepEqual(fetched, []int{0}) {
+		t.Errorf("expected only blob 0 to be fetched, got %v", fetched)
+	}
+	if avail := f.Pieces().Availability(); avail[1] != 0 {
+		t.Errorf("expected no peers for blob 1, got %v", avail)
+	}
+}
+
+func TestFetcher_HandlerError(t *testing.T) {
+	_, peers, f := testFetcher(t, 3)
+	peers.seeds = []string{"a", "b"}
+
+	stop := errors.Base("stop")
+	err := f.Fetch(context.Background(), func(num int, hash string, b Blob) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("expected the handler's error, got %v", err)
+	}
+}
+
+func TestFetcher_CancelStopsDownloads(t *testing.T) {
+	_, peers, f := testFetcher(t, 4)
+	peers.seeds = []string{"hanging"}
+	peers.hanging["hanging"] = true
+	peers.cancelled = make(chan string, 4)
+	f.Concurrency(4, 4)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := f.Fetch(ctx, func(num int, hash string, b Blob) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		select {
+		case <-peers.cancelled:
+		case <-time.After(time.Second):
+			t.Fatalf("only %d of 4 downloads were cancelled", i)
+		}
+	}
+}
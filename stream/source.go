@@ -0,0 +1,72 @@
+package stream
+
+import (
+	"encoding/hex"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lbryio/lbry.go/v2/extras/errors"
+)
+
+const defaultMediaType = "application/octet-stream"
+
+// Source is what a stream claim needs to point to an encoded file, e.g.
+//
+//	stake.NewStreamClaim().SDHash(src.SDHash).FileHash(src.FileHash).File(src.FileName, src.Size, src.MediaType)
+type Source struct {
+	// hex-encoded hash of the sd blob, which is what the claim's stream source points to
+	SDHash string
+	// hex-encoded sha384 of the file
+	FileHash  string
+	FileName  string
+	Size      uint64
+	MediaType string
+	// hex-encoded hashes of the content blobs, in stream order
+	BlobHashes []string
+}
+
+// EncodeFile splits the file at path into encrypted blobs and passes each one to handler, content blobs first and the
+// sd blob last. Nothing is kept in memory, so handler should store the blobs somewhere.
+func EncodeFile(path string, handler func(hash string, blob []byte) error) (*Source, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+	if info.IsDir() {
+		return nil, errors.Err("%s is a directory", path)
+	}
+
+	name := filepath.Base(path)
+	enc := NewEncoder(f).SourceSizeHint(int(info.Size())).WithFileName(name)
+	manifest, err := enc.Encode(handler)
+	if err != nil {
+		return nil, errors.Err(err)
+	}
+
+	return &Source{
+		SDHash:     manifest[0],
+		FileHash:   hex.EncodeToString(enc.SourceHash()),
+		FileName:   name,
+		Size:       uint64(enc.SourceLen()),
+		MediaType:  MediaType(name),
+		BlobHashes: manifest[1:],
+	}, nil
+}
+
+// MediaType guesses the media type of a file from its extension
+func MediaType(fileName string) string {
+	t := mime.TypeByExtension(filepath.Ext(fileName))
+	if t == "" {
+		return defaultMediaType
+	}
+	// drop parameters like "; charset=utf-8"
+	return strings.TrimSpace(strings.SplitN(t, ";", 2)[0])
+}
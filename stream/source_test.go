@@ -0,0 +1,98 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+)
+
+func TestEncodeFile(t *testing.T) {
+	data := make([]byte, maxBlobDataSize+10)
+	_, err := rand.Read(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blobs := make(map[string][]byte)
+	var order []string
+	src, err := EncodeFile(path, func(hash string, blob []byte) error {
+		blobs[hash] = blob
+		order = append(order, hash)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(src.BlobHashes) != 2 || len(blobs) != 3 {
+		t.Fatalf("expected 2 content blobs and an sd blob, got %d blobs", len(blobs))
+	}
+	if order[len(order)-1] != src.SDHash {
+		t.Error("expected the sd blob to be handled last")
+	}
+	fileHash := sha512.Sum384(data)
+	if src.FileHash != hex.EncodeToString(fileHash[:]) {
+		t.Errorf("file hash mismatch")
+	}
+	if src.FileName != "video.mp4" || src.Size != uint64(len(data)) || src.MediaType != "video/mp4" {
+		t.Errorf("unexpected source %+v", src)
+	}
+
+	sdBlob := &SDBlob{}
+	err = sdBlob.FromBlob(blobs[src.SDHash])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sdBlob.StreamName != "video.mp4" || sdBlob.SuggestedFileName != "video.mp4" {
+		t.Errorf("expected the file name in the sd blob, got %q and %q", sdBlob.StreamName, sdBlob.SuggestedFileName)
+	}
+
+	s := Stream{blobs[src.SDHash]}
+	for _, hash := range src.BlobHashes {
+		s = append(s, blobs[hash])
+	}
+	decoded, err := s.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Error("decoded stream does not match the file")
+	}
+}
+
+func TestEncodeShortReads(t *testing.T) {
+	data := make([]byte, maxBlobDataSize+10)
+	s, err := NewEncoder(iotest.HalfReader(bytes.NewReader(data))).Stream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 3 {
+		t.Fatalf("expected an sd blob and 2 content blobs, got %d blobs", len(s))
+	}
+	if s[1].Size() != MaxBlobSize {
+		t.Errorf("expected the first blob to be full, got %d bytes", s[1].Size())
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	for name, expected := range map[string]string{
+		"a.mp4":    "video/mp4",
+		"a.txt":    "text/plain",
+		"a":        defaultMediaType,
+		"a.nope42": defaultMediaType,
+	} {
+		if got := MediaType(name); got != expected {
+			t.Errorf("%s: expected %s, got %s", name, expected, got)
+		}
+	}
+}
@@ -141,6 +141,13 @@ func NewEncoderFromSD(src io.Reader, sdBlob *SDBlob) *Encoder {
 	return e
 }
 
+// WithFileName sets the stream name and suggested file name in the sd blob
+func (e *Encoder) WithFileName(name string) *Encoder {
+	e.sd.StreamName = name
+	e.sd.SuggestedFileName = name
+	return e
+}
+
 // TODO: consider making a NewPartialEncoder that also copies blobinfos from sdBlobs and seeks forward in the data
 // this would avoid re-creating blobs that were created in the past
 
@@ -148,7 +155,11 @@ func NewEncoderFromSD(src io.Reader, sdBlob *SDBlob) *Encoder {
 // When the source is fully consumed, Next() makes sure the stream is terminated (i.e. the sd blob
 // ends with an empty terminating blob) and returns io.EOF
 func (e *Encoder) Next() (Blob, error) {
-	n, err := e.src.Read(e.buf)
+	// fill the whole buffer, so every blob but the last is full even if src returns short reads
+	n, err := io.ReadFull(e.src, e.buf)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil // this is the last blob
+	}
 	if err != nil {
 		if errors.Is(err, io.EOF) {
 			e.ensureTerminated()